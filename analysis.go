@@ -1,9 +1,13 @@
 package lookout
 
 import (
+	"fmt"
+	"strings"
+
 	"google.golang.org/grpc"
 	log "gopkg.in/src-d/go-log.v1"
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+	yaml "gopkg.in/yaml.v2"
 )
 
 type EventResponse = pb.EventResponse
@@ -16,16 +20,27 @@ func RegisterAnalyzerServer(s *grpc.Server, srv AnalyzerServer) {
 	pb.RegisterAnalyzerServer(s, srv)
 }
 
+// NewAnalyzerClient creates a client for the analyzer at conn. The client
+// also implements AnalyzerStreamClient.
 func NewAnalyzerClient(conn *grpc.ClientConn) AnalyzerClient {
-	return pb.NewAnalyzerClient(conn)
+	return &analyzerClient{
+		AnalyzerClient:       pb.NewAnalyzerClient(conn),
+		AnalyzerStreamClient: NewAnalyzerStreamClient(conn),
+	}
 }
 
 // AnalyzerConfig is a configuration of analyzer
 type AnalyzerConfig struct {
 	Name string
-	// Addr is gRPC URL.
+	// Addr is gRPC URL, a comma separated list of gRPC URLs, or a dns:// URL
+	// resolved to all its addresses. In the YAML configuration it can also be
+	// a list of gRPC URLs.
 	// can be defined only in global config, repository-scoped configuration is ignored
 	Addr string
+	// Balancer is the policy used to balance the calls when Addr has more than
+	// one endpoint, round_robin (the default) or least_request.
+	// can be defined only in global config, repository-scoped configuration is ignored
+	Balancer string
 	// Disabled repository-scoped configuration can accept only true value, false value is ignored
 	Disabled bool
 	// Feedback is a url to be linked after each comment
@@ -34,6 +49,44 @@ type AnalyzerConfig struct {
 	Settings map[string]interface{}
 }
 
+// UnmarshalYAML implements yaml.Unmarshaler, to accept addr as a list
+func (c *AnalyzerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AnalyzerConfig
+
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	list, ok := raw["addr"].([]interface{})
+	if !ok {
+		return unmarshal((*plain)(c))
+	}
+
+	addrs := make([]string, len(list))
+	for i, a := range list {
+		s, ok := a.(string)
+		if !ok {
+			return fmt.Errorf("addr must be a list of strings, got %v", a)
+		}
+
+		addrs[i] = s
+	}
+
+	delete(raw, "addr")
+	b, err := yaml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(b, (*plain)(c)); err != nil {
+		return err
+	}
+
+	c.Addr = strings.Join(addrs, ",")
+	return nil
+}
+
 // Analyzer is a struct of analyzer client and config
 type Analyzer struct {
 	Client AnalyzerClient
@@ -45,6 +98,9 @@ type Analyzer struct {
 type AnalyzerComments struct {
 	Config   AnalyzerConfig
 	Comments []*Comment
+	// Partial is true when the analyzer did not finish, and Comments are the
+	// ones it sent before failing
+	Partial bool
 }
 
 // AnalyzerCommentsGroups list of AnalyzerComments
@@ -74,6 +130,7 @@ func (g AnalyzerCommentsGroups) Filter(fn CommentsFilterFn) ([]AnalyzerComments,
 			result = append(result, AnalyzerComments{
 				Config:   group.Config,
 				Comments: newComments,
+				Partial:  group.Partial,
 			})
 		}
 	}
@@ -124,6 +181,7 @@ func (g AnalyzerCommentsGroups) Dedup() AnalyzerCommentsGroups {
 			result = append(result, AnalyzerComments{
 				Config:   group.Config,
 				Comments: newComments,
+				Partial:  group.Partial,
 			})
 		}
 
@@ -0,0 +1,162 @@
+package lookout
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+)
+
+// The AnalyzerStream service is an optional service that analyzers can
+// implement, next to the Analyzer one, to send their comments while the
+// analysis is in progress. Each EventResponse sent in the stream contains the
+// new comments; lookoutd accumulates them, so the comments sent before a
+// timeout or a failure are not lost.
+//
+// The service is defined here instead of in the SDK protocol, using the SDK
+// messages:
+//
+//	service AnalyzerStream {
+//	    rpc NotifyReviewEventStream (pb.ReviewEvent) returns (stream pb.EventResponse);
+//	    rpc NotifyPushEventStream (pb.PushEvent) returns (stream pb.EventResponse);
+//	}
+//
+// The client and the service descriptor below are written by hand, following
+// the code generated for the SDK services. They must be replaced by the
+// generated ones once the SDK defines the service.
+const analyzerStreamServiceName = "pb.AnalyzerStream"
+
+// EventResponseStream is the client side of a stream of EventResponse
+type EventResponseStream interface {
+	Recv() (*EventResponse, error)
+	grpc.ClientStream
+}
+
+// EventResponseSender is the server side of a stream of EventResponse
+type EventResponseSender interface {
+	Send(*EventResponse) error
+	grpc.ServerStream
+}
+
+// AnalyzerStreamClient is the client API for the AnalyzerStream service
+type AnalyzerStreamClient interface {
+	// NotifyReviewEventStream sends a review event, and returns the stream of
+	// the comments produced for it
+	NotifyReviewEventStream(ctx context.Context, in *pb.ReviewEvent, opts ...grpc.CallOption) (EventResponseStream, error)
+	// NotifyPushEventStream sends a push event, and returns the stream of the
+	// comments produced for it
+	NotifyPushEventStream(ctx context.Context, in *pb.PushEvent, opts ...grpc.CallOption) (EventResponseStream, error)
+}
+
+// AnalyzerStreamServer is the server API for the AnalyzerStream service
+type AnalyzerStreamServer interface {
+	NotifyReviewEventStream(*pb.ReviewEvent, EventResponseSender) error
+	NotifyPushEventStream(*pb.PushEvent, EventResponseSender) error
+}
+
+// RegisterAnalyzerStreamServer registers srv in s, an analyzer can register
+// it along with RegisterAnalyzerServer to send its comments as a stream
+func RegisterAnalyzerStreamServer(s *grpc.Server, srv AnalyzerStreamServer) {
+	s.RegisterService(&analyzerStreamServiceDesc, srv)
+}
+
+// NewAnalyzerStreamClient creates a client for the AnalyzerStream service
+func NewAnalyzerStreamClient(conn *grpc.ClientConn) AnalyzerStreamClient {
+	return &analyzerStreamClient{conn}
+}
+
+// analyzerClient implements both AnalyzerClient and AnalyzerStreamClient
+type analyzerClient struct {
+	AnalyzerClient
+	AnalyzerStreamClient
+}
+
+type analyzerStreamClient struct {
+	cc *grpc.ClientConn
+}
+
+func (c *analyzerStreamClient) NotifyReviewEventStream(ctx context.Context, in *pb.ReviewEvent, opts ...grpc.CallOption) (EventResponseStream, error) {
+	return c.newStream(ctx, 0, "NotifyReviewEventStream", in, opts...)
+}
+
+func (c *analyzerStreamClient) NotifyPushEventStream(ctx context.Context, in *pb.PushEvent, opts ...grpc.CallOption) (EventResponseStream, error) {
+	return c.newStream(ctx, 1, "NotifyPushEventStream", in, opts...)
+}
+
+func (c *analyzerStreamClient) newStream(ctx context.Context, i int, method string, in interface{}, opts ...grpc.CallOption) (EventResponseStream, error) {
+	stream, err := c.cc.NewStream(ctx, &analyzerStreamServiceDesc.Streams[i],
+		"/"+analyzerStreamServiceName+"/"+method, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &eventResponseStream{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	return x, nil
+}
+
+type eventResponseStream struct {
+	grpc.ClientStream
+}
+
+func (x *eventResponseStream) Recv() (*EventResponse, error) {
+	m := new(EventResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+type eventResponseSender struct {
+	grpc.ServerStream
+}
+
+func (x *eventResponseSender) Send(m *EventResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func notifyReviewEventStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(pb.ReviewEvent)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return srv.(AnalyzerStreamServer).NotifyReviewEventStream(m, &eventResponseSender{stream})
+}
+
+func notifyPushEventStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(pb.PushEvent)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return srv.(AnalyzerStreamServer).NotifyPushEventStream(m, &eventResponseSender{stream})
+}
+
+// analyzerStreamServiceDesc must be replaced by the one of the SDK once it
+// defines the AnalyzerStream service
+var analyzerStreamServiceDesc = grpc.ServiceDesc{
+	ServiceName: analyzerStreamServiceName,
+	HandlerType: (*AnalyzerStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "NotifyReviewEventStream",
+			Handler:       notifyReviewEventStreamHandler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "NotifyPushEventStream",
+			Handler:       notifyPushEventStreamHandler,
+			ServerStreams: true,
+		},
+	},
+}
@@ -0,0 +1,115 @@
+package lookout
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+)
+
+type streamAnalyzer struct{}
+
+func (a *streamAnalyzer) NotifyReviewEventStream(e *pb.ReviewEvent, s EventResponseSender) error {
+	for _, text := range []string{"first", "second"} {
+		err := s.Send(&EventResponse{
+			AnalyzerVersion: "v1",
+			Comments:        []*Comment{{Text: text + " " + e.Head.Hash}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *streamAnalyzer) NotifyPushEventStream(e *pb.PushEvent, s EventResponseSender) error {
+	if err := s.Send(&EventResponse{Comments: []*Comment{{Text: "push"}}}); err != nil {
+		return err
+	}
+
+	return status.Error(codes.Internal, "failed")
+}
+
+func dialTestServer(t *testing.T, register func(*grpc.Server)) (*grpc.ClientConn, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	register(srv)
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+
+	return conn, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func recvAll(stream EventResponseStream) ([]string, error) {
+	var texts []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return texts, nil
+		}
+
+		if err != nil {
+			return texts, err
+		}
+
+		for _, c := range resp.Comments {
+			texts = append(texts, c.Text)
+		}
+	}
+}
+
+func TestAnalyzerStream(t *testing.T) {
+	require := require.New(t)
+
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {
+		RegisterAnalyzerStreamServer(s, &streamAnalyzer{})
+	})
+	defer stop()
+
+	client, ok := NewAnalyzerClient(conn).(AnalyzerStreamClient)
+	require.True(ok)
+
+	stream, err := client.NotifyReviewEventStream(context.Background(), &pb.ReviewEvent{
+		CommitRevision: pb.CommitRevision{Head: pb.ReferencePointer{Hash: "abc"}},
+	})
+	require.NoError(err)
+
+	texts, err := recvAll(stream)
+	require.NoError(err)
+	require.Equal([]string{"first abc", "second abc"}, texts)
+
+	stream, err = client.NotifyPushEventStream(context.Background(), &pb.PushEvent{})
+	require.NoError(err)
+
+	texts, err = recvAll(stream)
+	require.Equal(codes.Internal, status.Code(err))
+	require.Equal([]string{"push"}, texts)
+}
+
+func TestAnalyzerStreamUnimplemented(t *testing.T) {
+	require := require.New(t)
+
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {})
+	defer stop()
+
+	stream, err := NewAnalyzerStreamClient(conn).NotifyReviewEventStream(
+		context.Background(), &pb.ReviewEvent{})
+	require.NoError(err)
+
+	_, err = stream.Recv()
+	require.Equal(codes.Unimplemented, status.Code(err))
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestAnalyzerCommentsGroupsFilter(t *testing.T) {
@@ -129,3 +131,39 @@ func TestAnalyzerCommentsGroupsDedup(t *testing.T) {
 		assert.Equal(sum, exp)
 	}
 }
+
+func TestAnalyzerConfigUnmarshalYAML(t *testing.T) {
+	require := require.New(t)
+
+	var confs []AnalyzerConfig
+	err := yaml.Unmarshal([]byte(`
+- name: single
+  addr: ipv4://localhost:9930
+  settings:
+    threshold: 0.8
+- name: list
+  addr:
+    - ipv4://localhost:9930
+    - ipv4://localhost:9931
+  balancer: least_request
+  feedback: http://example.com
+  settings:
+    threshold: 0.8
+`), &confs)
+	require.NoError(err)
+
+	require.Equal([]AnalyzerConfig{{
+		Name:     "single",
+		Addr:     "ipv4://localhost:9930",
+		Settings: map[string]interface{}{"threshold": 0.8},
+	}, {
+		Name:     "list",
+		Addr:     "ipv4://localhost:9930,ipv4://localhost:9931",
+		Balancer: "least_request",
+		Feedback: "http://example.com",
+		Settings: map[string]interface{}{"threshold": 0.8},
+	}}, confs)
+
+	err = yaml.Unmarshal([]byte("- name: wrong\n  addr: [1, 2]\n"), &confs)
+	require.Error(err)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/meyskens/lookout/server"
+)
+
+// breakerStatusVar is the name of the expvar with the status of the analyzers
+// circuit breakers, served by the health probes HTTP server in /debug/vars
+const breakerStatusVar = "analyzer_circuit_breakers"
+
+// publishBreakerStatus makes the status of the circuit breakers of srv
+// visible in the readiness probe and in the metrics
+func (c *lookoutdCommand) publishBreakerStatus(srv *server.Server) {
+	c.breakerStatus = srv.BreakerStatus
+	expvar.Publish(breakerStatusVar, expvar.Func(func() interface{} {
+		return srv.BreakerStatus()
+	}))
+}
+
+// writeBreakerStatus writes one line for each analyzer with the state of its
+// circuit breaker, sorted by analyzer name
+func writeBreakerStatus(w io.Writer, breakerStatus func() map[string]server.BreakerStatus) {
+	if breakerStatus == nil {
+		return
+	}
+
+	status := breakerStatus()
+	names := make([]string, 0, len(status))
+	for name := range status {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		st := status[name]
+		fmt.Fprintf(w, "\nanalyzer %s: circuit breaker %s, %d consecutive failures",
+			name, st.State, st.Failures)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/meyskens/lookout/store/models"
 	"github.com/meyskens/lookout/util/cache"
 	"github.com/meyskens/lookout/util/cli"
+	"github.com/meyskens/lookout/util/config"
+	"github.com/meyskens/lookout/util/ctxlog"
 	"github.com/meyskens/lookout/util/grpchelper"
 
 	"github.com/jinzhu/copier"
@@ -31,6 +33,7 @@ import (
 	"google.golang.org/grpc"
 	"gopkg.in/src-d/go-billy.v4/osfs"
 	gocli "gopkg.in/src-d/go-cli.v0"
+	gitcache "gopkg.in/src-d/go-git.v4/plumbing/cache"
 	log "gopkg.in/src-d/go-log.v1"
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
 	yaml "gopkg.in/yaml.v2"
@@ -54,8 +57,21 @@ type lookoutdCommand struct {
 	ProbesAddr  string `long:"probes-addr" default:"0.0.0.0:8090" env:"LOOKOUT_PROBES_ADDRESS" description:"TCP address to bind the health probe endpoints"`
 
 	pool           *github.ClientPool
+	githubCache    *cache.ValidableCache
 	probeReadiness bool
 	conf           Config
+
+	// breakerStatus returns the status of the analyzers circuit breakers, it
+	// is set once the server is created
+	breakerStatus func() map[string]server.BreakerStatus
+
+	// interpolated are the paths of the configuration values that contain
+	// environment variables or files, they are redacted from the logs
+	interpolated []config.Path
+
+	// syncInstallationsOnce makes initProvider sync the GitHub App
+	// installations before returning, instead of syncing them periodically
+	syncInstallationsOnce bool
 }
 
 // Init implements the go-cli initializer interface. Initializes logs
@@ -79,7 +95,28 @@ type queueConsumerCommand struct {
 	Library    string `long:"library" default:"/tmp/lookout" env:"LOOKOUT_LIBRARY" description:"path to the lookout library"`
 	Workers    int    `long:"workers" env:"LOOKOUT_WORKERS" default:"1" description:"number of concurrent workers processing events, 0 means the same number as processors"`
 
-	analyzers map[string]lookout.AnalyzerClient
+	// analyzers holds the running analyzers by name
+	analyzers map[string]*runningAnalyzer
+	// commitLoader loads the commits from the library, it is set by
+	// initDataHandler
+	commitLoader git.CommitLoader
+	// janitor cleans up the library, it is set by initDataHandler if the
+	// library cleanup is enabled
+	janitor *git.Janitor
+}
+
+// runningAnalyzer is an analyzer with an open connection
+type runningAnalyzer struct {
+	lookout.Analyzer
+	conn *grpc.ClientConn
+	// stopLog stops logging the connection status changes
+	stopLog context.CancelFunc
+}
+
+// close closes the analyzer connection
+func (a *runningAnalyzer) close() error {
+	a.stopLog()
+	return a.conn.Close()
 }
 
 var defaultInstallationsSyncInterval = 5 * time.Minute
@@ -93,8 +130,13 @@ type Config struct {
 	Providers     struct {
 		Github github.ProviderConfig
 	}
-	Repositories []RepoConfig
-	Timeout      TimeoutConfig
+	Repositories   []RepoConfig
+	Timeout        TimeoutConfig
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	AnalyzerCache  AnalyzerCacheConfig  `yaml:"analyzer_cache"`
+	Library        LibraryConfig        `yaml:"library"`
+	Mirrors        []MirrorConfig       `yaml:"mirrors"`
+	LFS            LFSConfig            `yaml:"lfs"`
 }
 
 // RepoConfig holds configuration for repository, support only github provider
@@ -105,14 +147,109 @@ type RepoConfig struct {
 
 // TimeoutConfig holds configuration for timeouts
 type TimeoutConfig struct {
-	AnalyzerReview time.Duration `yaml:"analyzer_review"`
-	AnalyzerPush   time.Duration `yaml:"analyzer_push"`
-	GithubRequest  time.Duration `yaml:"github_request"`
-	GitFetch       time.Duration `yaml:"git_fetch"`
-	BblfshParse    time.Duration `yaml:"bblfsh_parse"`
+	AnalyzerReview    time.Duration `yaml:"analyzer_review"`
+	AnalyzerPush      time.Duration `yaml:"analyzer_push"`
+	AnalyzerHeartbeat time.Duration `yaml:"analyzer_heartbeat"`
+	GithubRequest     time.Duration `yaml:"github_request"`
+	GitFetch          time.Duration `yaml:"git_fetch"`
+	BblfshParse       time.Duration `yaml:"bblfsh_parse"`
+}
+
+// CircuitBreakerConfig holds configuration for the analyzers circuit breaker
+type CircuitBreakerConfig struct {
+	Threshold     int           `yaml:"threshold"`
+	ProbeInterval time.Duration `yaml:"probe_interval"`
+}
+
+// options returns the server options for the circuit breaker
+func (c CircuitBreakerConfig) options() server.BreakerOptions {
+	return server.BreakerOptions{
+		Threshold:     c.Threshold,
+		ProbeInterval: c.ProbeInterval,
+	}
+}
+
+// AnalyzerCacheConfig holds configuration for the analyzer results cache
+type AnalyzerCacheConfig struct {
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+}
+
+// options returns the server options for the analyzer results cache
+func (c AnalyzerCacheConfig) options() server.ResultCacheOptions {
+	return server.ResultCacheOptions{
+		TTL:        c.TTL,
+		MaxEntries: c.MaxEntries,
+	}
+}
+
+// LibraryConfig holds configuration for the cleanup of the git library
+type LibraryConfig struct {
+	GCInterval  time.Duration `yaml:"gc_interval"`
+	MaxSizeMB   int64         `yaml:"max_size_mb"`
+	GracePeriod time.Duration `yaml:"grace_period"`
+	FetchDepth  int           `yaml:"fetch_depth"`
+	CacheSizeMB int64         `yaml:"cache_size_mb"`
+}
+
+// MirrorConfig holds configuration for a local mirror of the repositories of
+// a host, they are read from it instead of being fetched into the library
+type MirrorConfig struct {
+	Host string `yaml:"host"`
+	Path string `yaml:"path"`
+}
+
+// LFSConfig holds configuration for the fetch of the files stored with Git LFS
+type LFSConfig struct {
+	Enabled   bool  `yaml:"enabled"`
+	MaxSizeMB int64 `yaml:"max_size_mb"`
+	// Path is a local directory with the objects, they are fetched from the
+	// Git LFS server of each repository if it is empty
+	Path string `yaml:"path"`
+}
+
+// resolver returns the Git LFS resolver, nil if it is not enabled
+func (c LFSConfig) resolver(authProvider git.AuthProvider) git.LFSResolver {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Path != "" {
+		return git.NewDirLFSResolver(osfs.New(c.Path))
+	}
+
+	return git.NewHTTPLFSResolver(nil, authProvider)
+}
+
+// libraryOptions returns the git library options
+func (c LibraryConfig) libraryOptions() git.LibraryOptions {
+	return git.LibraryOptions{
+		ObjectCacheSize: gitcache.FileSize(c.CacheSizeMB) * gitcache.MiByte,
+	}
+}
+
+// options returns the git janitor options for the library cleanup
+func (c LibraryConfig) options() git.JanitorOptions {
+	return git.JanitorOptions{
+		Interval:    c.GCInterval,
+		MaxSize:     c.MaxSizeMB * 1024 * 1024,
+		GracePeriod: c.GracePeriod,
+	}
 }
 
 func (c *lookoutdCommand) initConfig() (Config, error) {
+	conf, err := c.readConfig()
+	if err != nil {
+		return conf, err
+	}
+
+	c.logConfig(conf)
+
+	return conf, nil
+}
+
+// readConfig reads and parses the configuration file
+func (c *lookoutdCommand) readConfig() (Config, error) {
 	var conf Config
 	configData, err := ioutil.ReadFile(c.ConfigFile)
 	if err != nil {
@@ -129,23 +266,43 @@ func (c *lookoutdCommand) initConfig() (Config, error) {
 
 	// Set default timeouts
 	conf.Timeout = TimeoutConfig{
-		AnalyzerReview: 10 * time.Minute,
-		AnalyzerPush:   60 * time.Minute,
-		GithubRequest:  time.Minute,
-		GitFetch:       20 * time.Minute,
-		BblfshParse:    2 * time.Minute,
+		AnalyzerReview:    10 * time.Minute,
+		AnalyzerPush:      60 * time.Minute,
+		AnalyzerHeartbeat: 5 * time.Minute,
+		GithubRequest:     time.Minute,
+		GitFetch:          20 * time.Minute,
+		BblfshParse:       2 * time.Minute,
+	}
+
+	// Set default circuit breaker
+	conf.CircuitBreaker = CircuitBreakerConfig{
+		Threshold:     5,
+		ProbeInterval: time.Minute,
+	}
+
+	// The analyzer results cache is disabled by default
+	conf.AnalyzerCache = AnalyzerCacheConfig{
+		MaxEntries: 10000,
+	}
+
+	// The library cleanup is disabled by default
+	conf.Library = LibraryConfig{
+		GracePeriod: time.Hour,
+	}
+
+	configData, c.interpolated, err = config.Interpolate(configData)
+	if err != nil {
+		return conf, fmt.Errorf("Can't interpolate configuration file: %s", err)
 	}
 
 	if err := yaml.Unmarshal([]byte(configData), &conf); err != nil {
 		return conf, fmt.Errorf("Can't parse configuration file: %s", err)
 	}
 
-	c.logConfig(conf)
-
 	return conf, nil
 }
 
-func logConfig(options interface{}, conf Config) {
+func logConfig(options interface{}, conf Config, interpolated []config.Path) {
 	var confCp Config
 	copier.Copy(&confCp, conf)
 
@@ -163,9 +320,16 @@ func logConfig(options interface{}, conf Config) {
 		Compact: true,
 	}
 
+	// interpolated values may contain secrets from the environment or files
+	confDump, err := config.Redact(confCp, interpolated)
+	if err != nil {
+		log.Errorf(err, "the configuration can't be logged")
+		confDump = ""
+	}
+
 	log.With(log.Fields{
 		"options": lt.Sdump(options),
-		"conf":    lt.Sdump(confCp),
+		"conf":    confDump,
 		"version": version,
 		"build":   build,
 	}).Infof("starting %s", name)
@@ -177,7 +341,7 @@ func (c *lookoutdCommand) logConfig(conf Config) {
 
 	cCp.GithubToken = "****"
 
-	logConfig(cCp, conf)
+	logConfig(cCp, conf, c.interpolated)
 }
 
 func (c *queueConsumerCommand) logConfig(conf Config) {
@@ -187,12 +351,13 @@ func (c *queueConsumerCommand) logConfig(conf Config) {
 	cCp.DBOptions.DB = "****"
 	cCp.GithubToken = "****"
 
-	logConfig(cCp, conf)
+	logConfig(cCp, conf, c.interpolated)
 }
 
 func (c *lookoutdCommand) initProvider(conf Config) error {
 	os.RemoveAll(diskcachePath)
 	cache := cache.NewValidableCache(diskcache.New(diskcachePath))
+	c.githubCache = cache
 
 	switch c.Provider {
 	case github.Provider:
@@ -207,6 +372,18 @@ func (c *lookoutdCommand) initProvider(conf Config) error {
 }
 
 func (c *lookoutdCommand) initProviderGithubToken(conf Config, cache *cache.ValidableCache) error {
+	pool, err := c.newTokenPool(conf, cache)
+	if err != nil {
+		return err
+	}
+
+	c.pool = pool
+	return nil
+}
+
+// newTokenPool returns a pool with a client for each configured repository,
+// authenticated with personal tokens
+func (c *lookoutdCommand) newTokenPool(conf Config, cache *cache.ValidableCache) (*github.ClientPool, error) {
 	noDefaultAuth := c.GithubUser == "" || c.GithubToken == ""
 	defaultConfig := github.ClientConfig{
 		User:  c.GithubUser,
@@ -232,13 +409,7 @@ func (c *lookoutdCommand) initProviderGithubToken(conf Config, cache *cache.Vali
 		}
 	}
 
-	pool, err := github.NewClientPoolFromTokens(repoToConfig, cache, conf.Timeout.GithubRequest)
-	if err != nil {
-		return err
-	}
-
-	c.pool = pool
-	return nil
+	return github.NewClientPoolFromTokens(repoToConfig, cache, conf.Timeout.GithubRequest)
 }
 
 func (c *lookoutdCommand) initProviderGithubApp(conf Config, cache *cache.ValidableCache) error {
@@ -267,6 +438,10 @@ func (c *lookoutdCommand) initProviderGithubApp(conf Config, cache *cache.Valida
 
 	c.pool = insts.Pool
 
+	if c.syncInstallationsOnce {
+		return insts.Sync()
+	}
+
 	go func() {
 		for {
 			if err := insts.Sync(); err != nil {
@@ -307,6 +482,7 @@ func (c *lookoutdCommand) startHealthProbes() error {
 		if c.probeReadiness {
 			w.WriteHeader(200)
 			w.Write([]byte("ok"))
+			writeBreakerStatus(w, c.breakerStatus)
 		} else {
 			w.WriteHeader(500)
 			w.Write([]byte("starting up"))
@@ -328,7 +504,17 @@ func (c *queueConsumerCommand) initPoster(conf Config) (lookout.Poster, error) {
 
 	switch c.Provider {
 	case github.Provider:
-		return github.NewPoster(c.pool, conf.Providers.Github)
+		poster, err := github.NewPoster(c.pool, conf.Providers.Github)
+		if err != nil {
+			return nil, err
+		}
+
+		// the diff of the files omitted by GitHub is computed from the library
+		if c.commitLoader != nil {
+			poster.SetPatchLoader(git.NewPatchLoader(c.commitLoader))
+		}
+
+		return poster, nil
 	case json.Provider:
 		return json.NewPoster(os.Stdout), nil
 	default:
@@ -336,7 +522,7 @@ func (c *queueConsumerCommand) initPoster(conf Config) (lookout.Poster, error) {
 	}
 }
 
-func (c *queueConsumerCommand) startAnalyzer(conf lookout.AnalyzerConfig) (lookout.AnalyzerClient, error) {
+func (c *queueConsumerCommand) startAnalyzer(conf lookout.AnalyzerConfig) (*runningAnalyzer, error) {
 	if conf.Name == "" {
 		return nil, fmt.Errorf("missing 'name' in analyzer config")
 	}
@@ -344,14 +530,11 @@ func (c *queueConsumerCommand) startAnalyzer(conf lookout.AnalyzerConfig) (looko
 	if conf.Addr == "" {
 		return nil, fmt.Errorf("missing 'addr' in config for analyzer %s", conf.Name)
 	}
-	addr, err := pb.ToGoGrpcAddress(conf.Addr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid address '%s' in config for analyzer %s: %s", conf.Addr, conf.Name, err)
-	}
 
-	ctx := context.Background()
-	conn, err := grpchelper.DialContext(ctx, addr)
+	ctx, stopLog := context.WithCancel(context.Background())
+	conn, err := grpchelper.DialBalanced(ctx, conf.Addr, conf.Balancer)
 	if err != nil {
+		stopLog()
 		return nil, fmt.Errorf("failed to create a client connection to address '%s' in config for analyzer %s: %s", conf.Addr, conf.Name, err)
 	}
 
@@ -360,7 +543,14 @@ func (c *queueConsumerCommand) startAnalyzer(conf lookout.AnalyzerConfig) (looko
 		"addr":     conf.Addr,
 	}), conn)
 
-	return lookout.NewAnalyzerClient(conn), nil
+	return &runningAnalyzer{
+		Analyzer: lookout.Analyzer{
+			Client: lookout.NewAnalyzerClient(conn),
+			Config: conf,
+		},
+		conn:    conn,
+		stopLog: stopLog,
+	}, nil
 }
 
 func (c *queueConsumerCommand) initDataHandler(conf Config) (*lookout.DataServerHandler, error) {
@@ -376,19 +566,50 @@ func (c *queueConsumerCommand) initDataHandler(conf Config) (*lookout.DataServer
 	}
 
 	var authProvider git.AuthProvider
+	var refsProvider git.ReferencesProvider
 	if c.Provider == github.Provider {
 		if c.pool == nil {
 			return nil, fmt.Errorf("pool must be initialized with initProvider")
 		}
 
 		authProvider = c.pool
+		refsProvider = c.pool
 	}
 
-	lib := git.NewLibrary(osfs.New(c.Library))
-	sync := git.NewSyncer(lib, authProvider, conf.Timeout.GitFetch)
-	loader := git.NewLibraryCommitLoader(lib, sync)
+	lib := git.NewLibraryWithOptions(osfs.New(c.Library), conf.Library.libraryOptions())
+	sync := git.NewSyncer(lib, authProvider, conf.Timeout.GitFetch, conf.Library.FetchDepth)
+	if c.Provider == github.Provider {
+		sync.SetParentProvider(c.pool)
+	}
+	var loader git.CommitLoader = git.NewLibraryCommitLoader(lib, sync)
+	if len(conf.Mirrors) > 0 {
+		mirrors := make(map[string]git.CommitLoader, len(conf.Mirrors))
+		for _, m := range conf.Mirrors {
+			if m.Host == "" {
+				return nil, fmt.Errorf("missing 'host' in mirror config for path %q", m.Path)
+			}
+
+			if m.Path == "" {
+				return nil, fmt.Errorf("missing 'path' in mirror config for host %q", m.Host)
+			}
+
+			mirrors[m.Host] = git.NewMirrorCommitLoader(osfs.New(m.Path))
+		}
+
+		loader = git.NewHostCommitLoader(loader, mirrors)
+	}
+
+	c.commitLoader = loader
+
+	if conf.Library.GCInterval > 0 {
+		c.janitor = git.NewJanitor(lib, refsProvider, conf.Library.options())
+	}
 
 	gitService := git.NewService(loader)
+	if r := conf.LFS.resolver(authProvider); r != nil {
+		gitService.SetLFSResolver(r, conf.LFS.MaxSizeMB*1024*1024)
+	}
+
 	enryService := enry.NewService(gitService, gitService)
 	bblfshService := bblfsh.NewService(enryService, enryService, bblfshConn, conf.Timeout.BblfshParse)
 	purgeService := purge.NewService(bblfshService, bblfshService)
@@ -401,6 +622,19 @@ func (c *queueConsumerCommand) initDataHandler(conf Config) (*lookout.DataServer
 	return srv, nil
 }
 
+// runJanitor cleans up the library until ctx is canceled, if the library
+// cleanup is enabled
+func (c *queueConsumerCommand) runJanitor(ctx context.Context) {
+	if c.janitor == nil {
+		return
+	}
+
+	err := c.janitor.Run(ctx)
+	if err != context.Canceled {
+		ctxlog.Get(ctx).Errorf(err, "library janitor stopped")
+	}
+}
+
 func (c *queueConsumerCommand) initDataServer(srv *lookout.DataServerHandler) (startFunc, stopFunc) {
 	var grpcSrv *grpc.Server
 
@@ -432,7 +666,8 @@ func (c *queueConsumerCommand) initDataServer(srv *lookout.DataServerHandler) (s
 }
 
 func (c *queueConsumerCommand) initDBOperators(db *sql.DB) (
-	*store.DBEventOperator, *store.DBCommentOperator, *store.DBOrganizationOperator) {
+	*store.DBEventOperator, *store.DBCommentOperator,
+	*store.DBOrganizationOperator, *store.DBRepositoryOperator) {
 	reviewStore := models.NewReviewEventStore(db)
 	reviewTargetStore := models.NewReviewTargetStore(db)
 	eventOp := store.NewDBEventOperator(
@@ -448,27 +683,75 @@ func (c *queueConsumerCommand) initDBOperators(db *sql.DB) (
 	organizationsOp := store.NewDBOrganizationOperator(
 		models.NewOrganizationStore(db),
 	)
+	repositoriesOp := store.NewDBRepositoryOperator(
+		models.NewRepositoryStore(db),
+	)
 
-	return eventOp, commentsOp, organizationsOp
+	return eventOp, commentsOp, organizationsOp, repositoriesOp
 }
 
 func (c *queueConsumerCommand) initAnalyzers(conf Config) (map[string]lookout.Analyzer, error) {
-	analyzers := make(map[string]lookout.Analyzer)
+	running, _, err := c.updateAnalyzers(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	c.analyzers = running
+	return analyzersOf(running), nil
+}
+
+// updateAnalyzers returns the running analyzers for conf, reusing the
+// connections of c.analyzers when the address did not change. removed are the
+// analyzers of c.analyzers that are not used anymore, it's up to the caller to
+// close them. c.analyzers is not modified.
+func (c *queueConsumerCommand) updateAnalyzers(conf Config) (
+	running map[string]*runningAnalyzer, removed []*runningAnalyzer, err error) {
+
+	running = make(map[string]*runningAnalyzer)
+	var started []*runningAnalyzer
 	for _, aConf := range conf.Analyzers {
 		if aConf.Disabled {
 			continue
 		}
-		client, err := c.startAnalyzer(aConf)
+
+		if prev, ok := c.analyzers[aConf.Name]; ok &&
+			prev.Config.Addr == aConf.Addr && prev.Config.Balancer == aConf.Balancer {
+			a := *prev
+			a.Config = aConf
+			running[aConf.Name] = &a
+			continue
+		}
+
+		a, err := c.startAnalyzer(aConf)
 		if err != nil {
-			return nil, err
+			for _, a := range started {
+				a.close()
+			}
+
+			return nil, nil, err
 		}
-		analyzers[aConf.Name] = lookout.Analyzer{
-			Client: client,
-			Config: aConf,
+
+		started = append(started, a)
+		running[aConf.Name] = a
+	}
+
+	for name, prev := range c.analyzers {
+		if a, ok := running[name]; !ok || a.conn != prev.conn {
+			removed = append(removed, prev)
 		}
 	}
 
-	return analyzers, nil
+	return running, removed, nil
+}
+
+// analyzersOf returns the lookout.Analyzer of each running analyzer
+func analyzersOf(running map[string]*runningAnalyzer) map[string]lookout.Analyzer {
+	analyzers := make(map[string]lookout.Analyzer, len(running))
+	for name, a := range running {
+		analyzers[name] = a.Analyzer
+	}
+
+	return analyzers
 }
 
 func (c *lookoutdCommand) runEventEnqueuer(
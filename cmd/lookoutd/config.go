@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/meyskens/lookout/provider/github"
+	"github.com/meyskens/lookout/server"
+	"github.com/meyskens/lookout/store"
+	"github.com/meyskens/lookout/store/models"
+	"github.com/meyskens/lookout/util/cli"
+
+	gocli "gopkg.in/src-d/go-cli.v0"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+)
+
+var configCommand = app.AddCommand(&ConfigCommand{})
+
+func init() {
+	configCommand.AddCommand(&ConfigExplainCommand{})
+}
+
+type ConfigCommand struct {
+	gocli.PlainCommand `name:"config" short-description:"inspect the analyzers configuration" long-description:"Inspect the analyzers configuration"`
+}
+
+type ConfigExplainCommand struct {
+	gocli.PlainCommand `name:"explain" short-description:"print the effective configuration of a repository" long-description:"Prints the configuration the analyzers receive for a repository, merging config.yml, the organization and repository configuration stored in the DB, and the .lookout.yml file. Each value is annotated with the layer it comes from"`
+	lookoutdCommand
+	cli.DBOptions
+
+	Repo  string `long:"repo" required:"true" description:"URL of the repository"`
+	Ref   string `long:"ref" description:"git reference used to read the .lookout.yml file, by default the repository default branch"`
+	OrgID string `long:"org-id" description:"ID of the organization of the repository. Only needed when the GitHub App is not used, to read the organization configuration"`
+}
+
+func (c *ConfigExplainCommand) ExecuteContext(ctx context.Context, args []string) error {
+	repoInfo, err := pb.ParseRepositoryInfo(c.Repo)
+	if err != nil {
+		return fmt.Errorf("invalid repository URL %s: %s", c.Repo, err)
+	}
+
+	if c.Provider != github.Provider {
+		return fmt.Errorf("provider %s not supported", c.Provider)
+	}
+
+	c.syncInstallationsOnce = true
+	if err := c.initProvider(c.conf); err != nil {
+		return err
+	}
+
+	client, ok := c.pool.Client(repoInfo.Owner, repoInfo.Name)
+	if !ok {
+		return fmt.Errorf("repository %s is not configured in the provider", repoInfo.FullName)
+	}
+
+	orgID := c.OrgID
+	if orgID == "" {
+		orgID = c.pool.OrganizationID(repoInfo.Owner, repoInfo.Name)
+	}
+	if orgID == "" {
+		log.Warningf("unknown organization ID, the organization configuration is ignored")
+	}
+
+	db, err := c.InitDB()
+	if err != nil {
+		return fmt.Errorf("Can't connect to the DB: %s", err)
+	}
+
+	organizationsOp := store.NewDBOrganizationOperator(models.NewOrganizationStore(db))
+	repositoriesOp := store.NewDBRepositoryOperator(models.NewRepositoryStore(db))
+
+	var orgConfig string
+	if orgID != "" {
+		orgConfig, err = organizationsOp.Config(ctx, github.Provider, orgID)
+		if err != nil {
+			return fmt.Errorf("could not load the organization configuration from the DB: %s", err)
+		}
+	}
+
+	repoConfig, err := repositoriesOp.Config(ctx, github.Provider, repoInfo.CloneURL)
+	if err != nil {
+		return fmt.Errorf("could not load the repository configuration from the DB: %s", err)
+	}
+
+	lookoutYml, err := github.FileContent(ctx, client.Client,
+		repoInfo.Owner, repoInfo.Name, ".lookout.yml", c.Ref)
+	if err != nil {
+		return fmt.Errorf("could not get the .lookout.yml file: %s", err)
+	}
+
+	confs, err := server.ExplainConfig(ctx, c.conf.Analyzers, orgConfig, repoConfig, lookoutYml)
+	if err != nil {
+		return err
+	}
+
+	return server.WriteExplainedConfig(os.Stdout, confs)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/meyskens/lookout"
+	"github.com/meyskens/lookout/provider/github"
+	"github.com/meyskens/lookout/server"
+
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// reloadFunc applies a new configuration to a running command
+type reloadFunc func(conf Config) error
+
+// watchReload reloads the configuration file every time the process receives
+// a SIGHUP, until ctx is done. apply is called with the new configuration, if
+// it is not nil, and then the repositories watched with personal tokens are
+// updated. Nothing changes if any of them fails.
+func (c *lookoutdCommand) watchReload(ctx context.Context, apply reloadFunc) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+		}
+
+		logger := log.With(log.Fields{"config": c.ConfigFile})
+		logger.Infof("reloading configuration")
+
+		conf, err := c.readConfig()
+		if err != nil {
+			logger.Errorf(err, "can't reload configuration, the previous one is kept")
+			continue
+		}
+
+		pool, err := c.reloadProvider(conf)
+		if err != nil {
+			logger.Errorf(err, "can't reload the provider configuration, the previous one is kept")
+			continue
+		}
+
+		if apply != nil {
+			if err := apply(conf); err != nil {
+				logger.Errorf(err, "can't apply the new configuration, the previous one is kept")
+				continue
+			}
+		}
+
+		// the pool is only replaced once the rest of the configuration is
+		// applied, so a failed reload keeps the previous repositories
+		if pool != nil {
+			c.pool.Replace(pool)
+		}
+
+		c.conf = conf
+		logger.Infof("configuration reloaded")
+	}
+}
+
+// reloadProvider returns the pool of the repositories watched with personal
+// tokens if they changed, or nil if they did not. The pool in use is not
+// modified. The GitHub App settings can't be changed without a restart
+func (c *lookoutdCommand) reloadProvider(conf Config) (*github.ClientPool, error) {
+	if c.Provider != github.Provider || c.pool == nil {
+		return nil, nil
+	}
+
+	prevGithub := c.conf.Providers.Github
+	newGithub := conf.Providers.Github
+	if prevGithub.PrivateKey != "" || prevGithub.AppID != 0 {
+		if prevGithub.PrivateKey != newGithub.PrivateKey ||
+			prevGithub.AppID != newGithub.AppID ||
+			prevGithub.InstallationSyncInterval != newGithub.InstallationSyncInterval ||
+			prevGithub.WatchMinInterval != newGithub.WatchMinInterval {
+			log.Warningf("the GitHub App configuration changed, lookoutd must be restarted to apply it")
+		}
+
+		return nil, nil
+	}
+
+	if reflect.DeepEqual(c.conf.Repositories, conf.Repositories) &&
+		c.conf.Timeout.GithubRequest == conf.Timeout.GithubRequest {
+		return nil, nil
+	}
+
+	return c.newTokenPool(conf, c.githubCache)
+}
+
+// reloadServer returns a reloadFunc that applies the analyzers, the analyzer
+// timeouts and the GitHub comment footer to srv and poster. The connections of
+// the removed analyzers are closed once the events that use them finish.
+func (c *queueConsumerCommand) reloadServer(srv *server.Server, poster lookout.Poster) reloadFunc {
+	return func(conf Config) error {
+		running, removed, err := c.updateAnalyzers(conf)
+		if err != nil {
+			return err
+		}
+
+		if p, ok := poster.(*github.Poster); ok {
+			if err := p.SetConfig(conf.Providers.Github); err != nil {
+				// close the connections opened by updateAnalyzers
+				for name, a := range running {
+					if prev, ok := c.analyzers[name]; !ok || prev.conn != a.conn {
+						a.close()
+					}
+				}
+
+				return err
+			}
+		}
+
+		c.analyzers = running
+		drained := srv.Reload(server.ReloadOptions{
+			Analyzers:        analyzersOf(running),
+			ReviewTimeout:    conf.Timeout.AnalyzerReview,
+			PushTimeout:      conf.Timeout.AnalyzerPush,
+			HeartbeatTimeout: conf.Timeout.AnalyzerHeartbeat,
+			Breaker:          conf.CircuitBreaker.options(),
+			ResultCache:      conf.AnalyzerCache.options(),
+		})
+
+		go func() {
+			<-drained
+			for _, a := range removed {
+				if err := a.close(); err != nil {
+					log.Errorf(err, "can't close the connection to analyzer %s", a.Config.Name)
+				}
+			}
+		}()
+
+		return nil
+	}
+}
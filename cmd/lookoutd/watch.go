@@ -52,6 +52,8 @@ func (c *WatchCommand) ExecuteContext(ctx context.Context, args []string) error
 		stopCh <- err
 	}()
 
+	go c.watchReload(ctx, nil)
+
 	c.probeReadiness = true
 
 	select {
@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/meyskens/lookout/server"
 	"github.com/meyskens/lookout/store"
 	"github.com/meyskens/lookout/store/models"
 
 	"github.com/meyskens/lookout/util/cli"
+	"github.com/meyskens/lookout/util/config"
 	"github.com/meyskens/lookout/web"
 	gocli "gopkg.in/src-d/go-cli.v0"
 	log "gopkg.in/src-d/go-log.v1"
@@ -31,7 +33,8 @@ type WebCommand struct {
 }
 
 type webConfig struct {
-	Providers struct {
+	server.Config `yaml:",inline"`
+	Providers     struct {
 		Github struct {
 			PrivateKey   string `yaml:"private_key"`
 			AppID        int    `yaml:"app_id"`
@@ -51,6 +54,11 @@ func (c *WebCommand) Execute(args []string) error {
 		return fmt.Errorf("Can't open configuration file: %s", err)
 	}
 
+	configData, _, err = config.Interpolate(configData)
+	if err != nil {
+		return fmt.Errorf("Can't interpolate configuration file: %s", err)
+	}
+
 	if err := yaml.Unmarshal([]byte(configData), &conf); err != nil {
 		return fmt.Errorf("Can't parse configuration file: %s", err)
 	}
@@ -81,10 +89,14 @@ func (c *WebCommand) Execute(args []string) error {
 
 	orgStore := models.NewOrganizationStore(db)
 	orgOp := store.NewDBOrganizationOperator(orgStore)
+	repoStore := models.NewRepositoryStore(db)
+	repoOp := store.NewDBRepositoryOperator(repoStore)
 	gh := web.GitHub{
 		AppID:          ghConfg.AppID,
 		PrivateKey:     ghConfg.PrivateKey,
 		OrganizationOp: orgOp,
+		RepositoryOp:   repoOp,
+		Analyzers:      conf.Analyzers,
 	}
 
 	static := web.NewStatic("/build/public", c.ServerURL, c.FooterHTML)
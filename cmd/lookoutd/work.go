@@ -47,7 +47,7 @@ func (c *WorkCommand) ExecuteContext(ctx context.Context, args []string) error {
 		return fmt.Errorf("Can't connect to the DB: %s", err)
 	}
 
-	eventOp, commentsOp, organizationsOp := c.initDBOperators(db)
+	eventOp, commentsOp, organizationsOp, repositoriesOp := c.initDBOperators(db)
 
 	analyzers, err := c.initAnalyzers(c.conf)
 	if err != nil {
@@ -65,16 +65,28 @@ func (c *WorkCommand) ExecuteContext(ctx context.Context, args []string) error {
 	}
 
 	server := server.NewServer(server.Options{
-		Poster:         poster,
-		FileGetter:     dataHandler.FileGetter,
-		Analyzers:      analyzers,
-		EventOp:        eventOp,
-		CommentOp:      commentsOp,
-		OrganizationOp: organizationsOp,
-		ReviewTimeout:  c.conf.Timeout.AnalyzerReview,
-		PushTimeout:    c.conf.Timeout.AnalyzerPush,
+		Poster:           poster,
+		FileGetter:       dataHandler.FileGetter,
+		Analyzers:        analyzers,
+		EventOp:          eventOp,
+		CommentOp:        commentsOp,
+		OrganizationOp:   organizationsOp,
+		RepositoryOp:     repositoriesOp,
+		ReviewTimeout:    c.conf.Timeout.AnalyzerReview,
+		PushTimeout:      c.conf.Timeout.AnalyzerPush,
+		HeartbeatTimeout: c.conf.Timeout.AnalyzerHeartbeat,
+		Breaker:          c.conf.CircuitBreaker.options(),
+		ResultCache:      c.conf.AnalyzerCache.options(),
 	})
 
+	// the analyzers report their progress through the data server
+	dataHandler.ProgressReporter = server
+
+	c.publishBreakerStatus(server)
+	go c.watchReload(ctx, c.reloadServer(server, poster))
+
+	go c.runJanitor(ctx)
+
 	startDataServer, stopDataServer := c.initDataServer(dataHandler)
 	go func() {
 		err := startDataServer()
@@ -0,0 +1,131 @@
+package lookout
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// The SDK Comment message only has the file, line, text and confidence
+// fields. The rest of the comment fields are encoded at the end of its text,
+// in an HTML comment that is not visible when the text is rendered as
+// Markdown:
+//
+//	Use strings.TrimSpace
+//	<!-- lookout-extension: {"suggestion":{"start_line":3,"end_line":3,"replacement":"..."}} -->
+//
+// Analyzers written in Go can use SetCommentRange, SuggestChange or
+// SetCommentExtension, and lookoutd reads it with SplitCommentExtension.
+const (
+	extensionPrefix = "<!-- lookout-extension: "
+	extensionSuffix = " -->"
+)
+
+// CommentExtension holds the comment fields that are not part of the SDK
+// Comment message
+type CommentExtension struct {
+	// StartLine is the first line of a comment that spans multiple lines,
+	// the last one is the comment Line. Zero means the comment is on a
+	// single line.
+	StartLine int32 `json:"start_line,omitempty"`
+	// Suggestion is a change proposed to fix the problem of the comment
+	Suggestion *Suggestion `json:"suggestion,omitempty"`
+}
+
+// Suggestion is a change proposed by an analyzer, it replaces a range of lines
+// of the head revision of the comment file
+type Suggestion struct {
+	// StartLine is the first line replaced, 1-based
+	StartLine int32 `json:"start_line"`
+	// EndLine is the last line replaced, inclusive. The comment should use it
+	// as its Line.
+	EndLine int32 `json:"end_line"`
+	// Replacement is the new content of the lines, without the trailing new
+	// line. An empty replacement removes the lines.
+	Replacement string `json:"replacement"`
+}
+
+// SetCommentExtension encodes ext at the end of the text of c, replacing the
+// previous one if any. A nil or empty ext removes it. c is not modified if ext
+// can not be encoded.
+func SetCommentExtension(c *Comment, ext *CommentExtension) error {
+	text, _ := SplitCommentExtension(c.Text)
+	if ext == nil || *ext == (CommentExtension{}) {
+		c.Text = text
+		return nil
+	}
+
+	// json.Marshal escapes '>', so the encoded extension can't end the HTML
+	// comment early
+	b, err := json.Marshal(ext)
+	if err != nil {
+		return err
+	}
+
+	if text != "" {
+		text += "\n"
+	}
+
+	c.Text = text + extensionPrefix + string(b) + extensionSuffix
+	return nil
+}
+
+// SetCommentRange makes c span the lines from start to end, inclusive
+func SetCommentRange(c *Comment, start, end int32) error {
+	_, ext := SplitCommentExtension(c.Text)
+	if ext == nil {
+		ext = &CommentExtension{}
+	}
+
+	c.Line = end
+	ext.StartLine = 0
+	if start > 0 && start < end {
+		ext.StartLine = start
+	}
+
+	return SetCommentExtension(c, ext)
+}
+
+// CommentRange returns the first and last lines of c. Both are c.Line if the
+// comment is on a single line.
+func CommentRange(c *Comment) (start, end int32) {
+	_, ext := SplitCommentExtension(c.Text)
+	if ext == nil || ext.StartLine <= 0 || ext.StartLine >= c.Line {
+		return c.Line, c.Line
+	}
+
+	return ext.StartLine, c.Line
+}
+
+// SuggestChange adds the Suggestion s to c, and makes c span the lines it
+// replaces
+func SuggestChange(c *Comment, s Suggestion) error {
+	if err := SetCommentRange(c, s.StartLine, s.EndLine); err != nil {
+		return err
+	}
+
+	_, ext := SplitCommentExtension(c.Text)
+	if ext == nil {
+		ext = &CommentExtension{}
+	}
+
+	ext.Suggestion = &s
+	return SetCommentExtension(c, ext)
+}
+
+// SplitCommentExtension returns the text of a comment without the encoded
+// extension, and the extension, or nil if there is none or it is not valid
+func SplitCommentExtension(text string) (string, *CommentExtension) {
+	trimmed := strings.TrimRight(text, " \t\r\n")
+	i := strings.LastIndex(trimmed, extensionPrefix)
+	if i < 0 || !strings.HasSuffix(trimmed, extensionSuffix) {
+		return text, nil
+	}
+
+	encoded := trimmed[i+len(extensionPrefix) : len(trimmed)-len(extensionSuffix)]
+	var ext CommentExtension
+	if err := json.Unmarshal([]byte(encoded), &ext); err != nil {
+		return text, nil
+	}
+
+	return strings.TrimRight(trimmed[:i], " \t\r\n"), &ext
+}
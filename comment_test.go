@@ -0,0 +1,70 @@
+package lookout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentExtension(t *testing.T) {
+	require := require.New(t)
+
+	c := &Comment{File: "main.go", Line: 1, Text: "Use a constant"}
+	require.NoError(SuggestChange(c, Suggestion{
+		StartLine:   2,
+		EndLine:     3,
+		Replacement: "a --> b",
+	}))
+	require.Equal(int32(3), c.Line)
+
+	text, ext := SplitCommentExtension(c.Text)
+	require.Equal("Use a constant", text)
+	require.Equal(&CommentExtension{StartLine: 2, Suggestion: &Suggestion{
+		StartLine:   2,
+		EndLine:     3,
+		Replacement: "a --> b",
+	}}, ext)
+
+	// the extension is replaced
+	require.NoError(SuggestChange(c, Suggestion{StartLine: 4, EndLine: 4}))
+	text, ext = SplitCommentExtension(c.Text)
+	require.Equal("Use a constant", text)
+	require.Equal(&Suggestion{StartLine: 4, EndLine: 4}, ext.Suggestion)
+
+	require.NoError(SetCommentExtension(c, nil))
+	require.Equal("Use a constant", c.Text)
+}
+
+func TestCommentRange(t *testing.T) {
+	require := require.New(t)
+
+	c := &Comment{File: "main.go", Line: 4, Text: "Long function"}
+	start, end := CommentRange(c)
+	require.Equal(int32(4), start)
+	require.Equal(int32(4), end)
+
+	require.NoError(SetCommentRange(c, 2, 8))
+	require.Equal(int32(8), c.Line)
+	start, end = CommentRange(c)
+	require.Equal(int32(2), start)
+	require.Equal(int32(8), end)
+
+	// a single line range removes the extension
+	require.NoError(SetCommentRange(c, 8, 8))
+	require.Equal("Long function", c.Text)
+}
+
+func TestSplitCommentExtensionInvalid(t *testing.T) {
+	require := require.New(t)
+
+	for _, text := range []string{
+		"",
+		"no extension",
+		"bad json\n<!-- lookout-extension: {bad -->",
+		"not at the end <!-- lookout-extension: {} --> text",
+	} {
+		res, ext := SplitCommentExtension(text)
+		require.Equal(text, res)
+		require.Nil(ext)
+	}
+}
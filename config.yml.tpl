@@ -9,6 +9,9 @@ analyzers:
 providers:
   github:
     comment_footer: "_{{if .Feedback}}If you have feedback about this comment made by the analyzer {{.Name}}, please, [tell us]({{.Feedback}}){{else}}Comment made by the analyzer {{.Name}}{{end}}._"
+    # What to do with the comments on lines that are not part of the diff:
+    # drop, nearest (post them on the nearest added line) or body (post them in the review body)
+    # out_of_diff_comments: drop
     # The minimum watch interval to discover new pull requests and push events
     watch_min_interval: 2s
     # Authorization with GitHub App
@@ -40,9 +43,52 @@ timeout:
   analyzer_review: 10m
   # Timeout for an analyzer to reply a NotifyPushEvent
   analyzer_push: 60m
+  # Time an analyzer that reported the progress of an event can go without
+  # reporting it again, before its request is canceled
+  analyzer_heartbeat: 5m
   # Timeout for an HTTP requests to the GitHub API
   github_request: 1m
   # Timeout for Git fetch actions
   git_fetch: 20m
   # Timeout for Bblfsh to reply a Parse request
   bblfsh_parse: 2m
+
+# These are the default circuit breaker values
+circuit_breaker:
+  # Number of consecutive analyzer failures that skip the analyzer. 0 disables it
+  threshold: 5
+  # Time to wait before trying again an analyzer that is skipped
+  probe_interval: 1m
+
+# Reuse the analyzer results when the same revision is analyzed again
+analyzer_cache:
+  # Time to reuse the analyzer results. 0 disables the cache
+  ttl: 0
+  # Maximum number of results kept. 0 means no limit
+  max_entries: 10000
+
+# Clean up the git library periodically
+library:
+  # Time between two cleanups. 0 disables the cleanup
+  gc_interval: 0
+  # Maximum size of the library in MB. 0 means no limit
+  max_size_mb: 0
+  # Time the recently used repositories and objects are kept
+  grace_period: 1h
+  # Number of commits fetched from each reference, deepened when needed. 0
+  # fetches the whole history
+  fetch_depth: 0
+  # Size of the git objects cache shared by all the repositories. 0 means 96
+  # MB
+  cache_size_mb: 0
+
+# Read the repositories of a host from a local mirror of bare repositories
+# instead of fetching them
+#mirrors:
+#  - host: github.com
+#    path: /var/lib/gitolite/repositories
+
+# Fetch the content of the files stored with Git LFS
+#lfs:
+#  enabled: true
+#  max_size_mb: 10
@@ -0,0 +1,180 @@
+package lookout
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/bblfsh/sdk.v1/uast"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+)
+
+// The FilesRequest and ChangesRequest of the SDK have no options to limit the
+// contents of the files, they are sent in the gRPC metadata of the GetFiles
+// and GetChanges calls instead. The files whose content was not loaded are
+// still returned, without Content nor UAST, and the reason is sent in a field
+// of the File message that is defined here instead of in the SDK protocol. The
+// clients that do not know it ignore it:
+//
+//	message File {
+//	    string path = 1;
+//	    uint32 mode = 2;
+//	    string hash = 3;
+//	    bytes content = 4;
+//	    gopkg.in.bblfsh.sdk.v1.uast.Node uast = 5;
+//	    string language = 6;
+//	    string skip_reason = 7;
+//	}
+const (
+	// MaxContentSizeMetadata is the gRPC metadata key of the GetFiles and
+	// GetChanges calls with the maximum size, in bytes, of the contents of
+	// the files
+	MaxContentSizeMetadata = "lookout-max-content-size"
+	// ExcludeBinaryMetadata is the gRPC metadata key of the GetFiles and
+	// GetChanges calls that do not want the contents of the binary files,
+	// "true" to enable it
+	ExcludeBinaryMetadata = "lookout-exclude-binary"
+)
+
+// The reasons why the content of a file was not loaded
+const (
+	// SkipTooBig is the reason of the files bigger than the maximum size
+	SkipTooBig = "too-big"
+	// SkipBinary is the reason of the binary files
+	SkipBinary = "binary"
+	// SkipLFSUnavailable is the reason of the files stored with Git LFS whose
+	// object could not be fetched
+	SkipLFSUnavailable = "lfs-unavailable"
+)
+
+// ContentLimits configures which contents of the files are loaded by the
+// GetFiles and GetChanges calls that want them
+type ContentLimits struct {
+	// MaxSize is the maximum size of the contents in bytes, zero means no
+	// limit
+	MaxSize int64
+	// ExcludeBinary does not load the contents of the binary files, detected
+	// like git does
+	ExcludeBinary bool
+}
+
+// IsZero returns true if l does not limit the contents
+func (l ContentLimits) IsZero() bool {
+	return l == ContentLimits{}
+}
+
+// WithContentLimits returns a copy of ctx that makes the GetFiles and
+// GetChanges calls made with it limit the contents of the files to l
+func WithContentLimits(ctx context.Context, l ContentLimits) context.Context {
+	if l.MaxSize > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			MaxContentSizeMetadata, strconv.FormatInt(l.MaxSize, 10))
+	}
+
+	if l.ExcludeBinary {
+		ctx = metadata.AppendToOutgoingContext(ctx, ExcludeBinaryMetadata, "true")
+	}
+
+	return ctx
+}
+
+// ContentLimitsFromContext returns the content limits sent by the analyzer in
+// the metadata of a GetFiles or GetChanges call. For the calls made inside the
+// same process, it falls back to the ones set with WithContentLimits. The
+// contents are not limited if they are not present.
+func ContentLimitsFromContext(ctx context.Context) (ContentLimits, error) {
+	var l ContentLimits
+	if val, ok := metadataValue(ctx, MaxContentSizeMetadata); ok {
+		size, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || size < 0 {
+			return l, fmt.Errorf("invalid max content size %q", val)
+		}
+
+		l.MaxSize = size
+	}
+
+	val, _ := metadataValue(ctx, ExcludeBinaryMetadata)
+	l.ExcludeBinary = val == "true"
+	return l, nil
+}
+
+// SkipScanner is a FileScanner or a ChangeScanner that also returns why the
+// contents of the files of the current file or change were not loaded. The
+// scanners that wrap another one implement it by returning the reasons of the
+// underlying scanner.
+type SkipScanner interface {
+	// SkipReason returns why the content of f, a file of the current file or
+	// change, was not loaded, or "" if it was loaded or the reason is
+	// unknown.
+	SkipReason(f *File) string
+}
+
+// ScannerSkipReason returns why the content of the file f of the current file
+// or change of s was not loaded, or "" if s is not a SkipScanner
+func ScannerSkipReason(s interface{}, f *File) string {
+	if ss, ok := s.(SkipScanner); ok && f != nil {
+		return ss.SkipReason(f)
+	}
+
+	return ""
+}
+
+// skippedFile is a File with the reason why its content was not loaded, as
+// sent by the data server
+type skippedFile struct {
+	Path       string     `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Mode       uint32     `protobuf:"varint,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	Hash       string     `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	Content    []byte     `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	UAST       *uast.Node `protobuf:"bytes,5,opt,name=uast,proto3" json:"uast,omitempty"`
+	Language   string     `protobuf:"bytes,6,opt,name=language,proto3" json:"language,omitempty"`
+	SkipReason string     `protobuf:"bytes,7,opt,name=skip_reason,json=skipReason,proto3" json:"skip_reason,omitempty"`
+}
+
+func (m *skippedFile) Reset()         { *m = skippedFile{} }
+func (m *skippedFile) String() string { return m.file().String() }
+func (*skippedFile) ProtoMessage()    {}
+
+// newSkippedFile returns the message of f with the reason of s, nil if f is nil
+func newSkippedFile(s interface{}, f *File) *skippedFile {
+	if f == nil {
+		return nil
+	}
+
+	return &skippedFile{
+		Path:       f.Path,
+		Mode:       f.Mode,
+		Hash:       f.Hash,
+		Content:    f.Content,
+		UAST:       f.UAST,
+		Language:   f.Language,
+		SkipReason: ScannerSkipReason(s, f),
+	}
+}
+
+func (m *skippedFile) file() *File {
+	if m == nil {
+		return nil
+	}
+
+	return &pb.File{
+		Path:     m.Path,
+		Mode:     m.Mode,
+		Hash:     m.Hash,
+		Content:  m.Content,
+		UAST:     m.UAST,
+		Language: m.Language,
+	}
+}
+
+// skipReasons holds the reasons of the files of the current file or change
+// received by a client scanner
+type skipReasons map[*File]string
+
+// add adds the reason of the message m of the file f
+func (r skipReasons) add(f *File, m *skippedFile) {
+	if f != nil && m.SkipReason != "" {
+		r[f] = m.SkipReason
+	}
+}
@@ -0,0 +1,141 @@
+package lookout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// pathSkipScanner is a SliceFileScanner that returns the reasons of the files
+// by path
+type pathSkipScanner struct {
+	SliceFileScanner
+	reasons map[string]string
+}
+
+func (s *pathSkipScanner) SkipReason(f *File) string {
+	return s.reasons[f.Path]
+}
+
+// pathSkipChangeScanner is a SliceChangeScanner that returns the reasons of
+// the files by path
+type pathSkipChangeScanner struct {
+	SliceChangeScanner
+	reasons map[string]string
+}
+
+func (s *pathSkipChangeScanner) SkipReason(f *File) string {
+	return s.reasons[f.Path]
+}
+
+func TestDataClientGetFilesSkipReasons(t *testing.T) {
+	require := require.New(t)
+
+	req := &FilesRequest{
+		Revision: &ReferencePointer{
+			InternalRepositoryURL: "repo",
+			Hash:                  "5262fd2b59d10e335a5c941140df16950958322d",
+		},
+		WantContents: true,
+	}
+	files := generateFiles(3)
+	files[0].Content = []byte("content")
+	reasons := map[string]string{"myfile1": SkipTooBig, "myfile2": SkipBinary}
+	dr := &MockService{
+		T:                t,
+		ExpectedFRequest: req,
+		FileScanner: &FnFileScanner{
+			Scanner: &pathSkipScanner{
+				SliceFileScanner: SliceFileScanner{Files: files},
+				reasons:          reasons,
+			},
+			Fn: func(*File) (bool, error) { return false, nil },
+		},
+	}
+
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {
+		RegisterDataServer(s, &DataServerHandler{FileGetter: dr})
+	})
+	defer stop()
+
+	s, err := NewDataClient(conn).GetFiles(context.TODO(), req)
+	require.NoError(err)
+
+	var scanned []*File
+	for s.Next() {
+		f := s.File()
+		scanned = append(scanned, f)
+		require.Equal(reasons[f.Path], ScannerSkipReason(s, f))
+	}
+
+	require.NoError(s.Err())
+	require.Equal(files, scanned)
+}
+
+func TestDataClientGetChangesSkipReasons(t *testing.T) {
+	require := require.New(t)
+
+	req := &ChangesRequest{
+		Head: &ReferencePointer{
+			InternalRepositoryURL: "repo",
+			Hash:                  "5262fd2b59d10e335a5c941140df16950958322d",
+		},
+		WantContents: true,
+	}
+	changes := []*Change{
+		{Base: &File{Path: "a.bin"}, Head: &File{Path: "a.bin"}},
+		{Head: &File{Path: "b.go", Content: []byte("content")}},
+	}
+	reasons := map[string]string{"a.bin": SkipBinary}
+	dr := &MockService{
+		T:                t,
+		ExpectedCRequest: req,
+		ChangeScanner: &pathSkipChangeScanner{
+			SliceChangeScanner: SliceChangeScanner{Changes: changes},
+			reasons:            reasons,
+		},
+	}
+
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {
+		RegisterDataServer(s, &DataServerHandler{ChangeGetter: dr})
+	})
+	defer stop()
+
+	s, err := NewDataClient(conn).GetChanges(context.TODO(), req)
+	require.NoError(err)
+
+	var scanned []*Change
+	for s.Next() {
+		ch := s.Change()
+		scanned = append(scanned, ch)
+		if ch.Base != nil {
+			require.Equal(reasons[ch.Base.Path], ScannerSkipReason(s, ch.Base))
+		}
+
+		require.Equal(reasons[ch.Head.Path], ScannerSkipReason(s, ch.Head))
+	}
+
+	require.NoError(s.Err())
+	require.Equal(changes, scanned)
+}
+
+func TestContentLimitsFromContext(t *testing.T) {
+	require := require.New(t)
+
+	l, err := ContentLimitsFromContext(context.Background())
+	require.NoError(err)
+	require.True(l.IsZero())
+
+	expected := ContentLimits{MaxSize: 1024, ExcludeBinary: true}
+	l, err = ContentLimitsFromContext(WithContentLimits(context.Background(), expected))
+	require.NoError(err)
+	require.Equal(expected, l)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(MaxContentSizeMetadata, "-1"))
+	_, err = ContentLimitsFromContext(ctx)
+	require.EqualError(err, `invalid max content size "-1"`)
+}
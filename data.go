@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"gopkg.in/src-d/go-git.v4/utils/ioutil"
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
 )
@@ -15,6 +16,27 @@ type FilesRequest = pb.FilesRequest
 type Change = pb.Change
 type File = pb.File
 
+// metadataValue returns the last value of the gRPC metadata key sent by the
+// client in ctx. For the calls made inside the same process, it falls back to
+// the metadata set for the outgoing calls of ctx.
+func metadataValue(ctx context.Context, key string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md, ok = metadata.FromOutgoingContext(ctx)
+	}
+
+	if !ok {
+		return "", false
+	}
+
+	vals := md.Get(key)
+	if len(vals) == 0 {
+		return "", false
+	}
+
+	return vals[len(vals)-1], true
+}
+
 // ChangeGetter is used to retrieve code changes.
 type ChangeGetter interface {
 	// GetChanges returns a ChangeScanner that scans all changes according
@@ -31,6 +53,7 @@ type FileGetter interface {
 
 func RegisterDataServer(s *grpc.Server, srv *DataServerHandler) {
 	pb.RegisterDataServer(s, srv)
+	s.RegisterService(&progressServiceDesc, srv)
 }
 
 // ChangeScanner is a scanner for changes.
@@ -66,6 +89,9 @@ type FileScanner interface {
 type DataServerHandler struct {
 	ChangeGetter ChangeGetter
 	FileGetter   FileGetter
+	// ProgressReporter receives the progress reported by the analyzers. Can
+	// be left unset.
+	ProgressReporter ProgressReporter
 }
 
 var _ pb.DataServer = &DataServerHandler{}
@@ -89,7 +115,20 @@ func (s *DataServerHandler) GetChanges(req *ChangesRequest,
 		default:
 		}
 
-		if err := srv.Send(iter.Change()); err != nil {
+		ch := iter.Change()
+		hunks := ScannerHunks(iter)
+		if hunks != nil || ScannerSkipReason(iter, ch.Base) != "" ||
+			ScannerSkipReason(iter, ch.Head) != "" {
+			err = srv.SendMsg(&changeHunks{
+				Base:  newSkippedFile(iter, ch.Base),
+				Head:  newSkippedFile(iter, ch.Head),
+				Hunks: hunks,
+			})
+		} else {
+			err = srv.Send(ch)
+		}
+
+		if err != nil {
 			return err
 		}
 	}
@@ -118,7 +157,14 @@ func (s *DataServerHandler) GetFiles(req *FilesRequest, srv pb.Data_GetFilesServ
 		default:
 		}
 
-		if err := srv.Send(iter.File()); err != nil {
+		f := iter.File()
+		if ScannerSkipReason(iter, f) != "" {
+			err = srv.SendMsg(newSkippedFile(iter, f))
+		} else {
+			err = srv.Send(f)
+		}
+
+		if err != nil {
 			return err
 		}
 	}
@@ -131,11 +177,13 @@ func (s *DataServerHandler) GetFiles(req *FilesRequest, srv pb.Data_GetFilesServ
 }
 
 type DataClient struct {
+	cc         *grpc.ClientConn
 	dataClient pb.DataClient
 }
 
 func NewDataClient(cc *grpc.ClientConn) *DataClient {
 	return &DataClient{
+		cc:         cc,
 		dataClient: pb.NewDataClient(cc),
 	}
 }
@@ -177,18 +225,27 @@ func (c *DataClient) GetFiles(ctx context.Context, in *FilesRequest, opts ...grp
 }
 
 type ClientChangeScanner struct {
-	client pb.Data_GetChangesClient
-	val    *Change
-	err    error
-	done   bool
+	client  pb.Data_GetChangesClient
+	val     *Change
+	hunks   []*Hunk
+	skipped skipReasons
+	err     error
+	done    bool
 }
 
+var _ HunkScanner = &ClientChangeScanner{}
+var _ SkipScanner = &ClientChangeScanner{}
+
 func (s *ClientChangeScanner) Next() bool {
 	if s.done {
 		return false
 	}
 
-	s.val, s.err = s.client.Recv()
+	// the change is received with its hunks, see WithHunks, and the reasons
+	// of its files, see WithContentLimits
+	m := new(changeHunks)
+	s.val, s.hunks, s.skipped = nil, nil, nil
+	s.err = s.client.RecvMsg(m)
 	if s.err == io.EOF {
 		s.err = nil
 		s.done = true
@@ -200,6 +257,10 @@ func (s *ClientChangeScanner) Next() bool {
 		return false
 	}
 
+	s.val, s.hunks = m.change(), m.Hunks
+	s.skipped = make(skipReasons)
+	s.skipped.add(s.val.Base, m.Base)
+	s.skipped.add(s.val.Head, m.Head)
 	return true
 }
 
@@ -211,23 +272,43 @@ func (s *ClientChangeScanner) Change() *Change {
 	return s.val
 }
 
+// Hunks returns the changed lines of the current change, they are only sent
+// by lookoutd if they were asked for with WithHunks
+func (s *ClientChangeScanner) Hunks() []*Hunk {
+	return s.hunks
+}
+
+// SkipReason returns why the content of f, a file of the current change, was
+// not loaded, it is only sent by lookoutd if the contents were limited with
+// WithContentLimits
+func (s *ClientChangeScanner) SkipReason(f *File) string {
+	return s.skipped[f]
+}
+
 func (s *ClientChangeScanner) Close() error {
 	return nil
 }
 
 type ClientFileScanner struct {
-	client pb.Data_GetFilesClient
-	val    *File
-	err    error
-	done   bool
+	client  pb.Data_GetFilesClient
+	val     *File
+	skipped string
+	err     error
+	done    bool
 }
 
+var _ SkipScanner = &ClientFileScanner{}
+
 func (s *ClientFileScanner) Next() bool {
 	if s.done {
 		return false
 	}
 
-	s.val, s.err = s.client.Recv()
+	// the file is received with the reason of its content, see
+	// WithContentLimits
+	m := new(skippedFile)
+	s.val, s.skipped = nil, ""
+	s.err = s.client.RecvMsg(m)
 	if s.err == io.EOF {
 		s.err = nil
 		s.done = true
@@ -239,6 +320,7 @@ func (s *ClientFileScanner) Next() bool {
 		return false
 	}
 
+	s.val, s.skipped = m.file(), m.SkipReason
 	return true
 }
 
@@ -250,6 +332,17 @@ func (s *ClientFileScanner) File() *File {
 	return s.val
 }
 
+// SkipReason returns why the content of the current file was not loaded, it
+// is only sent by lookoutd if the contents were limited with
+// WithContentLimits
+func (s *ClientFileScanner) SkipReason(f *File) string {
+	if f != s.val {
+		return ""
+	}
+
+	return s.skipped
+}
+
 func (s *ClientFileScanner) Close() error {
 	return nil
 }
@@ -314,6 +407,14 @@ func (s *FnChangeScanner) Change() *Change {
 	return s.val
 }
 
+func (s *FnChangeScanner) Hunks() []*Hunk {
+	return ScannerHunks(s.Scanner)
+}
+
+func (s *FnChangeScanner) SkipReason(f *File) string {
+	return ScannerSkipReason(s.Scanner, f)
+}
+
 func (s *FnChangeScanner) Close() error {
 	return s.Scanner.Close()
 }
@@ -378,6 +479,10 @@ func (s *FnFileScanner) File() *File {
 	return s.val
 }
 
+func (s *FnFileScanner) SkipReason(f *File) string {
+	return ScannerSkipReason(s.Scanner, f)
+}
+
 func (s *FnFileScanner) Close() error {
 	return s.Scanner.Close()
 }
@@ -12,6 +12,7 @@ import Callback from './Callback';
 import Loader from './components/Loader';
 import Organization from './components/Organization';
 import Organizations from './components/Organizations';
+import Repository from './components/Repository';
 import Auth, { User } from './services/auth';
 
 function Login() {
@@ -76,6 +77,32 @@ function Org({ user, match }: OrgProps) {
   );
 }
 
+interface RepoMatchParams {
+  name: string;
+  repo: string;
+}
+
+interface RepoProps extends RouteComponentProps<RepoMatchParams> {
+  user: User;
+}
+
+function Repo({ user, match }: RepoProps) {
+  return (
+    <div>
+      <Header user={user} />
+      <Repository
+        user={user}
+        orgName={match.params.name}
+        repoName={match.params.repo}
+      />
+      <div>
+        <br />
+        <a href={`/org/${match.params.name}`}>Back to organization</a>
+      </div>
+    </div>
+  );
+}
+
 interface PrivateRouteState {
   isAuthenticated: boolean | undefined;
 }
@@ -142,6 +169,11 @@ function AppRouter() {
       <div className="App">
         <PrivateRoute path="/" exact={true} component={Index} />
         <PrivateRoute path="/org/:name" exact={true} component={Org} />
+        <PrivateRoute
+          path="/org/:name/repo/:repo"
+          exact={true}
+          component={Repo}
+        />
         <Route path="/login" component={Login} />
         <Route path="/logout" component={Logout} />
         <Route path="/callback" component={Callback} />
@@ -116,3 +116,57 @@ export function updateConfig(
     body: { config }
   });
 }
+
+export interface RepoListItem {
+  id: number;
+  name: string;
+}
+
+interface ReposResponse extends Array<RepoListItem> {}
+
+// Returns a list of the organization repositories where lookout is installed
+export function repos(orgName: string): Promise<ReposResponse> {
+  return apiCall<ReposResponse>(`/api/org/${orgName}/repos`);
+}
+
+export interface RepoResponse {
+  id: number;
+  name: string;
+  config: string;
+  effective_config: string;
+}
+
+// Returns info about the individual repository
+export function repo(orgName: string, name: string): Promise<RepoResponse> {
+  return apiCall<RepoResponse>(`/api/org/${orgName}/repo/${name}`);
+}
+
+// Updates the repository config
+export function updateRepoConfig(
+  orgName: string,
+  name: string,
+  config: string
+): Promise<RepoResponse> {
+  return apiCall<RepoResponse>(`/api/org/${orgName}/repo/${name}`, {
+    method: 'PUT',
+    body: { config }
+  });
+}
+
+export interface ExplainResponse {
+  ref: string;
+  config: string;
+}
+
+// Returns the effective repository config, annotated with the layer each
+// value comes from. If ref is empty the default branch is used
+export function explainRepoConfig(
+  orgName: string,
+  name: string,
+  ref: string = ''
+): Promise<ExplainResponse> {
+  const query = ref ? `?ref=${encodeURIComponent(ref)}` : '';
+  return apiCall<ExplainResponse>(
+    `/api/org/${orgName}/repo/${name}/explain${query}`
+  );
+}
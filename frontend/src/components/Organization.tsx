@@ -3,6 +3,7 @@ import * as api from '../api';
 import { User } from '../services/auth';
 import Errors from './Errors';
 import Loader from './Loader';
+import Repositories from './Repositories';
 
 interface OrgProps {
   user: User;
@@ -80,6 +81,7 @@ class Organization extends React.Component<OrgProps, OrgState> {
           <br />
           <button onClick={this.handleConfigSave}>Save</button>
         </div>
+        <Repositories orgName={this.state.org.name} />
       </div>
     );
   }
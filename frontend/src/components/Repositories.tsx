@@ -0,0 +1,68 @@
+import React from 'react';
+import * as api from '../api';
+import Errors from './Errors';
+import Loader from './Loader';
+
+interface ReposProps {
+  orgName: string;
+}
+
+interface ReposState {
+  done: boolean;
+  repos: api.RepoListItem[];
+  errors: string[];
+}
+
+class Repositories extends React.Component<ReposProps, ReposState> {
+  public state: ReposState = {
+    done: false,
+    repos: [],
+    errors: []
+  };
+
+  public componentDidMount() {
+    return api
+      .repos(this.props.orgName)
+      .then(resp => {
+        this.setState({
+          done: true,
+          repos: resp,
+          errors: []
+        });
+      })
+      .catch(err => {
+        this.setState({
+          done: true,
+          repos: [],
+          errors: err
+        });
+      });
+  }
+
+  public render() {
+    if (!this.state.done) {
+      return <Loader />;
+    }
+
+    if (this.state.errors.length > 0) {
+      return <Errors errors={this.state.errors} />;
+    }
+
+    const repos = this.state.repos.map(repo => (
+      <li key={repo.name}>
+        <a href={`/org/${this.props.orgName}/repo/${repo.name}`}>
+          {repo.name}
+        </a>
+      </li>
+    ));
+
+    return (
+      <div>
+        <h2>Repositories</h2>
+        <ul>{repos}</ul>
+      </div>
+    );
+  }
+}
+
+export default Repositories;
@@ -0,0 +1,145 @@
+import React from 'react';
+import * as api from '../api';
+import { User } from '../services/auth';
+import Errors from './Errors';
+import Loader from './Loader';
+
+interface RepoProps {
+  user: User;
+  orgName: string;
+  repoName: string;
+}
+
+interface RepoState {
+  done: boolean;
+  repo: api.RepoResponse | undefined;
+  errors: string[];
+
+  config: string;
+  explained: string;
+}
+
+class Repository extends React.Component<RepoProps, RepoState> {
+  constructor(props: RepoProps) {
+    super(props);
+
+    this.state = {
+      done: false,
+      repo: undefined,
+      errors: [],
+      config: '',
+      explained: ''
+    };
+
+    this.handleConfigChange = this.handleConfigChange.bind(this);
+    this.handleConfigSave = this.handleConfigSave.bind(this);
+  }
+
+  public componentDidMount() {
+    return api
+      .repo(this.props.orgName, this.props.repoName)
+      .then(resp => {
+        this.setState({
+          done: true,
+          repo: resp,
+          errors: [],
+          config: resp.config
+        });
+        this.explain();
+      })
+      .catch(err => {
+        this.setState({
+          done: true,
+          repo: undefined,
+          errors: err,
+          config: ''
+        });
+      });
+  }
+
+  public render() {
+    if (!this.state.done) {
+      return <Loader />;
+    }
+
+    if (this.state.errors.length > 0) {
+      return <Errors errors={this.state.errors} />;
+    }
+
+    if (this.state.repo === undefined) {
+      return (
+        <Errors errors={["Internal error, 'repo' should not be undefined"]} />
+      );
+    }
+
+    return (
+      <div>
+        <h1>
+          Settings for Repository {this.props.orgName}/{this.state.repo.name}
+        </h1>
+        <textarea
+          value={this.state.config}
+          onChange={this.handleConfigChange}
+          style={{ font: 'monospace', width: '300px', height: '10em' }}
+        />
+        <div>
+          <br />
+          <button onClick={this.handleConfigSave}>Save</button>
+        </div>
+        <h2>Effective configuration for the default branch</h2>
+        <pre>{this.state.repo.effective_config}</pre>
+        <h2>Where each value comes from</h2>
+        <pre>{this.state.explained}</pre>
+      </div>
+    );
+  }
+
+  private explain() {
+    api
+      .explainRepoConfig(this.props.orgName, this.props.repoName)
+      .then(resp => this.setState({ explained: resp.config }))
+      .catch(err => this.setState({ errors: err }));
+  }
+
+  private handleConfigChange(event: React.ChangeEvent<HTMLTextAreaElement>) {
+    this.setState({ config: event.target.value });
+  }
+
+  private handleConfigSave() {
+    if (this.state.repo === undefined) {
+      this.setState({
+        done: true,
+        errors: [
+          'Internal error, handleConfigSave called with undefined state.repo'
+        ]
+      });
+      return;
+    }
+
+    api
+      .updateRepoConfig(
+        this.props.orgName,
+        this.state.repo.name,
+        this.state.config
+      )
+      .then(resp => {
+        this.setState({
+          done: true,
+          repo: resp,
+          errors: [],
+          config: resp.config
+        });
+        this.explain();
+      })
+      .catch(err => {
+        this.setState({
+          done: true,
+          repo: undefined,
+          errors: err,
+          config: ''
+        });
+      });
+  }
+}
+
+export default Repository;
@@ -0,0 +1,100 @@
+package lookout
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+)
+
+// The changed lines of each Change are sent in a field of the Change message
+// that is defined here instead of in the SDK protocol. The clients that do
+// not know it ignore it:
+//
+//	message Change {
+//	    File base = 1;
+//	    File head = 2;
+//	    repeated Hunk hunks = 3;
+//	}
+//
+//	message Hunk {
+//	    int32 base_start = 1;
+//	    int32 base_lines = 2;
+//	    int32 head_start = 3;
+//	    int32 head_lines = 4;
+//	}
+//
+// They are only computed if the analyzer asks for them with the
+// WantHunksMetadata metadata of the GetChanges call.
+
+// WantHunksMetadata is the gRPC metadata key of the GetChanges calls that
+// want the changed lines of each change, "true" to enable it
+const WantHunksMetadata = "lookout-want-hunks"
+
+// Hunk is a range of changed lines, like a hunk of a unified diff without
+// context lines. The lines are numbered from 1. A range without lines starts
+// at the line before the lines removed or added in the other file, 0 for the
+// start of the file.
+type Hunk struct {
+	BaseStart int32 `protobuf:"varint,1,opt,name=base_start,json=baseStart,proto3" json:"base_start,omitempty"`
+	BaseLines int32 `protobuf:"varint,2,opt,name=base_lines,json=baseLines,proto3" json:"base_lines,omitempty"`
+	HeadStart int32 `protobuf:"varint,3,opt,name=head_start,json=headStart,proto3" json:"head_start,omitempty"`
+	HeadLines int32 `protobuf:"varint,4,opt,name=head_lines,json=headLines,proto3" json:"head_lines,omitempty"`
+}
+
+func (m *Hunk) Reset()      { *m = Hunk{} }
+func (*Hunk) ProtoMessage() {}
+func (m *Hunk) String() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", m.BaseStart, m.BaseLines, m.HeadStart, m.HeadLines)
+}
+
+// changeHunks is a Change with its hunks and the reasons of its files, as
+// sent by the data server
+type changeHunks struct {
+	Base  *skippedFile `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+	Head  *skippedFile `protobuf:"bytes,2,opt,name=head,proto3" json:"head,omitempty"`
+	Hunks []*Hunk      `protobuf:"bytes,3,rep,name=hunks,proto3" json:"hunks,omitempty"`
+}
+
+func (m *changeHunks) Reset()         { *m = changeHunks{} }
+func (m *changeHunks) String() string { return m.change().String() }
+func (*changeHunks) ProtoMessage()    {}
+
+func (m *changeHunks) change() *Change {
+	return &pb.Change{Base: m.Base.file(), Head: m.Head.file()}
+}
+
+// HunkScanner is a ChangeScanner that also returns the changed lines of each
+// change. The scanners that wrap another one implement it by returning the
+// hunks of the underlying scanner.
+type HunkScanner interface {
+	ChangeScanner
+	// Hunks returns the changed lines of the current change, nil if they
+	// are unknown.
+	Hunks() []*Hunk
+}
+
+// ScannerHunks returns the hunks of the current change of s, or nil if s is
+// not a HunkScanner
+func ScannerHunks(s ChangeScanner) []*Hunk {
+	if hs, ok := s.(HunkScanner); ok {
+		return hs.Hunks()
+	}
+
+	return nil
+}
+
+// WithHunks returns a copy of ctx that makes the GetChanges calls made with it
+// return the changed lines of each change
+func WithHunks(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, WantHunksMetadata, "true")
+}
+
+// WantHunksFromContext returns true if the analyzer asked for the changed
+// lines in the metadata of a GetChanges call. For the calls made inside the
+// same process, it falls back to the context set with WithHunks.
+func WantHunksFromContext(ctx context.Context) bool {
+	val, _ := metadataValue(ctx, WantHunksMetadata)
+	return val == "true"
+}
@@ -0,0 +1,110 @@
+package lookout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// sliceHunkScanner is a SliceChangeScanner with the same hunks for every
+// change
+type sliceHunkScanner struct {
+	SliceChangeScanner
+	hunks []*Hunk
+}
+
+func (s *sliceHunkScanner) Hunks() []*Hunk {
+	return s.hunks
+}
+
+func TestDataClientGetChangesHunks(t *testing.T) {
+	require := require.New(t)
+
+	req := &ChangesRequest{
+		Head: &ReferencePointer{
+			InternalRepositoryURL: "repo",
+			Hash:                  "5262fd2b59d10e335a5c941140df16950958322d",
+		},
+	}
+	hunks := []*Hunk{
+		{BaseStart: 2, BaseLines: 1, HeadStart: 2, HeadLines: 3},
+		{BaseStart: 10, BaseLines: 2, HeadStart: 11},
+	}
+	changes := generateChanges(2)
+	dr := &MockService{
+		T:                t,
+		ExpectedCRequest: req,
+		ChangeScanner: &FnChangeScanner{
+			Scanner: &sliceHunkScanner{
+				SliceChangeScanner: SliceChangeScanner{Changes: changes},
+				hunks:              hunks,
+			},
+			Fn: func(*Change) (bool, error) { return false, nil },
+		},
+	}
+
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {
+		RegisterDataServer(s, &DataServerHandler{ChangeGetter: dr})
+	})
+	defer stop()
+
+	s, err := NewDataClient(conn).GetChanges(WithHunks(context.TODO()), req)
+	require.NoError(err)
+
+	var scanned []*Change
+	for s.Next() {
+		scanned = append(scanned, s.Change())
+		require.Equal(hunks, ScannerHunks(s))
+	}
+
+	require.NoError(s.Err())
+	require.Equal(changes, scanned)
+}
+
+func TestDataClientGetChangesNoHunks(t *testing.T) {
+	require := require.New(t)
+
+	req := &ChangesRequest{
+		Head: &ReferencePointer{
+			InternalRepositoryURL: "repo",
+			Hash:                  "5262fd2b59d10e335a5c941140df16950958322d",
+		},
+	}
+	changes := generateChanges(2)
+	dr := &MockService{
+		T:                t,
+		ExpectedCRequest: req,
+		ChangeScanner:    &SliceChangeScanner{Changes: changes},
+	}
+
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {
+		RegisterDataServer(s, &DataServerHandler{ChangeGetter: dr})
+	})
+	defer stop()
+
+	s, err := NewDataClient(conn).GetChanges(context.TODO(), req)
+	require.NoError(err)
+
+	var scanned []*Change
+	for s.Next() {
+		scanned = append(scanned, s.Change())
+		require.Nil(ScannerHunks(s))
+	}
+
+	require.NoError(s.Err())
+	require.Equal(changes, scanned)
+}
+
+func TestWantHunksFromContext(t *testing.T) {
+	require := require.New(t)
+
+	require.False(WantHunksFromContext(context.Background()))
+	require.True(WantHunksFromContext(WithHunks(context.Background())))
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(WantHunksMetadata, "true"))
+	require.True(WantHunksFromContext(ctx))
+}
@@ -0,0 +1,93 @@
+package lookout
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lfsPointerVersion is the first line of the Git LFS pointer files
+const lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+// maxLFSPointerSize is the maximum size of a Git LFS pointer file, the bigger
+// files are never pointers
+const maxLFSPointerSize = 1024
+
+// LFSPointer is the content of a file stored with Git LFS in the repository,
+// it points to the object with the real content
+type LFSPointer struct {
+	// OID is the SHA-256 hash of the object, in hexadecimal
+	OID string
+	// Size is the size of the object in bytes
+	Size int64
+}
+
+// ParseLFSPointer parses the content of a Git LFS pointer file, it returns
+// false if content is not a pointer
+func ParseLFSPointer(content []byte) (*LFSPointer, bool) {
+	if len(content) > maxLFSPointerSize ||
+		!bytes.HasPrefix(content, []byte(lfsPointerVersion+"\n")) {
+		return nil, false
+	}
+
+	p := &LFSPointer{Size: -1}
+	for _, line := range strings.Split(string(content), "\n") {
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "oid":
+			p.OID = strings.TrimPrefix(kv[1], "sha256:")
+			if p.OID == kv[1] || !isSHA256(p.OID) {
+				return nil, false
+			}
+		case "size":
+			size, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil || size < 0 {
+				return nil, false
+			}
+
+			p.Size = size
+		}
+	}
+
+	if p.OID == "" || p.Size < 0 {
+		return nil, false
+	}
+
+	return p, true
+}
+
+// isSHA256 returns true if s is a SHA-256 hash in lowercase hexadecimal
+func isSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsLFSPointer returns true if the Content of f is a Git LFS pointer, the
+// content of the object it points to was not fetched
+func IsLFSPointer(f *File) bool {
+	if f == nil {
+		return false
+	}
+
+	_, ok := ParseLFSPointer(f.Content)
+	return ok
+}
+
+// String returns the content of the pointer file of p
+func (p *LFSPointer) String() string {
+	return fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerVersion, p.OID, p.Size)
+}
@@ -0,0 +1,47 @@
+package lookout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testLFSOID = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestParseLFSPointer(t *testing.T) {
+	require := require.New(t)
+
+	content := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:" + testLFSOID + "\n" +
+		"size 12345\n"
+
+	p, ok := ParseLFSPointer([]byte(content))
+	require.True(ok)
+	require.Equal(&LFSPointer{OID: testLFSOID, Size: 12345}, p)
+	require.Equal(content, p.String())
+
+	require.True(IsLFSPointer(&File{Content: []byte(content)}))
+	require.False(IsLFSPointer(&File{Content: []byte("package main\n")}))
+	require.False(IsLFSPointer(nil))
+}
+
+func TestParseLFSPointerInvalid(t *testing.T) {
+	version := "version https://git-lfs.github.com/spec/v1\n"
+	oid := "oid sha256:" + testLFSOID + "\n"
+
+	for name, content := range map[string]string{
+		"empty":      "",
+		"no version": oid + "size 1\n",
+		"no oid":     version + "size 1\n",
+		"no size":    version + oid,
+		"bad hash":   version + "oid md5:" + testLFSOID + "\n" + "size 1\n",
+		"short oid":  version + "oid sha256:4d7a\n" + "size 1\n",
+		"not hex":    version + "oid sha256:" + strings.Repeat("../", 21) + "a\n" + "size 1\n",
+		"bad size":   version + oid + "size -1\n",
+		"too big":    version + oid + "size 1\n" + strings.Repeat("x", 1024),
+	} {
+		_, ok := ParseLFSPointer([]byte(content))
+		require.False(t, ok, name)
+	}
+}
@@ -36,3 +36,24 @@ type Poster interface {
 	// Status sends the current analysis status to the provider
 	Status(context.Context, Event, AnalysisStatus) error
 }
+
+// SkippedAnalyzersPoster is a Poster that can also report the analyzers that
+// were skipped in the analysis
+type SkippedAnalyzersPoster interface {
+	Poster
+
+	// StatusSkipped sends the current analysis status to the provider, along
+	// with the names of the analyzers that were skipped because they are
+	// unavailable
+	StatusSkipped(ctx context.Context, e Event, st AnalysisStatus, skipped []string) error
+}
+
+// ProgressPoster is a Poster that can also report the progress of an analysis
+// while it is pending
+type ProgressPoster interface {
+	Poster
+
+	// StatusProgress sends a pending analysis status to the provider, with
+	// the given description of the analysis progress
+	StatusProgress(ctx context.Context, e Event, progress string) error
+}
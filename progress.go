@@ -0,0 +1,155 @@
+package lookout
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The Progress service is served by the data server, next to the Data
+// service, so the analyzers can report the progress of an analysis. Each
+// report also works as a heartbeat: once an analyzer reports the progress of
+// an event, lookoutd expects new reports regularly, and fails the analysis if
+// they stop. It is defined here instead of in the SDK protocol:
+//
+//	service Progress {
+//	    rpc ReportProgress (ProgressReport) returns (ProgressResponse);
+//	}
+//
+//	message ProgressReport {
+//	    string event_id = 1;
+//	    string analyzer = 2;
+//	    float percent = 3;
+//	    string description = 4;
+//	}
+//
+//	message ProgressResponse {}
+//
+// The messages, the client and the service descriptor below are written by
+// hand, following the code generated for the SDK services. They must be
+// replaced by the generated ones once the SDK defines the service.
+const progressServiceName = "pb.Progress"
+
+// AnalyzerNameMetadata is the gRPC metadata key of the Notify* calls with the
+// name of the analyzer in the lookoutd configuration
+const AnalyzerNameMetadata = "lookout-analyzer"
+
+// AnalyzerNameFromContext returns the name of the analyzer sent by lookoutd in
+// the context of a Notify* call, or "" if it is not present
+func AnalyzerNameFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	names := md.Get(AnalyzerNameMetadata)
+	if len(names) == 0 {
+		return ""
+	}
+
+	return names[0]
+}
+
+// ProgressReport is the progress of the analysis of an event by an analyzer
+type ProgressReport struct {
+	// EventID is the ID of the event being analyzed, as returned by its
+	// ID().String() method
+	EventID string `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// Analyzer is the name of the analyzer, as sent by lookoutd in the
+	// AnalyzerNameMetadata metadata. It can be empty if the analyzer is the
+	// only one analyzing the event.
+	Analyzer string `protobuf:"bytes,2,opt,name=analyzer,proto3" json:"analyzer,omitempty"`
+	// Percent is the percentage of the analysis completed, zero if it is
+	// unknown
+	Percent float32 `protobuf:"fixed32,3,opt,name=percent,proto3" json:"percent,omitempty"`
+	// Description of the progress, like "of files" or "parsing files". A
+	// report without percent nor description is only a heartbeat.
+	Description string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+}
+
+func (m *ProgressReport) Reset()         { *m = ProgressReport{} }
+func (m *ProgressReport) String() string { return m.EventID + "/" + m.Analyzer }
+func (*ProgressReport) ProtoMessage()    {}
+
+// ProgressResponse is the response to a ProgressReport
+type ProgressResponse struct{}
+
+func (m *ProgressResponse) Reset()         { *m = ProgressResponse{} }
+func (m *ProgressResponse) String() string { return "" }
+func (*ProgressResponse) ProtoMessage()    {}
+
+// ProgressReporter receives the progress reported by the analyzers
+type ProgressReporter interface {
+	// ReportProgress records the progress of an analysis
+	ReportProgress(context.Context, *ProgressReport) error
+}
+
+// ProgressServer is the server API for the Progress service
+type ProgressServer interface {
+	ReportProgress(context.Context, *ProgressReport) (*ProgressResponse, error)
+}
+
+// ReportProgress implements ProgressServer, sending the report to the
+// ProgressReporter. It fails with Unimplemented if there is no
+// ProgressReporter.
+func (s *DataServerHandler) ReportProgress(ctx context.Context, r *ProgressReport) (*ProgressResponse, error) {
+	if s.ProgressReporter == nil {
+		return nil, status.Error(codes.Unimplemented, "progress reports are not supported")
+	}
+
+	if err := s.ProgressReporter.ReportProgress(ctx, r); err != nil {
+		return nil, err
+	}
+
+	return &ProgressResponse{}, nil
+}
+
+var _ ProgressServer = &DataServerHandler{}
+
+// ReportProgress sends the progress of an analysis to lookoutd
+func (c *DataClient) ReportProgress(ctx context.Context, in *ProgressReport, opts ...grpc.CallOption) error {
+	return c.cc.Invoke(ctx, "/"+progressServiceName+"/ReportProgress", in, new(ProgressResponse), opts...)
+}
+
+func reportProgressHandler(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	in := new(ProgressReport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(ProgressServer).ReportProgress(ctx, in)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + progressServiceName + "/ReportProgress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProgressServer).ReportProgress(ctx, req.(*ProgressReport))
+	}
+
+	return interceptor(ctx, in, info, handler)
+}
+
+// progressServiceDesc must be replaced by the one of the SDK once it defines
+// the Progress service
+var progressServiceDesc = grpc.ServiceDesc{
+	ServiceName: progressServiceName,
+	HandlerType: (*ProgressServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ReportProgress",
+			Handler:    reportProgressHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
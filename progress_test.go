@@ -0,0 +1,72 @@
+package lookout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type progressReporterMock struct {
+	reports []*ProgressReport
+}
+
+func (r *progressReporterMock) ReportProgress(ctx context.Context, report *ProgressReport) error {
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestReportProgress(t *testing.T) {
+	require := require.New(t)
+
+	reporter := &progressReporterMock{}
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {
+		RegisterDataServer(s, &DataServerHandler{ProgressReporter: reporter})
+	})
+	defer stop()
+
+	client := NewDataClient(conn)
+	err := client.ReportProgress(context.Background(), &ProgressReport{
+		EventID:     "01ab",
+		Analyzer:    "mock",
+		Percent:     40,
+		Description: "of files",
+	})
+	require.NoError(err)
+
+	require.Len(reporter.reports, 1)
+	require.Equal(&ProgressReport{
+		EventID:     "01ab",
+		Analyzer:    "mock",
+		Percent:     40,
+		Description: "of files",
+	}, reporter.reports[0])
+}
+
+func TestReportProgressUnimplemented(t *testing.T) {
+	require := require.New(t)
+
+	conn, stop := dialTestServer(t, func(s *grpc.Server) {
+		RegisterDataServer(s, &DataServerHandler{})
+	})
+	defer stop()
+
+	client := NewDataClient(conn)
+	err := client.ReportProgress(context.Background(), &ProgressReport{EventID: "01ab"})
+	require.Error(err)
+	require.Equal(codes.Unimplemented, status.Code(err))
+}
+
+func TestAnalyzerNameFromContext(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("", AnalyzerNameFromContext(context.Background()))
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(AnalyzerNameMetadata, "mock"))
+	require.Equal("mock", AnalyzerNameFromContext(ctx))
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/meyskens/lookout/util/ctxlog"
 
 	"github.com/google/go-github/v28/github"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	log "gopkg.in/src-d/go-log.v1"
 )
@@ -126,6 +128,42 @@ func (p *ClientPool) ReposByClient(c *Client) []*repositoryInfo {
 	return p.byClients[c]
 }
 
+// Replace updates the pool to hold the same clients and repositories as
+// other. Subscribers are notified of the removed and added clients
+func (p *ClientPool) Replace(other *ClientPool) {
+	clients := other.Clients()
+	for c := range p.Clients() {
+		if _, ok := clients[c]; !ok {
+			p.RemoveClient(c)
+		}
+	}
+
+	for c, repos := range clients {
+		p.Update(c, repos)
+	}
+}
+
+// OrganizationID returns the organization ID of the repository, or "" if the
+// repository is not in the pool or its client does not belong to an
+// organization installation
+func (p *ClientPool) OrganizationID(username, repo string) string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	c, ok := p.byRepo[username+"/"+repo]
+	if !ok {
+		return ""
+	}
+
+	for _, r := range p.byClients[c] {
+		if r.Owner == username && r.Name == repo {
+			return r.OrganizationID
+		}
+	}
+
+	return ""
+}
+
 // Update updates list of repositories for a client
 func (p *ClientPool) Update(c *Client, newRepos []*repositoryInfo) {
 	if len(newRepos) == 0 {
@@ -242,6 +280,78 @@ func (p *ClientPool) GitAuth(ctx context.Context, repoInfo *lookout.RepositoryIn
 	return c.gitAuth(ctx)
 }
 
+var _ git.ReferencesProvider = &ClientPool{}
+
+// ActiveReferences returns the head references of the open pull requests of
+// a repo, and their heads in the forks they come from, so the Janitor keeps
+// them
+func (p *ClientPool) ActiveReferences(
+	ctx context.Context,
+	repoInfo *lookout.RepositoryInfo,
+) ([]plumbing.ReferenceName, error) {
+	c, ok := p.Client(repoInfo.Owner, repoInfo.Name)
+	if !ok {
+		return nil, fmt.Errorf("no client for repository %s", repoInfo.FullName)
+	}
+
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var refs []plumbing.ReferenceName
+	for {
+		prs, resp, err := c.PullRequests.List(ctx, repoInfo.Owner, repoInfo.Name, opts)
+		if err = handleAPIError(resp, err, "pull requests could not be listed"); err != nil {
+			return nil, err
+		}
+
+		for _, pr := range prs {
+			refs = append(refs, plumbing.ReferenceName(
+				fmt.Sprintf("refs/pull/%d/head", pr.GetNumber())))
+
+			head, err := pb.ParseRepositoryInfo(pr.GetHead().GetRepo().GetCloneURL())
+			if err != nil || head.CloneURL == repoInfo.CloneURL {
+				continue
+			}
+
+			refs = append(refs, git.ForkReferenceName(head,
+				plumbing.ReferenceName("refs/heads/"+pr.GetHead().GetRef())))
+		}
+
+		if resp.NextPage == 0 {
+			return refs, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+var _ git.ParentProvider = &ClientPool{}
+
+// ParentRepository returns the repository a repo was forked from, or nil if
+// it is not a fork
+func (p *ClientPool) ParentRepository(
+	ctx context.Context,
+	repoInfo *lookout.RepositoryInfo,
+) (*lookout.RepositoryInfo, error) {
+	c, ok := p.Client(repoInfo.Owner, repoInfo.Name)
+	if !ok {
+		return nil, fmt.Errorf("no client for repository %s", repoInfo.FullName)
+	}
+
+	repo, resp, err := c.Repositories.Get(ctx, repoInfo.Owner, repoInfo.Name)
+	if err = handleAPIError(resp, err, "repository could not be requested"); err != nil {
+		return nil, err
+	}
+
+	if !repo.GetFork() || repo.GetParent() == nil {
+		return nil, nil
+	}
+
+	return pb.ParseRepositoryInfo(repo.GetParent().GetCloneURL())
+}
+
 type gitAuthFn = func(ctx context.Context) transport.AuthMethod
 
 // Client is a wrapper for github.Client that supports cache and provides rate limit information
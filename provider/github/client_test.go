@@ -141,6 +141,53 @@ func TestClientPoolMultipleDeleteRepos(t *testing.T) {
 	require.Equal(newRepos, p.ReposByClient(client))
 }
 
+func TestClientPoolReplace(t *testing.T) {
+	require := require.New(t)
+
+	p := NewClientPool()
+
+	oldClient := &Client{}
+	info1, _ := parseTestRepositoryInfo("github.com/foo/bar1")
+	p.Update(oldClient, []*repositoryInfo{info1})
+
+	ch := make(chan ClientPoolEvent, 2)
+	p.Subscribe(ch)
+
+	newClient := &Client{}
+	info2, _ := parseTestRepositoryInfo("github.com/foo/bar2")
+	other := NewClientPool()
+	other.Update(newClient, []*repositoryInfo{info2})
+
+	p.Replace(other)
+
+	require.Len(p.Clients(), 1)
+	_, ok := p.Client("foo", "bar1")
+	require.False(ok)
+	c, ok := p.Client("foo", "bar2")
+	require.True(ok)
+	require.Equal(newClient, c)
+
+	require.Equal(ClientPoolEvent{Type: ClientPoolEventRemove, Client: oldClient}, <-ch)
+	require.Equal(ClientPoolEvent{Type: ClientPoolEventAdd, Client: newClient}, <-ch)
+}
+
+func TestClientPoolOrganizationID(t *testing.T) {
+	require := require.New(t)
+
+	p := NewClientPool()
+
+	client := &Client{}
+	info1, _ := parseTestRepositoryInfo("github.com/foo/bar1")
+	info1.OrganizationID = "1234"
+	info2, _ := parseTestRepositoryInfo("github.com/foo/bar2")
+
+	p.Update(client, []*repositoryInfo{info1, info2})
+
+	require.Equal("1234", p.OrganizationID("foo", "bar1"))
+	require.Equal("", p.OrganizationID("foo", "bar2"))
+	require.Equal("", p.OrganizationID("foo", "bar3"))
+}
+
 func TestErrorResponseDoesNotPanic(t *testing.T) {
 	require := require.New(t)
 
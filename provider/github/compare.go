@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/meyskens/lookout"
+	"github.com/meyskens/lookout/util/ctxlog"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+// errPossibleRenames is returned by localComparison when some files were
+// removed and others added. GitHub may show them as renamed files with
+// changes, and the diff positions of their comments would not match.
+var errPossibleRenames = errors.NewKind("the diff may contain renamed files")
+
+// Values of the status of the files of a CommitsComparison
+const (
+	fileAdded    = "added"
+	fileRemoved  = "removed"
+	fileModified = "modified"
+	fileRenamed  = "renamed"
+)
+
+// compare returns the files changed by the pull request of e. They are
+// computed from the local repository with the PatchLoader, and requested to
+// the GitHub API only if there is no PatchLoader or the local diff can't be
+// used.
+func (p *Poster) compare(
+	ctx context.Context,
+	client *Client,
+	owner, repo string,
+	e *lookout.ReviewEvent,
+) (*github.CommitsComparison, error) {
+	if p.patchLoader != nil {
+		cc, err := localComparison(ctx, p.patchLoader, e)
+		if err == nil {
+			return cc, nil
+		}
+
+		if errPossibleRenames.Is(err) {
+			ctxlog.Get(ctx).Debugf("comparing commits with the GitHub API: %s", err)
+		} else {
+			ctxlog.Get(ctx).Errorf(err, "commits could not be compared locally, using the GitHub API")
+		}
+	}
+
+	cc, resp, err := client.Repositories.CompareCommits(ctx, owner, repo,
+		e.Base.Hash,
+		e.Head.Hash)
+	if err = handleAPIError(resp, err, "commits could not be compared"); err != nil {
+		return nil, err
+	}
+
+	return cc, nil
+}
+
+// localComparison returns the files changed by the pull request of e,
+// computed with l. The files do not have a patch, diffLines loads it with l
+// when needed. Renames are only detected if the content of the file did not
+// change, errPossibleRenames is returned if there may be other renames.
+func localComparison(
+	ctx context.Context,
+	l PatchLoader,
+	e *lookout.ReviewEvent,
+) (*github.CommitsComparison, error) {
+	changes, err := l.Changes(ctx, e.Base, e.Head)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []github.CommitFile
+	var added, removed []*lookout.File
+	addedByHash := make(map[string][]*lookout.File)
+	for _, ch := range changes {
+		switch {
+		case ch.Base == nil:
+			added = append(added, ch.Head)
+			addedByHash[ch.Head.Hash] = append(addedByHash[ch.Head.Hash], ch.Head)
+		case ch.Head == nil:
+			removed = append(removed, ch.Base)
+		default:
+			changed := 1
+			if ch.Base.Hash == ch.Head.Hash {
+				changed = 0
+			}
+
+			files = append(files, github.CommitFile{
+				Filename: github.String(ch.Head.Path),
+				Status:   github.String(fileModified),
+				Changes:  github.Int(changed),
+			})
+		}
+	}
+
+	var unmatched []*lookout.File
+	renamed := make(map[string]bool)
+	for _, f := range removed {
+		candidates := addedByHash[f.Hash]
+		if len(candidates) == 0 {
+			unmatched = append(unmatched, f)
+			continue
+		}
+
+		to := candidates[0]
+		addedByHash[f.Hash] = candidates[1:]
+		renamed[to.Path] = true
+		files = append(files, github.CommitFile{
+			Filename:         github.String(to.Path),
+			PreviousFilename: github.String(f.Path),
+			Status:           github.String(fileRenamed),
+			Changes:          github.Int(0),
+		})
+	}
+
+	if len(unmatched) > 0 && len(added) > len(renamed) {
+		return nil, errPossibleRenames.New()
+	}
+
+	for _, f := range unmatched {
+		files = append(files, github.CommitFile{
+			Filename: github.String(f.Path),
+			Status:   github.String(fileRemoved),
+			Changes:  github.Int(1),
+		})
+	}
+
+	for _, f := range added {
+		if renamed[f.Path] {
+			continue
+		}
+
+		files = append(files, github.CommitFile{
+			Filename: github.String(f.Path),
+			Status:   github.String(fileAdded),
+			Changes:  github.Int(1),
+		})
+	}
+
+	return &github.CommitsComparison{Files: files}, nil
+}
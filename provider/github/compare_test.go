@@ -0,0 +1,82 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/meyskens/lookout"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalComparison(t *testing.T) {
+	require := require.New(t)
+
+	loader := &patchLoaderMock{changes: []*lookout.Change{
+		{
+			Base: &lookout.File{Path: "modified.go", Hash: "1"},
+			Head: &lookout.File{Path: "modified.go", Hash: "2"},
+		},
+		{
+			Base: &lookout.File{Path: "mode.sh", Hash: "3", Mode: 0100644},
+			Head: &lookout.File{Path: "mode.sh", Hash: "3", Mode: 0100755},
+		},
+		{Base: &lookout.File{Path: "old.go", Hash: "4"}},
+		{Head: &lookout.File{Path: "new.go", Hash: "4"}},
+		{Base: &lookout.File{Path: "removed.go", Hash: "5"}},
+	}}
+
+	cc, err := localComparison(context.Background(), loader, mockEvent)
+	require.NoError(err)
+	require.Equal([]github.CommitFile{
+		{
+			Filename: strptr("modified.go"),
+			Status:   strptr(fileModified),
+			Changes:  intptr(1),
+		},
+		{
+			Filename: strptr("mode.sh"),
+			Status:   strptr(fileModified),
+			Changes:  intptr(0),
+		},
+		{
+			Filename:         strptr("new.go"),
+			PreviousFilename: strptr("old.go"),
+			Status:           strptr(fileRenamed),
+			Changes:          intptr(0),
+		},
+		{
+			Filename: strptr("removed.go"),
+			Status:   strptr(fileRemoved),
+			Changes:  intptr(1),
+		},
+	}, cc.Files)
+
+	// added files are kept if no file was removed
+	loader.changes = []*lookout.Change{
+		{Head: &lookout.File{Path: "empty1", Hash: "6"}},
+		{Head: &lookout.File{Path: "empty2", Hash: "6"}},
+	}
+	cc, err = localComparison(context.Background(), loader, mockEvent)
+	require.NoError(err)
+	require.Equal([]github.CommitFile{
+		{
+			Filename: strptr("empty1"),
+			Status:   strptr(fileAdded),
+			Changes:  intptr(1),
+		},
+		{
+			Filename: strptr("empty2"),
+			Status:   strptr(fileAdded),
+			Changes:  intptr(1),
+		},
+	}, cc.Files)
+
+	// a removed and an added file with changes may be a rename
+	loader.changes = []*lookout.Change{
+		{Base: &lookout.File{Path: "old.go", Hash: "7"}},
+		{Head: &lookout.File{Path: "new.go", Hash: "8"}},
+	}
+	_, err = localComparison(context.Background(), loader, mockEvent)
+	require.True(errPossibleRenames.Is(err))
+}
@@ -22,11 +22,23 @@ var (
 	ErrFileNotFound = errors.NewKind("file not found")
 	// ErrBadPatch is returned when there was a problem parsing the diff
 	ErrBadPatch = errors.NewKind("diff patch could not be parsed")
+	// ErrPatchNotLoaded is returned when the diff of a file omitted by GitHub
+	// could not be computed locally
+	ErrPatchNotLoaded = errors.NewKind("diff patch could not be loaded")
 )
 
+// loadPatchFunc returns the hunks of the diff between the file from in the
+// base revision and the file to in the head revision. from is empty for added
+// files.
+type loadPatchFunc func(from, to string) (string, error)
+
 type diffLines struct {
 	cc     *github.CommitsComparison
 	parsed map[string]*parsedFile
+	// loadPatch, if set, computes the patch of the files that GitHub does
+	// not include in the comparison, like the big ones
+	loadPatch loadPatchFunc
+	loaded    map[string]string
 }
 
 type lineType int
@@ -51,6 +63,8 @@ type hunk struct {
 type posRange struct {
 	AbsStart, AbsEnd int
 	RelStart, RelEnd int
+	// Hunk is the index of the hunk of the range
+	Hunk int
 }
 
 type parsedFile struct {
@@ -62,7 +76,36 @@ func newDiffLines(cc *github.CommitsComparison) *diffLines {
 	return &diffLines{
 		cc:     cc,
 		parsed: make(map[string]*parsedFile, len(cc.Files)),
+		loaded: make(map[string]string),
+	}
+}
+
+// Path returns the path in the head revision of file. It is the same file,
+// unless file is the previous name of a file renamed in the diff.
+func (d *diffLines) Path(file string) string {
+	if f := d.commitFile(file); f != nil {
+		return f.GetFilename()
+	}
+
+	return file
+}
+
+// commitFile returns the file of the diff with the name file in the head
+// revision, or renamed from it, or nil if there is none
+func (d *diffLines) commitFile(file string) *github.CommitFile {
+	for i, f := range d.cc.Files {
+		if f.GetFilename() == file {
+			return &d.cc.Files[i]
+		}
 	}
+
+	for i, f := range d.cc.Files {
+		if f.GetPreviousFilename() == file {
+			return &d.cc.Files[i]
+		}
+	}
+
+	return nil
 }
 
 // ConvertLine takes a line number on the original file, and returns the
@@ -90,6 +133,65 @@ func (d *diffLines) ConvertLine(file string, line int, strict bool) (int, error)
 	return diffLine, nil
 }
 
+// NearestLine returns the added line (+ in the diff) of file closest to line,
+// and its line number in the patch diff. ErrLineOutOfDiff is returned if the
+// diff of the file does not add any line.
+func (d *diffLines) NearestLine(file string, line int) (int, int, error) {
+	parsedFile, err := d.parseFile(file)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	nearest, nearestPos, distance := 0, 0, -1
+	for _, r := range parsedFile.ranges {
+		for abs := r.AbsStart; abs < r.AbsEnd; abs++ {
+			pos := abs - r.AbsStart + r.RelStart
+			if !parsedFile.linesAdded[pos] {
+				continue
+			}
+
+			dist := abs - line
+			if dist < 0 {
+				dist = -dist
+			}
+
+			if distance < 0 || dist < distance {
+				nearest, nearestPos, distance = abs, pos, dist
+			}
+		}
+	}
+
+	if distance < 0 {
+		return 0, 0, ErrLineOutOfDiff.New()
+	}
+
+	return nearest, nearestPos, nil
+}
+
+// SameHunk returns true if the lines start and end of file are part of the
+// same hunk of the diff, so all the lines between them are in the diff
+func (d *diffLines) SameHunk(file string, start, end int) bool {
+	parsedFile, err := d.parseFile(file)
+	if err != nil {
+		return false
+	}
+
+	startRange := findRange(parsedFile.ranges, start)
+	endRange := findRange(parsedFile.ranges, end)
+	return startRange != nil && endRange != nil && startRange.Hunk == endRange.Hunk
+}
+
+// findRange returns the range with line, or nil if there is none
+func findRange(ranges []*posRange, line int) *posRange {
+	for _, r := range ranges {
+		if line >= r.AbsStart && line < r.AbsEnd {
+			return r
+		}
+	}
+
+	return nil
+}
+
 func (d *diffLines) convertLine(ranges []*posRange, line int) (int, error) {
 	for _, r := range ranges {
 		if line >= r.AbsStart && line < r.AbsEnd {
@@ -101,6 +203,7 @@ func (d *diffLines) convertLine(ranges []*posRange, line int) (int, error) {
 }
 
 func (d *diffLines) parseFile(file string) (*parsedFile, error) {
+	file = d.Path(file)
 	if parsedFile, ok := d.parsed[file]; ok {
 		return parsedFile, nil
 	}
@@ -116,23 +219,43 @@ func (d *diffLines) parseFile(file string) (*parsedFile, error) {
 }
 
 func (d *diffLines) filePatch(file string) (string, error) {
-	var ff *github.CommitFile
-	for _, f := range d.cc.Files {
-		if file == *f.Filename {
-			ff = &f
-			break
-		}
-	}
-
+	ff := d.commitFile(file)
 	if ff == nil {
 		return "", ErrFileNotFound.New()
 	}
 
-	if ff.Patch == nil {
+	if ff.Patch != nil {
+		return *ff.Patch, nil
+	}
+
+	// GitHub omits the patch of binary and big files, and of the files
+	// renamed without changes
+	if d.loadPatch == nil || ff.GetStatus() == fileRemoved ||
+		(ff.GetStatus() == fileRenamed && ff.GetChanges() == 0) {
 		return "", ErrLineOutOfDiff.New()
 	}
 
-	return *ff.Patch, nil
+	to := ff.GetFilename()
+	if patch, ok := d.loaded[to]; ok {
+		return patch, nil
+	}
+
+	var from string
+	switch {
+	case ff.GetStatus() == fileAdded:
+	case ff.GetPreviousFilename() != "":
+		from = ff.GetPreviousFilename()
+	default:
+		from = to
+	}
+
+	patch, err := d.loadPatch(from, to)
+	if err != nil {
+		return "", ErrPatchNotLoaded.Wrap(err)
+	}
+
+	d.loaded[to] = patch
+	return patch, nil
 }
 
 func (d *diffLines) hunks(file string) ([]*hunk, map[int]bool, error) {
@@ -255,7 +378,7 @@ func convertRanges(hunks []*hunk) []*posRange {
 	ranges := make([]*posRange, 0)
 	// relative position of the last range end
 	lastRelEnd := 0
-	for _, hunk := range hunks {
+	for i, hunk := range hunks {
 		absStart := hunk.NewStartLine
 
 		// number of lines in diff to skip
@@ -271,6 +394,7 @@ func convertRanges(hunks []*hunk) []*posRange {
 				AbsEnd:   absStart + lines,
 				RelStart: lastRelEnd + skipLines,
 				RelEnd:   lastRelEnd + lines + skipLines,
+				Hunk:     i,
 			}
 			ranges = append(ranges, r)
 
@@ -212,6 +212,7 @@ func TestConvertRanges(t *testing.T) {
 	}, &posRange{
 		AbsStart: 500, AbsEnd: 510,
 		RelStart: 9, RelEnd: 19,
+		Hunk: 1,
 	}}, ranges)
 
 	ranges = convertRanges([]*hunk{&hunk{
@@ -272,6 +273,7 @@ func TestConvertRanges(t *testing.T) {
 	}, &posRange{
 		AbsStart: 22, AbsEnd: 30,
 		RelStart: 10, RelEnd: 18,
+		Hunk: 1,
 	}}, ranges)
 
 	ranges = convertRanges([]*hunk{&hunk{
@@ -478,3 +480,185 @@ func TestConvertLines_issue213(t *testing.T) {
 	_, err := dl.ConvertLine(filename, 42, false)
 	require.EqualError(err, ErrLineOutOfDiff.Message)
 }
+
+func TestNearestLine(t *testing.T) {
+	require := require.New(t)
+
+	filename := "some_file"
+	// context lines, an addition, a deletion and two more additions
+	patch := `@@ -5,5 +5,6 @@ header-line
+ context-line1
++new-line1
+ context-line2
+ context-line3
+-old-line1
+ context-line4
+@@ -20,3 +21,5 @@ header-line
+ context-line1
++new-line2
++new-line3
+ context-line2`
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{{
+			Filename: &filename,
+			Patch:    &patch,
+		}},
+	})
+
+	cases := []struct {
+		line, abs, pos int
+	}{
+		{1, 6, 2},
+		{6, 6, 2},
+		{9, 6, 2},
+		{20, 22, 9},
+		{23, 23, 10},
+		{100, 23, 10},
+	}
+
+	for _, c := range cases {
+		abs, pos, err := dl.NearestLine(filename, c.line)
+		require.NoError(err, "line %d", c.line)
+		require.Equal(c.abs, abs, "line %d", c.line)
+		require.Equal(c.pos, pos, "line %d", c.line)
+	}
+
+	onlyDeletion := `@@ -3,2 +3,1 @@
+ context-line1
+-old-line1`
+	dl = newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{{
+			Filename: &filename,
+			Patch:    &onlyDeletion,
+		}},
+	})
+
+	_, _, err := dl.NearestLine(filename, 3)
+	require.True(ErrLineOutOfDiff.Is(err))
+
+	_, _, err = dl.NearestLine("other_file", 3)
+	require.True(ErrFileNotFound.Is(err))
+}
+
+func TestDiffLinesRenamed(t *testing.T) {
+	require := require.New(t)
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{
+			{
+				Filename:         strptr("new.go"),
+				PreviousFilename: strptr("old.go"),
+				Status:           strptr("renamed"),
+				Changes:          intptr(1),
+				Patch: strptr(`@@ -1,2 +1,2 @@
+ package main
+-var a = 1
++var a = 2`),
+			},
+			{
+				Filename:         strptr("moved.go"),
+				PreviousFilename: strptr("unchanged.go"),
+				Status:           strptr("renamed"),
+				Changes:          intptr(0),
+			},
+		},
+	})
+
+	require.Equal("new.go", dl.Path("old.go"))
+	require.Equal("new.go", dl.Path("new.go"))
+	require.Equal("other.go", dl.Path("other.go"))
+
+	line, err := dl.ConvertLine("new.go", 2, true)
+	require.NoError(err)
+	require.Equal(3, line)
+
+	line, err = dl.ConvertLine("old.go", 2, true)
+	require.NoError(err)
+	require.Equal(3, line)
+
+	_, err = dl.ConvertLine("moved.go", 1, false)
+	require.True(ErrLineOutOfDiff.Is(err))
+}
+
+func TestDiffLinesLoadPatch(t *testing.T) {
+	require := require.New(t)
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{
+			{
+				Filename: strptr("big.go"),
+				Status:   strptr("modified"),
+				Changes:  intptr(2),
+			},
+			{
+				Filename:         strptr("new.go"),
+				PreviousFilename: strptr("old.go"),
+				Status:           strptr("renamed"),
+				Changes:          intptr(2),
+			},
+			{
+				Filename: strptr("added.go"),
+				Status:   strptr("added"),
+				Changes:  intptr(1),
+			},
+			{
+				Filename: strptr("removed.go"),
+				Status:   strptr("removed"),
+				Changes:  intptr(1),
+			},
+			{
+				Filename: strptr("broken.go"),
+				Status:   strptr("modified"),
+				Changes:  intptr(1),
+			},
+		},
+	})
+
+	// without loader the files without patch are out of the diff
+	_, err := dl.ConvertLine("big.go", 2, false)
+	require.True(ErrLineOutOfDiff.Is(err))
+
+	dl = newDiffLines(dl.cc)
+	var loads []string
+	dl.loadPatch = func(from, to string) (string, error) {
+		loads = append(loads, from+"->"+to)
+		switch to {
+		case "big.go", "new.go":
+			return "@@ -1,2 +1,2 @@\n package main\n-var a = 1\n+var a = 2\n", nil
+		case "added.go":
+			return "@@ -0,0 +1 @@\n+package main\n", nil
+		default:
+			return "", fmt.Errorf("cannot load %s", to)
+		}
+	}
+
+	line, err := dl.ConvertLine("big.go", 2, true)
+	require.NoError(err)
+	require.Equal(3, line)
+
+	line, err = dl.ConvertLine("big.go", 1, false)
+	require.NoError(err)
+	require.Equal(1, line)
+
+	line, err = dl.ConvertLine("old.go", 2, true)
+	require.NoError(err)
+	require.Equal(3, line)
+
+	line, err = dl.ConvertLine("added.go", 1, true)
+	require.NoError(err)
+	require.Equal(1, line)
+
+	_, err = dl.ConvertLine("removed.go", 1, false)
+	require.True(ErrLineOutOfDiff.Is(err))
+
+	_, err = dl.ConvertLine("broken.go", 1, false)
+	require.True(ErrPatchNotLoaded.Is(err))
+
+	require.Equal([]string{
+		"big.go->big.go",
+		"old.go->new.go",
+		"->added.go",
+		"broken.go->broken.go",
+	}, loads)
+}
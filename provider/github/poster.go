@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"text/template"
+	"unicode/utf8"
 
 	"github.com/meyskens/lookout"
 	"github.com/meyskens/lookout/util/ctxlog"
@@ -31,29 +33,92 @@ const (
 	statusContext   = "lookout"
 )
 
+// PatchLoader computes the diff between two revisions, from their merge base
+// like the diff of a pull request. It is implemented by git.PatchLoader.
+type PatchLoader interface {
+	// Changes returns the files changed from base to head, without content
+	Changes(ctx context.Context, base, head lookout.ReferencePointer) (
+		[]*lookout.Change, error)
+	// FilePatch returns the hunks of the unified diff between the file from
+	// in base and the file to in head. from is empty for added files.
+	FilePatch(ctx context.Context, base, head lookout.ReferencePointer,
+		from, to string) (string, error)
+	// RevisionPatch returns the hunks of the unified diff of the file path
+	// between the revisions from and to, compared directly
+	RevisionPatch(ctx context.Context, from, to lookout.ReferencePointer,
+		path string) (string, error)
+}
+
 // Poster posts comments as Pull Request Reviews.
 type Poster struct {
 	pool           *ClientPool
 	conf           ProviderConfig
 	footerTemplate *template.Template
+	confMutex      sync.RWMutex
+	patchLoader    PatchLoader
 }
 
 var _ lookout.Poster = &Poster{}
 
 // NewPoster creates a new poster for the GitHub API.
 func NewPoster(pool *ClientPool, conf ProviderConfig) (*Poster, error) {
+	p := &Poster{pool: pool}
+	if err := p.SetConfig(conf); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SetConfig replaces the configuration of the poster. Reviews that are
+// already being posted keep using the previous one.
+func (p *Poster) SetConfig(conf ProviderConfig) error {
+	switch conf.OutOfDiffComments {
+	case "", OutOfDiffDrop, OutOfDiffNearest, OutOfDiffBody:
+	default:
+		return fmt.Errorf("unknown out_of_diff_comments value %q, it must be %s, %s or %s",
+			conf.OutOfDiffComments, OutOfDiffDrop, OutOfDiffNearest, OutOfDiffBody)
+	}
+
 	tpl, err := newFooterTemplate(conf.CommentFooter)
 	if ErrEmptyTemplate.Is(err) {
 		log.DefaultLogger.Warningf("no footer template being used: %s", err)
 	} else if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &Poster{
-		pool:           pool,
-		conf:           conf,
-		footerTemplate: tpl,
-	}, nil
+	p.confMutex.Lock()
+	defer p.confMutex.Unlock()
+
+	p.conf = conf
+	p.footerTemplate = tpl
+
+	return nil
+}
+
+// SetPatchLoader sets the PatchLoader used to compute the diff of the pull
+// requests locally, instead of requesting it to the GitHub API. It is also
+// used for the files whose patch is omitted by GitHub, like binary or big
+// files, and to re-anchor the comments when the pull request gets new
+// commits during the analysis. Without it, the comments on those files are
+// handled as out of the diff, and the comments are posted on the analyzed
+// commit. It must be called before posting.
+func (p *Poster) SetPatchLoader(l PatchLoader) {
+	p.patchLoader = l
+}
+
+func (p *Poster) getOutOfDiffComments() string {
+	p.confMutex.RLock()
+	defer p.confMutex.RUnlock()
+
+	return p.conf.OutOfDiffComments
+}
+
+func (p *Poster) getFooterTemplate() *template.Template {
+	p.confMutex.RLock()
+	defer p.confMutex.RUnlock()
+
+	return p.footerTemplate
 }
 
 // Post posts comments as a Pull Request Review.
@@ -90,12 +155,10 @@ func (p *Poster) postPR(ctx context.Context, e *lookout.ReviewEvent,
 		return err
 	}
 
-	// TODO: make this request lazily, only if there are comments using
-	// positions.
-	cc, resp, err := client.Repositories.CompareCommits(ctx, owner, repo,
-		e.Base.Hash,
-		e.Head.Hash)
-	if err = handleAPIError(resp, err, "commits could not be compared"); err != nil {
+	e, aCommentsList = p.followHead(ctx, client, owner, repo, pr, e, aCommentsList)
+
+	cc, err := p.compare(ctx, client, owner, repo, e)
+	if err != nil {
 		return err
 	}
 
@@ -109,7 +172,14 @@ func (p *Poster) postPR(ctx context.Context, e *lookout.ReviewEvent,
 	}
 
 	dl := newDiffLines(cc)
-	review, err := p.createReviewRequest(ctx, aCommentsList, dl, e.Head.Hash, postedComments)
+	if p.patchLoader != nil {
+		dl.loadPatch = func(from, to string) (string, error) {
+			return p.patchLoader.FilePatch(ctx, e.Base, e.Head, from, to)
+		}
+	}
+
+	fallback := newOutOfDiff(p.getOutOfDiffComments(), e)
+	review, err := p.createReviewRequest(ctx, aCommentsList, dl, fallback, e.Head.Hash, postedComments)
 	if errNoComments.Is(err) {
 		ctxlog.Get(ctx).Infof("skipping posting analysis, there are no comments")
 		return nil
@@ -146,6 +216,12 @@ func (p *Poster) validatePR(
 	return
 }
 
+// partialNote returns the text added to the review body for an analyzer that
+// did not finish its analysis
+func partialNote(analyzer string) string {
+	return fmt.Sprintf("_The analyzer %s did not finish, these are partial results._", analyzer)
+}
+
 var (
 	approveEvent        = "APPROVE"
 	requestChangesEvent = "REQUEST_CHANGES"
@@ -158,21 +234,27 @@ func (p *Poster) createReviewRequest(
 	ctx context.Context,
 	aCommentsList []lookout.AnalyzerComments,
 	dl *diffLines,
+	fallback outOfDiff,
 	commitID string,
 	postedComments []*github.PullRequestComment,
-) (*github.PullRequestReviewRequest, error) {
-	req := &github.PullRequestReviewRequest{
+) (*reviewRequest, error) {
+	req := &reviewRequest{
 		CommitID: &commitID,
 		Event:    &commentEvent,
 	}
 
+	footerTemplate := p.getFooterTemplate()
+
 	var bodyComments []string
 	for _, aComments := range aCommentsList {
 		ctx, _ := ctxlog.WithLogFields(ctx, log.Fields{
 			"analyzer": aComments.Config.Name,
 		})
 
-		forBody, ghComments := convertComments(ctx, aComments.Comments, dl)
+		forBody, ghComments := convertComments(ctx, aComments.Comments, dl, fallback)
+		if aComments.Partial {
+			forBody = append([]string{partialNote(aComments.Config.Name)}, forBody...)
+		}
 
 		if len(postedComments) > 0 {
 			ghComments = filterPostedComments(ghComments, postedComments)
@@ -181,13 +263,13 @@ func (p *Poster) createReviewRequest(
 		ghComments = mergeComments(ghComments)
 
 		for i, c := range ghComments {
-			body := addFootnote(ctx, c.GetBody(), p.footerTemplate, &aComments.Config)
+			body := addFootnote(ctx, c.GetBody(), footerTemplate, &aComments.Config)
 			ghComments[i].Body = &body
 		}
 
 		bodyComments = append(
 			bodyComments,
-			addFootnote(ctx, strings.Join(forBody, "\n\n"), p.footerTemplate, &aComments.Config),
+			addFootnote(ctx, strings.Join(forBody, "\n\n"), footerTemplate, &aComments.Config),
 		)
 		req.Comments = append(req.Comments, ghComments...)
 	}
@@ -212,7 +294,64 @@ func (p *Poster) Status(ctx context.Context, e lookout.Event, status lookout.Ana
 				fmt.Errorf("unsupported provider: %s", ev.Provider))
 		}
 
-		return p.statusPR(ctx, ev, status)
+		statusStr, description, err := statusStrings(status)
+		if err != nil {
+			return err
+		}
+
+		return p.statusPR(ctx, ev, statusStr, description)
+	case *lookout.PushEvent:
+		// Currently we don't post push comments anywhere
+		return nil
+	default:
+		return ErrEventNotSupported.Wrap(fmt.Errorf("unsupported event type %s", reflect.TypeOf(e)))
+	}
+}
+
+// StatusSkipped sets the Pull Request global status like Status does, adding
+// to the description the analyzers that were skipped.
+// If a GitHub API request fails, ErrGitHubAPI is returned.
+func (p *Poster) StatusSkipped(ctx context.Context, e lookout.Event, status lookout.AnalysisStatus, skipped []string) error {
+	switch ev := e.(type) {
+	case *lookout.ReviewEvent:
+		if ev.Provider != Provider {
+			return ErrEventNotSupported.Wrap(
+				fmt.Errorf("unsupported provider: %s", ev.Provider))
+		}
+
+		statusStr, description, err := statusStrings(status)
+		if err != nil {
+			return err
+		}
+
+		return p.statusPR(ctx, ev, statusStr, skippedDescription(description, skipped))
+	case *lookout.PushEvent:
+		// Currently we don't post push comments anywhere
+		return nil
+	default:
+		return ErrEventNotSupported.Wrap(fmt.Errorf("unsupported event type %s", reflect.TypeOf(e)))
+	}
+}
+
+var _ lookout.SkippedAnalyzersPoster = &Poster{}
+
+// StatusProgress sets the Pull Request global status to pending, using the
+// progress as description.
+// If a GitHub API request fails, ErrGitHubAPI is returned.
+func (p *Poster) StatusProgress(ctx context.Context, e lookout.Event, progress string) error {
+	switch ev := e.(type) {
+	case *lookout.ReviewEvent:
+		if ev.Provider != Provider {
+			return ErrEventNotSupported.Wrap(
+				fmt.Errorf("unsupported provider: %s", ev.Provider))
+		}
+
+		statusStr, _, err := statusStrings(lookout.PendingAnalysisStatus)
+		if err != nil {
+			return err
+		}
+
+		return p.statusPR(ctx, ev, statusStr, truncateDescription(progress))
 	case *lookout.PushEvent:
 		// Currently we don't post push comments anywhere
 		return nil
@@ -221,6 +360,8 @@ func (p *Poster) Status(ctx context.Context, e lookout.Event, status lookout.Ana
 	}
 }
 
+var _ lookout.ProgressPoster = &Poster{}
+
 // StatusCreator creates statuses on GitHub. *github.RepositoriesService
 // fulfills this interface.
 type StatusCreator interface {
@@ -247,16 +388,41 @@ func statusStrings(s lookout.AnalysisStatus) (string, string, error) {
 	}
 }
 
-func (p *Poster) statusPR(ctx context.Context, e *lookout.ReviewEvent, status lookout.AnalysisStatus) error {
-	owner, repo, _, err := p.validatePR(e)
-	if err != nil {
-		return err
+// maxStatusDescription is the maximum length of a status description
+// accepted by GitHub
+const maxStatusDescription = 140
+
+// skippedDescription adds the skipped analyzers to a status description
+func skippedDescription(description string, skipped []string) string {
+	if len(skipped) == 0 {
+		return description
+	}
+
+	return truncateDescription(fmt.Sprintf("%s, skipped unavailable analyzers: %s",
+		description, strings.Join(skipped, ", ")))
+}
+
+// truncateDescription shortens description to the maximum length accepted
+// by GitHub, without cutting a multi-byte character
+func truncateDescription(description string) string {
+	if len(description) <= maxStatusDescription {
+		return description
 	}
 
-	statusStr, description, err := statusStrings(status)
+	cut := maxStatusDescription - 3
+	for cut > 0 && !utf8.RuneStart(description[cut]) {
+		cut--
+	}
+
+	return description[:cut] + "..."
+}
+
+func (p *Poster) statusPR(ctx context.Context, e *lookout.ReviewEvent, statusStr, description string) error {
+	owner, repo, _, err := p.validatePR(e)
 	if err != nil {
 		return err
 	}
+
 	targetURL := statusTargetURL
 	context := statusContext
 
@@ -3,12 +3,15 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
 
@@ -222,6 +225,216 @@ func (s *PosterTestSuite) TestPostFooter() {
 	s.True(createReviewsCalled)
 }
 
+func (s *PosterTestSuite) TestPostPartial() {
+	compareCalled := false
+	s.compareHandle(&compareCalled)
+
+	createReviewsCalled := false
+	s.mux.HandleFunc("/repos/foo/bar/pulls/42/reviews", func(w http.ResponseWriter, r *http.Request) {
+		s.False(createReviewsCalled)
+		createReviewsCalled = true
+
+		var review github.PullRequestReviewRequest
+		s.NoError(json.NewDecoder(r.Body).Decode(&review))
+		s.Equal("_The analyzer mock did not finish, these are partial results._\n\n"+
+			"Global comment\n\nAnother global comment", review.GetBody())
+		s.Len(review.Comments, 2)
+
+		resp := &github.Response{Response: &http.Response{StatusCode: 200}}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	aComments := []lookout.AnalyzerComments{{
+		Config:   mockAnalyzerComments[0].Config,
+		Comments: mockAnalyzerComments[0].Comments,
+		Partial:  true,
+	}}
+
+	p := &Poster{pool: s.pool}
+	err := p.Post(context.Background(), mockEvent, aComments, false)
+	s.NoError(err)
+
+	s.True(createReviewsCalled)
+}
+
+// patchLoaderMock returns the changes and patches of the mocked compare API,
+// and revisionPatches by file, or err if it is set. The files without revision
+// patch can't be compared.
+type patchLoaderMock struct {
+	changes         []*lookout.Change
+	revisionPatches map[string]string
+	err             error
+}
+
+func (l *patchLoaderMock) Changes(ctx context.Context,
+	base, head lookout.ReferencePointer) ([]*lookout.Change, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return l.changes, nil
+}
+
+func (l *patchLoaderMock) FilePatch(ctx context.Context,
+	base, head lookout.ReferencePointer, from, to string) (string, error) {
+	if l.err != nil {
+		return "", l.err
+	}
+
+	return mockedPatch, nil
+}
+
+func (l *patchLoaderMock) RevisionPatch(ctx context.Context,
+	from, to lookout.ReferencePointer, path string) (string, error) {
+	if l.err != nil {
+		return "", l.err
+	}
+
+	patch, ok := l.revisionPatches[path]
+	if !ok {
+		return "", fmt.Errorf("file %s not found", path)
+	}
+
+	return patch, nil
+}
+
+var mockedChanges = []*lookout.Change{{
+	Base: &lookout.File{Path: "main.go", Hash: hash1},
+	Head: &lookout.File{Path: "main.go", Hash: hash2},
+}}
+
+func (s *PosterTestSuite) TestPostLocalComparison() {
+	compareCalled := false
+	s.compareHandle(&compareCalled)
+
+	createReviewsCalled := false
+	s.mux.HandleFunc("/repos/foo/bar/pulls/42/reviews", func(w http.ResponseWriter, r *http.Request) {
+		s.False(createReviewsCalled)
+		createReviewsCalled = true
+
+		var review github.PullRequestReviewRequest
+		s.NoError(json.NewDecoder(r.Body).Decode(&review))
+		s.Len(review.Comments, 2)
+		s.Equal(3, review.Comments[1].GetPosition())
+
+		resp := &github.Response{Response: &http.Response{StatusCode: 200}}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	p := &Poster{pool: s.pool}
+	p.SetPatchLoader(&patchLoaderMock{changes: mockedChanges})
+	err := p.Post(context.Background(), mockEvent, mockAnalyzerComments, false)
+	s.NoError(err)
+
+	s.False(compareCalled)
+	s.True(createReviewsCalled)
+}
+
+func (s *PosterTestSuite) TestPostHeadMoved() {
+	newHead := "3a6f1bab5f9a1da6ff4e2a5b1f8f7c59f5b0e1d2"
+	s.mux.HandleFunc("/repos/foo/bar/pulls/42", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&github.PullRequest{
+			Number: intptr(42),
+			Head:   &github.PullRequestBranch{SHA: strptr(newHead)},
+		})
+	})
+
+	createReviewsCalled := false
+	s.mux.HandleFunc("/repos/foo/bar/pulls/42/reviews", func(w http.ResponseWriter, r *http.Request) {
+		s.False(createReviewsCalled)
+		createReviewsCalled = true
+
+		var review github.PullRequestReviewRequest
+		s.NoError(json.NewDecoder(r.Body).Decode(&review))
+		s.Equal(newHead, review.GetCommitID())
+		s.Len(review.Comments, 2)
+		// two lines were added before line 5 by the new commits
+		s.Equal("Line comment", review.Comments[1].GetBody())
+		s.Equal(5, review.Comments[1].GetPosition())
+
+		resp := &github.Response{Response: &http.Response{StatusCode: 200}}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	p := &Poster{pool: s.pool}
+	p.SetPatchLoader(&patchLoaderMock{
+		changes: mockedChanges,
+		revisionPatches: map[string]string{
+			"main.go": "@@ -1,0 +2,2 @@\n+a\n+b\n",
+		},
+	})
+	err := p.Post(context.Background(), mockEvent, mockAnalyzerComments, false)
+	s.NoError(err)
+
+	s.True(createReviewsCalled)
+	// the comments of the analyzer are not modified
+	s.Equal(int32(5), mockComments[2].Line)
+}
+
+func (s *PosterTestSuite) TestPostLocalComparisonError() {
+	s.testPostLocalComparisonFallback(&patchLoaderMock{
+		err: fmt.Errorf("repository not found"),
+	})
+}
+
+func (s *PosterTestSuite) TestPostLocalComparisonRenames() {
+	s.testPostLocalComparisonFallback(&patchLoaderMock{
+		changes: []*lookout.Change{
+			{Base: &lookout.File{Path: "old.go", Hash: hash1}},
+			{Head: &lookout.File{Path: "main.go", Hash: hash2}},
+		},
+	})
+}
+
+// testPostLocalComparisonFallback checks that the GitHub API is used to
+// compare the commits when the loader can't be used
+func (s *PosterTestSuite) testPostLocalComparisonFallback(loader PatchLoader) {
+	compareCalled := false
+	s.compareHandle(&compareCalled)
+
+	createReviewsCalled := false
+	s.mux.HandleFunc("/repos/foo/bar/pulls/42/reviews", func(w http.ResponseWriter, r *http.Request) {
+		s.False(createReviewsCalled)
+		createReviewsCalled = true
+
+		resp := &github.Response{Response: &http.Response{StatusCode: 200}}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	p := &Poster{pool: s.pool}
+	p.SetPatchLoader(loader)
+	err := p.Post(context.Background(), mockEvent, mockAnalyzerComments, false)
+	s.NoError(err)
+
+	s.True(compareCalled)
+	s.True(createReviewsCalled)
+}
+
+func (s *PosterTestSuite) TestSetConfig() {
+	p, err := NewPoster(s.pool, ProviderConfig{})
+	s.NoError(err)
+	s.Nil(p.getFooterTemplate())
+
+	err = p.SetConfig(ProviderConfig{CommentFooter: "To post feedback go to {{.Feedback}}"})
+	s.NoError(err)
+	tpl := p.getFooterTemplate()
+	s.NotNil(tpl)
+
+	// an invalid template keeps the previous configuration
+	err = p.SetConfig(ProviderConfig{CommentFooter: "{{.Feedback"})
+	s.Error(err)
+	s.Equal(tpl, p.getFooterTemplate())
+
+	err = p.SetConfig(ProviderConfig{OutOfDiffComments: OutOfDiffNearest})
+	s.NoError(err)
+	s.Equal(OutOfDiffNearest, p.getOutOfDiffComments())
+
+	// an unknown value keeps the previous configuration
+	err = p.SetConfig(ProviderConfig{OutOfDiffComments: "file"})
+	s.Error(err)
+	s.Equal(OutOfDiffNearest, p.getOutOfDiffComments())
+}
+
 func (s *PosterTestSuite) TestPostBadProvider() {
 	p := &Poster{pool: s.pool}
 
@@ -459,6 +672,62 @@ func (s *PosterTestSuite) TestStatusOK() {
 	s.True(createStatusCalled)
 }
 
+func (s *PosterTestSuite) TestStatusSkipped() {
+	createStatusCalled := false
+
+	s.mux.HandleFunc("/repos/foo/bar/statuses/02801e1a27a0a906d59530aeb81f4cd137f2c717", func(w http.ResponseWriter, r *http.Request) {
+		s.False(createStatusCalled)
+		createStatusCalled = true
+
+		body, err := ioutil.ReadAll(r.Body)
+		s.NoError(err)
+
+		expected, _ := json.Marshal(&github.RepoStatus{
+			State:       strptr("success"),
+			TargetURL:   strptr("https://github.com/meyskens/lookout"),
+			Description: strptr("The analysis was performed, skipped unavailable analyzers: a, b"),
+			Context:     strptr("lookout"),
+		})
+		s.JSONEq(string(expected), string(body))
+
+		json.NewEncoder(w).Encode(&github.RepoStatus{ID: int64ptr(1234)})
+	})
+
+	p := &Poster{pool: s.pool}
+	err := p.StatusSkipped(context.Background(), mockEvent, lookout.SuccessAnalysisStatus, []string{"a", "b"})
+	s.NoError(err)
+
+	s.True(createStatusCalled)
+}
+
+func (s *PosterTestSuite) TestStatusProgress() {
+	createStatusCalled := false
+
+	s.mux.HandleFunc("/repos/foo/bar/statuses/02801e1a27a0a906d59530aeb81f4cd137f2c717", func(w http.ResponseWriter, r *http.Request) {
+		s.False(createStatusCalled)
+		createStatusCalled = true
+
+		body, err := ioutil.ReadAll(r.Body)
+		s.NoError(err)
+
+		expected, _ := json.Marshal(&github.RepoStatus{
+			State:       strptr("pending"),
+			TargetURL:   strptr("https://github.com/meyskens/lookout"),
+			Description: strptr("analyzer a: 40% of files"),
+			Context:     strptr("lookout"),
+		})
+		s.JSONEq(string(expected), string(body))
+
+		json.NewEncoder(w).Encode(&github.RepoStatus{ID: int64ptr(1234)})
+	})
+
+	p := &Poster{pool: s.pool}
+	err := p.StatusProgress(context.Background(), mockEvent, "analyzer a: 40% of files")
+	s.NoError(err)
+
+	s.True(createStatusCalled)
+}
+
 func (s *PosterTestSuite) TestStatusBadProvider() {
 	p := &Poster{pool: s.pool}
 	err := p.Status(context.Background(), badProviderEvent, lookout.PendingAnalysisStatus)
@@ -516,6 +785,21 @@ func (s *PosterTestSuite) TestStatusHttpJSONErr() {
 	s.IsType(ErrGitHubAPI.New(), err)
 }
 
+func (s *PosterTestSuite) TestTruncateDescription() {
+	short := "Analyzer ü failed"
+	s.Equal(short, truncateDescription(short))
+
+	// "é" takes 2 bytes, the last one that fits would be cut in half
+	long := strings.Repeat("a", maxStatusDescription-4) + strings.Repeat("é", 10)
+	truncated := truncateDescription(long)
+	s.True(utf8.ValidString(truncated))
+	s.True(len(truncated) <= maxStatusDescription)
+	s.Equal(strings.Repeat("a", maxStatusDescription-4)+"...", truncated)
+
+	ascii := strings.Repeat("a", maxStatusDescription+1)
+	s.Equal(strings.Repeat("a", maxStatusDescription-3)+"...", truncateDescription(ascii))
+}
+
 func TestPosterTestSuite(t *testing.T) {
 	suite.Run(t, new(PosterTestSuite))
 }
@@ -0,0 +1,237 @@
+package github
+
+import (
+	"context"
+
+	"github.com/meyskens/lookout"
+	"github.com/meyskens/lookout/util/ctxlog"
+
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// followHead returns the event with the current head of its pull request, and
+// the comments re-anchored to it, if the pull request got new commits while it
+// was analyzed. The comments on lines changed by the new commits are dropped.
+// The event and the comments are returned unchanged if there is no
+// PatchLoader or the current head can't be requested.
+func (p *Poster) followHead(
+	ctx context.Context,
+	client *Client,
+	owner, repo string,
+	pr int,
+	e *lookout.ReviewEvent,
+	aCommentsList []lookout.AnalyzerComments,
+) (*lookout.ReviewEvent, []lookout.AnalyzerComments) {
+	if p.patchLoader == nil {
+		return e, aCommentsList
+	}
+
+	ghPR, resp, err := client.PullRequests.Get(ctx, owner, repo, pr)
+	if err = handleAPIError(resp, err, "pull request could not be requested"); err != nil {
+		ctxlog.Get(ctx).Warningf("the head of the pull request could not be checked: %s", err)
+		return e, aCommentsList
+	}
+
+	head := ghPR.GetHead().GetSHA()
+	if head == "" || head == e.Head.Hash {
+		return e, aCommentsList
+	}
+
+	ctxlog.Get(ctx).With(log.Fields{
+		"analyzed-head": e.Head.Hash,
+		"head":          head,
+	}).Infof("the pull request head moved during the analysis, re-anchoring the comments")
+
+	moved := *e
+	moved.Head.Hash = head
+
+	return &moved, reanchorComments(ctx, p.patchLoader, e.Head, moved.Head, aCommentsList)
+}
+
+// reanchorComments maps the lines of the comments from the revision from to
+// the revision to. The comments on lines changed between both revisions, or
+// on files that can't be compared, are dropped. The comments are copied
+// before being modified.
+func reanchorComments(
+	ctx context.Context,
+	l PatchLoader,
+	from, to lookout.ReferencePointer,
+	aCommentsList []lookout.AnalyzerComments,
+) []lookout.AnalyzerComments {
+	// hunks by file, nil if the file could not be compared
+	files := make(map[string][]*hunk)
+	fileHunks := func(file string) ([]*hunk, bool) {
+		if hs, ok := files[file]; ok {
+			return hs, hs != nil
+		}
+
+		logger := ctxlog.Get(ctx).With(log.Fields{"file": file})
+		patch, err := l.RevisionPatch(ctx, from, to, file)
+		if err != nil {
+			logger.Warningf("the file could not be compared with the new head: %s", err)
+			files[file] = nil
+			return nil, false
+		}
+
+		hs, _, err := parseHunks(patch)
+		if err != nil {
+			logger.Errorf(err, "the diff with the new head could not be parsed")
+			files[file] = nil
+			return nil, false
+		}
+
+		// an empty slice means the file did not change
+		if hs == nil {
+			hs = []*hunk{}
+		}
+
+		files[file] = hs
+		return hs, true
+	}
+
+	result := make([]lookout.AnalyzerComments, len(aCommentsList))
+	for i, aComments := range aCommentsList {
+		result[i] = aComments
+		result[i].Comments = nil
+
+		for _, c := range aComments.Comments {
+			if c.File == "" {
+				result[i].Comments = append(result[i].Comments, c)
+				continue
+			}
+
+			hs, ok := fileHunks(c.File)
+			if !ok {
+				convertLineLogger(ctx, c).Debugf("skipping comment on a file that could not be compared with the new head")
+				continue
+			}
+
+			if c.Line < 1 {
+				result[i].Comments = append(result[i].Comments, c)
+				continue
+			}
+
+			moved, ok, err := reanchorComment(hs, c)
+			if err != nil {
+				convertLineLogger(ctx, c).Errorf(err, "skipping comment that can not be moved")
+				continue
+			}
+
+			if !ok {
+				convertLineLogger(ctx, c).Debugf("skipping comment on lines changed after the analysis")
+				continue
+			}
+
+			result[i].Comments = append(result[i].Comments, moved)
+		}
+	}
+
+	return result
+}
+
+// reanchorComment returns a copy of c with its lines, and the lines of its
+// suggestion, mapped with the hunks hs. It returns false if any of the lines
+// was changed.
+func reanchorComment(hs []*hunk, c *lookout.Comment) (*lookout.Comment, bool, error) {
+	start, end := lookout.CommentRange(c)
+	newStart, ok := mapLineRange(hs, int(start), int(end))
+	if !ok {
+		return nil, false, nil
+	}
+
+	moved := *c
+	offset := int32(newStart) - start
+	if err := lookout.SetCommentRange(&moved, start+offset, end+offset); err != nil {
+		return nil, false, err
+	}
+
+	_, ext := lookout.SplitCommentExtension(moved.Text)
+	if ext == nil || ext.Suggestion == nil {
+		return &moved, true, nil
+	}
+
+	s := *ext.Suggestion
+	newStart, ok = mapLineRange(hs, int(s.StartLine), int(s.EndLine))
+	if !ok {
+		return nil, false, nil
+	}
+
+	s.EndLine += int32(newStart) - s.StartLine
+	s.StartLine = int32(newStart)
+	ext.Suggestion = &s
+	if err := lookout.SetCommentExtension(&moved, ext); err != nil {
+		return nil, false, err
+	}
+
+	return &moved, true, nil
+}
+
+// mapLineRange returns the line of the new revision of a file of the line
+// start of the old one, given the hunks of the diff between both. It returns
+// false if any line from start to end, inclusive, was changed.
+func mapLineRange(hs []*hunk, start, end int) (int, bool) {
+	newStart, ok := mapLine(hs, start)
+	if !ok {
+		return 0, false
+	}
+
+	for line := start + 1; line <= end; line++ {
+		newLine, ok := mapLine(hs, line)
+		if !ok || newLine != newStart+line-start {
+			return 0, false
+		}
+	}
+
+	return newStart, true
+}
+
+// mapLine returns the line of the new revision of a file of the line of the
+// old one, given the hunks of the diff between both. It returns false if the
+// line was changed or removed.
+func mapLine(hs []*hunk, line int) (int, bool) {
+	offset := 0
+	for _, h := range hs {
+		// a hunk without old lines adds the new ones after OldStartLine
+		oldStart, newStart := h.OldStartLine, h.NewStartLine
+		if h.OldLines == 0 {
+			oldStart++
+		}
+		if h.NewLines == 0 {
+			newStart++
+		}
+
+		if line < oldStart {
+			break
+		}
+
+		if line >= oldStart+h.OldLines {
+			offset = newStart + h.NewLines - (oldStart + h.OldLines)
+			continue
+		}
+
+		oldLine, newLine := oldStart, newStart
+		for _, chunk := range h.Chunks {
+			switch chunk.Type {
+			case lineContext:
+				if line < oldLine+chunk.Lines {
+					return newLine + line - oldLine, true
+				}
+
+				oldLine += chunk.Lines
+				newLine += chunk.Lines
+			case lineDeleted:
+				if line < oldLine+chunk.Lines {
+					return 0, false
+				}
+
+				oldLine += chunk.Lines
+			case lineAdded:
+				newLine += chunk.Lines
+			}
+		}
+
+		return 0, false
+	}
+
+	return line + offset, true
+}
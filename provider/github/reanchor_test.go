@@ -0,0 +1,120 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/meyskens/lookout"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapLine(t *testing.T) {
+	require := require.New(t)
+
+	hs, _, err := parseHunks(`@@ -3,0 +4,2 @@
++new-line1
++new-line2
+@@ -10,4 +12,3 @@
+ context-line1
+-old-line1
+-old-line2
++changed-line1
+ context-line2
+@@ -20 +20,0 @@
+-removed-line`)
+	require.NoError(err)
+
+	cases := []struct {
+		line, newLine int
+		ok            bool
+	}{
+		{1, 1, true},
+		{3, 3, true},
+		{4, 6, true},
+		{9, 11, true},
+		{10, 12, true},
+		{11, 0, false},
+		{12, 0, false},
+		{13, 14, true},
+		{19, 20, true},
+		{20, 0, false},
+		{21, 21, true},
+		{100, 100, true},
+	}
+
+	for _, c := range cases {
+		newLine, ok := mapLine(hs, c.line)
+		require.Equal(c.ok, ok, "line %d", c.line)
+		require.Equal(c.newLine, newLine, "line %d", c.line)
+	}
+
+	newLine, ok := mapLine(nil, 42)
+	require.True(ok)
+	require.Equal(42, newLine)
+}
+
+func TestReanchorComments(t *testing.T) {
+	require := require.New(t)
+
+	loader := &patchLoaderMock{revisionPatches: map[string]string{
+		"main.go": `@@ -3,0 +4,2 @@
++new-line1
++new-line2
+@@ -10,2 +12,2 @@
+-old-line1
++changed-line1
+ context-line1`,
+		"unchanged.go": "",
+	}}
+
+	rangeComment := &lookout.Comment{File: "main.go", Text: "range"}
+	require.NoError(lookout.SetCommentRange(rangeComment, 5, 7))
+	suggestion := &lookout.Comment{File: "main.go", Text: "suggestion"}
+	require.NoError(lookout.SuggestChange(suggestion, lookout.Suggestion{
+		StartLine: 6, EndLine: 6, Replacement: "fixed",
+	}))
+	changedRange := &lookout.Comment{File: "main.go", Text: "changed range"}
+	require.NoError(lookout.SetCommentRange(changedRange, 9, 10))
+
+	input := []lookout.AnalyzerComments{{
+		Config: lookout.AnalyzerConfig{Name: "mock"},
+		Comments: []*lookout.Comment{
+			{Text: "global"},
+			{File: "main.go", Text: "file"},
+			{File: "main.go", Line: 2, Text: "before"},
+			rangeComment,
+			suggestion,
+			changedRange,
+			{File: "main.go", Line: 10, Text: "changed"},
+			{File: "main.go", Line: 11, Text: "context"},
+			{File: "unchanged.go", Line: 10, Text: "unchanged"},
+			{File: "removed.go", Line: 1, Text: "removed"},
+		},
+	}}
+
+	result := reanchorComments(context.TODO(), loader,
+		mockEvent.Base, mockEvent.Head, input)
+
+	expectedRange := &lookout.Comment{File: "main.go", Text: "range"}
+	require.NoError(lookout.SetCommentRange(expectedRange, 7, 9))
+	expectedSuggestion := &lookout.Comment{File: "main.go", Text: "suggestion"}
+	require.NoError(lookout.SuggestChange(expectedSuggestion, lookout.Suggestion{
+		StartLine: 8, EndLine: 8, Replacement: "fixed",
+	}))
+
+	require.Len(result, 1)
+	require.Equal("mock", result[0].Config.Name)
+	require.Equal([]*lookout.Comment{
+		{Text: "global"},
+		{File: "main.go", Text: "file"},
+		{File: "main.go", Line: 2, Text: "before"},
+		expectedRange,
+		expectedSuggestion,
+		{File: "main.go", Line: 13, Text: "context"},
+		{File: "unchanged.go", Line: 10, Text: "unchanged"},
+	}, result[0].Comments)
+
+	// the input is not modified
+	require.Len(input[0].Comments, 10)
+	require.Equal(int32(7), rangeComment.Line)
+}
@@ -2,6 +2,9 @@ package github
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
 	"sort"
 	"strings"
 	"text/template"
@@ -37,17 +40,105 @@ var (
 	ErrTemplateError = errors.NewKind("error generating the footer: %s")
 )
 
+// reviewRequest is a github.PullRequestReviewRequest with reviewComment
+// comments
+type reviewRequest struct {
+	CommitID *string          `json:"commit_id,omitempty"`
+	Body     *string          `json:"body,omitempty"`
+	Event    *string          `json:"event,omitempty"`
+	Comments []*reviewComment `json:"comments,omitempty"`
+}
+
+// reviewComment is a github.DraftReviewComment that can also span multiple
+// lines. go-github does not support the multi-line comments yet.
+type reviewComment struct {
+	Path     *string
+	Position *int
+	Body     *string
+	// StartLine and Line are the lines of a multi-line comment in the head
+	// revision. Position is the position of Line, it is only used to find
+	// the posted and duplicated comments.
+	StartLine *int
+	Line      *int
+}
+
+// GetPath returns the Path field if it's non-nil, zero value otherwise.
+func (c *reviewComment) GetPath() string {
+	if c == nil || c.Path == nil {
+		return ""
+	}
+	return *c.Path
+}
+
+// GetPosition returns the Position field if it's non-nil, zero value otherwise.
+func (c *reviewComment) GetPosition() int {
+	if c == nil || c.Position == nil {
+		return 0
+	}
+	return *c.Position
+}
+
+// GetBody returns the Body field if it's non-nil, zero value otherwise.
+func (c *reviewComment) GetBody() string {
+	if c == nil || c.Body == nil {
+		return ""
+	}
+	return *c.Body
+}
+
+// GetStartLine returns the StartLine field if it's non-nil, zero value otherwise.
+func (c *reviewComment) GetStartLine() int {
+	if c == nil || c.StartLine == nil {
+		return 0
+	}
+	return *c.StartLine
+}
+
+// rightSide is the side of the diff of the head revision
+const rightSide = "RIGHT"
+
+// multiLineComment is the request format of the multi-line review comments
+type multiLineComment struct {
+	Path      *string `json:"path,omitempty"`
+	Body      *string `json:"body,omitempty"`
+	StartLine *int    `json:"start_line"`
+	StartSide string  `json:"start_side"`
+	Line      *int    `json:"line"`
+	Side      string  `json:"side"`
+}
+
+// MarshalJSON implements json.Marshaler. The multi-line comments use the
+// line fields, and the rest the position.
+func (c *reviewComment) MarshalJSON() ([]byte, error) {
+	if c.StartLine == nil {
+		return json.Marshal(&github.DraftReviewComment{
+			Path:     c.Path,
+			Position: c.Position,
+			Body:     c.Body,
+		})
+	}
+
+	return json.Marshal(&multiLineComment{
+		Path:      c.Path,
+		Body:      c.Body,
+		StartLine: c.StartLine,
+		StartSide: rightSide,
+		Line:      c.Line,
+		Side:      rightSide,
+	})
+}
+
 // createReview creates pull request review on github using multiple http calls
 // in case of too many comments
 func createReview(
 	ctx context.Context,
 	client *Client,
 	owner, repo string, number int,
-	req *github.PullRequestReviewRequest,
+	req *reviewRequest,
 ) error {
 	requests := splitReviewRequest(req, batchReviewComments)
 	for i, req := range requests {
-		_, resp, err := client.PullRequests.CreateReview(ctx, owner, repo, number, req)
+		resp, err := postReview(ctx, client, owner, repo, number, req)
 
 		if err = handleAPIError(resp, err, "review could not be pushed"); err != nil {
 			return err
@@ -62,8 +153,25 @@ func createReview(
 	return nil
 }
 
-func filterPostedComments(comments []*github.DraftReviewComment, posted []*github.PullRequestComment) []*github.DraftReviewComment {
-	var filtered []*github.DraftReviewComment
+// postReview creates a pull request review. It does the same request as
+// PullRequestsService.CreateReview, with support for multi-line comments.
+func postReview(
+	ctx context.Context,
+	client *Client,
+	owner, repo string, number int,
+	review *reviewRequest,
+) (*github.Response, error) {
+	u := fmt.Sprintf("repos/%v/%v/pulls/%d/reviews", owner, repo, number)
+	req, err := client.NewRequest("POST", u, review)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(ctx, req, new(github.PullRequestReview))
+}
+
+func filterPostedComments(comments []*reviewComment, posted []*github.PullRequestComment) []*reviewComment {
+	var filtered []*reviewComment
 
 	for _, comment := range comments {
 		var filterOut bool
@@ -143,29 +251,27 @@ func getPostedComment(ctx context.Context, client *Client, owner, repo string, n
 	return result, nil
 }
 
-func mergeComments(comments []*github.DraftReviewComment) []*github.DraftReviewComment {
-	var mergedComments []*github.DraftReviewComment
+func mergeComments(comments []*reviewComment) []*reviewComment {
+	var mergedComments []*reviewComment
 
-	// sort by path and position
-	sort.Slice(comments, func(i, j int) bool {
-		if comments[i].GetPath() < comments[j].GetPath() {
-			return true
-		}
-		if comments[i].GetPath() > comments[j].GetPath() {
-			return false
+	// sort by path, position and start line
+	sort.SliceStable(comments, func(i, j int) bool {
+		if comments[i].GetPath() != comments[j].GetPath() {
+			return comments[i].GetPath() < comments[j].GetPath()
 		}
-		if comments[i].GetPosition() < comments[j].GetPosition() {
-			return true
+		if comments[i].GetPosition() != comments[j].GetPosition() {
+			return comments[i].GetPosition() < comments[j].GetPosition()
 		}
 
-		return false
+		return comments[i].GetStartLine() < comments[j].GetStartLine()
 	})
 
-	var lastComment *github.DraftReviewComment
+	var lastComment *reviewComment
 	for _, comment := range comments {
 		if lastComment != nil &&
 			lastComment.GetPath() == comment.GetPath() &&
-			lastComment.GetPosition() == comment.GetPosition() {
+			lastComment.GetPosition() == comment.GetPosition() &&
+			lastComment.GetStartLine() == comment.GetStartLine() {
 
 			mergedBody := lastComment.GetBody() + commentsSeparator + comment.GetBody()
 			lastComment.Body = &mergedBody
@@ -180,18 +286,18 @@ func mergeComments(comments []*github.DraftReviewComment) []*github.DraftReviewC
 }
 
 // splitReviewRequest transforms a review into a list of reviews with not more than N comments in each
-func splitReviewRequest(review *github.PullRequestReviewRequest, n int) []*github.PullRequestReviewRequest {
+func splitReviewRequest(review *reviewRequest, n int) []*reviewRequest {
 	if len(review.Comments) <= n {
-		return []*github.PullRequestReviewRequest{review}
+		return []*reviewRequest{review}
 	}
 
-	var result []*github.PullRequestReviewRequest
+	var result []*reviewRequest
 	comments := review.Comments
 	// set body only to the last review
 	emptyBody := ""
 
 	for len(comments) > n {
-		result = append(result, &github.PullRequestReviewRequest{
+		result = append(result, &reviewRequest{
 			CommitID: review.CommitID,
 			Event:    review.Event,
 			Body:     &emptyBody,
@@ -202,7 +308,7 @@ func splitReviewRequest(review *github.PullRequestReviewRequest, n int) []*githu
 	}
 
 	if len(comments) > 0 {
-		result = append(result, &github.PullRequestReviewRequest{
+		result = append(result, &reviewRequest{
 			CommitID: review.CommitID,
 			Event:    review.Event,
 			Body:     &emptyBody,
@@ -269,23 +375,155 @@ func removeFootnote(text string) string {
 	return strings.SplitN(text, footnoteSeparator, 2)[0]
 }
 
-// convertComments transforms []*lookout.Comment to []*github.DraftReviewComment and list of string for body
-func convertComments(ctx context.Context, cs []*lookout.Comment, dl *diffLines) ([]string, []*github.DraftReviewComment) {
+// Values of ProviderConfig.OutOfDiffComments, what to do with the comments on
+// lines that are not part of the pull request diff
+const (
+	// OutOfDiffDrop skips them, it is the default
+	OutOfDiffDrop = "drop"
+	// OutOfDiffNearest posts them on the nearest added line, with a note
+	OutOfDiffNearest = "nearest"
+	// OutOfDiffBody adds them to the review body, grouped by file
+	OutOfDiffBody = "body"
+)
+
+// outOfDiff defines what to do with the comments on lines that are not part
+// of the diff
+type outOfDiff struct {
+	mode string
+	// blobURL is the URL of the files in the head revision, used to link
+	// the lines of the comments
+	blobURL string
+}
+
+// newOutOfDiff returns the outOfDiff for the given mode and event
+func newOutOfDiff(mode string, e *lookout.ReviewEvent) outOfDiff {
+	return outOfDiff{
+		mode: mode,
+		blobURL: fmt.Sprintf("%s/blob/%s/",
+			strings.TrimSuffix(e.Head.InternalRepositoryURL, ".git"), e.Head.Hash),
+	}
+}
+
+// fileURL returns the URL of file in the head revision
+func (o outOfDiff) fileURL(file string) string {
+	parts := strings.Split(file, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+
+	return o.blobURL + strings.Join(parts, "/")
+}
+
+// permalink returns the URL of the lines from start to end of file
+func (o outOfDiff) permalink(file string, start, end int32) string {
+	link := o.fileURL(file)
+	if start == end {
+		return fmt.Sprintf("%s#L%d", link, end)
+	}
+
+	return fmt.Sprintf("%s#L%d-L%d", link, start, end)
+}
+
+// linesLink returns a Markdown link to the lines from start to end of file
+func (o outOfDiff) linesLink(file string, start, end int32) string {
+	if start == end {
+		return fmt.Sprintf("[line %d](%s)", end, o.permalink(file, start, end))
+	}
+
+	return fmt.Sprintf("[lines %d-%d](%s)", start, end, o.permalink(file, start, end))
+}
+
+// nearestComment returns the comment c moved to the nearest added line of
+// the diff, with a note about the original lines
+func (o outOfDiff) nearestComment(dl *diffLines, c *lookout.Comment, text string) (*reviewComment, bool) {
+	_, pos, err := dl.NearestLine(c.File, int(c.Line))
+	if err != nil {
+		return nil, false
+	}
+
+	start, end := lookout.CommentRange(c)
+	body := fmt.Sprintf("_This comment is about %s, that is not part of the diff._\n\n%s",
+		o.linesLink(c.File, start, end), text)
+
+	return &reviewComment{
+		Path:     &c.File,
+		Position: &pos,
+		Body:     &body,
+	}, true
+}
+
+// outOfDiffComment is a comment added to the review body
+type outOfDiffComment struct {
+	file       string
+	start, end int32
+	text       string
+}
+
+// body returns the comments cs grouped by file, to be added to the review
+// body
+func (o outOfDiff) body(cs []outOfDiffComment) string {
+	if len(cs) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].file != cs[j].file {
+			return cs[i].file < cs[j].file
+		}
+
+		return cs[i].start < cs[j].start
+	})
+
+	parts := []string{"Comments on lines that are not part of the diff:"}
+	for i, c := range cs {
+		if i == 0 || cs[i-1].file != c.file {
+			parts = append(parts, fmt.Sprintf("**[%s](%s)**", c.file, o.fileURL(c.file)))
+		}
+
+		parts = append(parts, fmt.Sprintf("On %s: %s", o.linesLink(c.file, c.start, c.end), c.text))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// convertComments transforms []*lookout.Comment to []*reviewComment and list
+// of string for body. The comments on lines that are not part of the diff
+// are handled as defined by fallback.
+func convertComments(
+	ctx context.Context,
+	cs []*lookout.Comment,
+	dl *diffLines,
+	fallback outOfDiff,
+) ([]string, []*reviewComment) {
 	var bodyComments []string
-	var comments []*github.DraftReviewComment
+	var comments []*reviewComment
+	var outOfDiffComments []outOfDiffComment
 
 	for _, c := range cs {
+		text, ext := lookout.SplitCommentExtension(c.Text)
 		if c.File == "" {
-			bodyComments = append(bodyComments, c.Text)
+			bodyComments = append(bodyComments, text)
 			continue
 		}
 
+		// comments on the previous name of a renamed file are posted on its
+		// new name
+		if path := dl.Path(c.File); path != c.File {
+			renamed := *c
+			renamed.File = path
+			c = &renamed
+		}
+
 		if c.Line < 1 {
+			if ext != nil && ext.Suggestion != nil {
+				text = addSuggestion(text, 0, 0, ext.Suggestion)
+			}
+
 			line := 1
-			comment := &github.DraftReviewComment{
+			comment := &reviewComment{
 				Path:     &c.File,
 				Position: &line,
-				Body:     &c.Text,
+				Body:     &text,
 			}
 			comments = append(comments, comment)
 			continue
@@ -293,6 +531,35 @@ func convertComments(ctx context.Context, cs []*lookout.Comment, dl *diffLines)
 
 		logger := convertLineLogger(ctx, c)
 		line, err := dl.ConvertLine(c.File, int(c.Line), true)
+		if ErrLineOutOfDiff.Is(err) || ErrLineNotAddition.Is(err) || ErrFileNotFound.Is(err) {
+			// the suggestion can't be applied out of its lines, it is added as
+			// a regular code block
+			text := text
+			if ext != nil && ext.Suggestion != nil {
+				text = addSuggestion(text, 0, 0, ext.Suggestion)
+			}
+
+			if fallback.mode == OutOfDiffNearest {
+				if comment, ok := fallback.nearestComment(dl, c, text); ok {
+					logger.Debugf("posting comment out of the diff on the nearest added line")
+					comments = append(comments, comment)
+					continue
+				}
+			}
+
+			if fallback.mode == OutOfDiffNearest || fallback.mode == OutOfDiffBody {
+				logger.Debugf("adding comment out of the diff to the review body")
+				start, end := lookout.CommentRange(c)
+				outOfDiffComments = append(outOfDiffComments, outOfDiffComment{
+					file:  c.File,
+					start: start,
+					end:   end,
+					text:  text,
+				})
+				continue
+			}
+		}
+
 		if ErrLineOutOfDiff.Is(err) {
 			logger.Debugf("skipping comment out the diff range")
 			continue
@@ -317,23 +584,112 @@ func convertComments(ctx context.Context, cs []*lookout.Comment, dl *diffLines)
 			continue
 		}
 
+		if ErrPatchNotLoaded.Is(err) {
+			logger.Errorf(err, "skipping comment because the diff of the file could not be computed")
+			continue
+		}
+
 		if err != nil {
 			convertLineLogger(ctx, c).Errorf(err, "skipping comment because of unknown error")
 			continue
 		}
 
-		comment := &github.DraftReviewComment{
+		comment := &reviewComment{
 			Path:     &c.File,
 			Position: &line,
-			Body:     &c.Text,
 		}
 
+		start, end := lookout.CommentRange(c)
+		if start < end {
+			if inDiff(dl, c.File, int(start), int(end)) {
+				startLine, endLine := int(start), int(end)
+				comment.StartLine = &startLine
+				comment.Line = &endLine
+			} else {
+				logger.With(log.Fields{"start-line": start}).Debugf(
+					"posting comment on its last line, the line range is not part of the diff")
+				start = end
+			}
+		}
+
+		if ext != nil && ext.Suggestion != nil {
+			text = addSuggestion(text, start, end, ext.Suggestion)
+		}
+
+		comment.Body = &text
 		comments = append(comments, comment)
 	}
 
+	if body := fallback.body(outOfDiffComments); body != "" {
+		bodyComments = append(bodyComments, body)
+	}
+
 	return bodyComments, comments
 }
 
+// inDiff returns true if the lines from start to end of file are part of the
+// same hunk of the diff, so they can be commented together. The range can
+// include deleted lines, GitHub only needs both ends in the same hunk.
+func inDiff(dl *diffLines, file string, start, end int) bool {
+	if _, err := dl.ConvertLine(file, start, false); err != nil {
+		return false
+	}
+
+	if _, err := dl.ConvertLine(file, end, false); err != nil {
+		return false
+	}
+
+	return dl.SameHunk(file, start, end)
+}
+
+// addSuggestion adds the suggested change s to the text of a comment on the
+// lines from start to end. GitHub suggestions replace the lines of the
+// comment, so other ranges are added as a regular code block.
+func addSuggestion(text string, start, end int32, s *lookout.Suggestion) string {
+	fence := codeFence(s.Replacement)
+	code := s.Replacement + "\n"
+	if s.Replacement == "" {
+		code = ""
+	}
+
+	var block string
+	if s.StartLine == start && s.EndLine == end {
+		block = fence + "suggestion\n" + code + fence
+	} else {
+		block = fmt.Sprintf("Suggested change for lines %d-%d:\n%s\n%s%s",
+			s.StartLine, s.EndLine, fence, code, fence)
+	}
+
+	if text == "" {
+		return block
+	}
+
+	return text + "\n\n" + block
+}
+
+// codeFence returns a fence for a code block with content, longer than any
+// run of backticks in it
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r != '`' {
+			run = 0
+			continue
+		}
+
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+
+	return strings.Repeat("`", longest+1)
+}
+
 func convertLineLogger(ctx context.Context, c *lookout.Comment) log.Logger {
 	return ctxlog.Get(ctx).With(log.Fields{
 		"file": c.File,
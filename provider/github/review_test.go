@@ -2,6 +2,7 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/google/go-github/v28/github"
@@ -12,7 +13,7 @@ import (
 func TestMergeComments(t *testing.T) {
 	require := require.New(t)
 
-	input := []*github.DraftReviewComment{
+	input := []*reviewComment{
 		{
 			Path:     strptr("file1"),
 			Position: intptr(1),
@@ -45,7 +46,7 @@ func TestMergeComments(t *testing.T) {
 func TestFilterPostedComments(t *testing.T) {
 	require := require.New(t)
 
-	input := []*github.DraftReviewComment{
+	input := []*reviewComment{
 		{
 			Path:     strptr("file1"),
 			Position: intptr(1),
@@ -85,20 +86,20 @@ func TestSplitReviewRequest(t *testing.T) {
 
 	n := 2
 
-	rw := &github.PullRequestReviewRequest{
+	rw := &reviewRequest{
 		Event: strptr(commentEvent),
 		Body:  strptr("body"),
 	}
 
-	rw.Comments = []*github.DraftReviewComment{
+	rw.Comments = []*reviewComment{
 		{Body: strptr("comment1")},
 	}
 
 	r := splitReviewRequest(rw, n)
 	require.Len(r, 1)
-	require.Equal([]*github.PullRequestReviewRequest{rw}, r)
+	require.Equal([]*reviewRequest{rw}, r)
 
-	rw.Comments = []*github.DraftReviewComment{
+	rw.Comments = []*reviewComment{
 		{Body: strptr("comment1")},
 		{Body: strptr("comment2")},
 		{Body: strptr("comment3")},
@@ -106,11 +107,11 @@ func TestSplitReviewRequest(t *testing.T) {
 
 	r = splitReviewRequest(rw, n)
 	require.Len(r, 2)
-	require.Equal([]*github.PullRequestReviewRequest{
+	require.Equal([]*reviewRequest{
 		{
 			Event: strptr(commentEvent),
 			Body:  strptr(""),
-			Comments: []*github.DraftReviewComment{
+			Comments: []*reviewComment{
 				{Body: strptr("comment1")},
 				{Body: strptr("comment2")},
 			},
@@ -118,13 +119,13 @@ func TestSplitReviewRequest(t *testing.T) {
 		{
 			Event: strptr(commentEvent),
 			Body:  strptr("body"),
-			Comments: []*github.DraftReviewComment{
+			Comments: []*reviewComment{
 				{Body: strptr("comment3")},
 			},
 		},
 	}, r)
 
-	rw.Comments = []*github.DraftReviewComment{
+	rw.Comments = []*reviewComment{
 		{Body: strptr("comment1")},
 		{Body: strptr("comment2")},
 		{Body: strptr("comment3")},
@@ -166,18 +167,172 @@ func TestConvertCommentsOutOfRange(t *testing.T) {
 			Text: "out of range comment after",
 		}}
 
-	bodyComments, ghComments := convertComments(context.TODO(), input, dl)
+	bodyComments, ghComments := convertComments(context.TODO(), input, dl, outOfDiff{})
 
 	require.Len(bodyComments, 1)
 	require.Len(ghComments, 1)
 
-	require.Equal([]*github.DraftReviewComment{&github.DraftReviewComment{
+	require.Equal([]*reviewComment{&reviewComment{
 		Path:     strptr("main.go"),
 		Position: intptr(1),
 		Body:     strptr("Line comment"),
 	}}, ghComments)
 }
 
+func TestConvertCommentsRenamed(t *testing.T) {
+	require := require.New(t)
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{github.CommitFile{
+			Filename:         strptr("main.go"),
+			PreviousFilename: strptr("old.go"),
+			Status:           strptr("renamed"),
+			Changes:          intptr(10),
+			Patch:            strptr(mockedPatch),
+		}}})
+
+	input := []*lookout.Comment{
+		&lookout.Comment{
+			File: "old.go",
+			Line: 3,
+			Text: "Line comment",
+		},
+		&lookout.Comment{
+			File: "old.go",
+			Text: "File comment",
+		}}
+
+	_, ghComments := convertComments(context.TODO(), input, dl, outOfDiff{})
+	require.Equal([]*reviewComment{&reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(1),
+		Body:     strptr("Line comment"),
+	}, &reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(1),
+		Body:     strptr("File comment"),
+	}}, ghComments)
+
+	// the comments of the analyzer are not modified
+	require.Equal("old.go", input[0].File)
+}
+
+func TestConvertCommentsOutOfDiffNearest(t *testing.T) {
+	require := require.New(t)
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{github.CommitFile{
+			Filename: strptr("main.go"),
+			Patch:    strptr(mockedPatch),
+		}}})
+
+	rangeComment := &lookout.Comment{File: "main.go", Text: "range before"}
+	require.NoError(lookout.SetCommentRange(rangeComment, 1, 2))
+
+	suggestion := &lookout.Comment{File: "main.go", Text: "after"}
+	require.NoError(lookout.SuggestChange(suggestion, lookout.Suggestion{
+		StartLine:   205,
+		EndLine:     205,
+		Replacement: "const a = 1",
+	}))
+
+	otherSuggestion := &lookout.Comment{File: "other.go", Text: "not in diff"}
+	require.NoError(lookout.SuggestChange(otherSuggestion, lookout.Suggestion{
+		StartLine:   5,
+		EndLine:     6,
+		Replacement: "const b = 2",
+	}))
+
+	input := []*lookout.Comment{
+		rangeComment,
+		suggestion,
+		otherSuggestion,
+	}
+
+	fallback := newOutOfDiff(OutOfDiffNearest, mockEvent)
+	bodyComments, ghComments := convertComments(context.TODO(), input, dl, fallback)
+
+	url := "https://github.com/foo/bar/blob/" + mockEvent.Head.Hash
+	require.Equal([]*reviewComment{&reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(1),
+		Body: strptr("_This comment is about [lines 1-2](" + url +
+			"/main.go#L1-L2), that is not part of the diff._\n\nrange before"),
+	}, &reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(10),
+		Body: strptr("_This comment is about [line 205](" + url +
+			"/main.go#L205), that is not part of the diff._\n\nafter\n\n" +
+			"Suggested change for lines 205-205:\n```\nconst a = 1\n```"),
+	}}, ghComments)
+
+	require.Equal([]string{
+		"Comments on lines that are not part of the diff:\n\n" +
+			"**[other.go](" + url + "/other.go)**\n\n" +
+			"On [lines 5-6](" + url + "/other.go#L5-L6): not in diff\n\n" +
+			"Suggested change for lines 5-6:\n```\nconst b = 2\n```",
+	}, bodyComments)
+}
+
+func TestConvertCommentsOutOfDiffBody(t *testing.T) {
+	require := require.New(t)
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{github.CommitFile{
+			Filename: strptr("main.go"),
+			Patch:    strptr(mockedPatch),
+		}}})
+
+	after := &lookout.Comment{File: "main.go", Text: "after"}
+	require.NoError(lookout.SuggestChange(after, lookout.Suggestion{
+		StartLine:   205,
+		EndLine:     205,
+		Replacement: "const a = 1",
+	}))
+
+	input := []*lookout.Comment{
+		after,
+		&lookout.Comment{
+			Text: "Body comment",
+		},
+		&lookout.Comment{
+			File: "main.go",
+			Line: 3,
+			Text: "Line comment",
+		},
+		&lookout.Comment{
+			File: "main.go",
+			Line: 1,
+			Text: "before",
+		},
+		&lookout.Comment{
+			File: "dir/file name.go",
+			Line: 5,
+			Text: "not in diff",
+		}}
+
+	fallback := newOutOfDiff(OutOfDiffBody, mockEvent)
+	bodyComments, ghComments := convertComments(context.TODO(), input, dl, fallback)
+
+	require.Equal([]*reviewComment{&reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(1),
+		Body:     strptr("Line comment"),
+	}}, ghComments)
+
+	url := "https://github.com/foo/bar/blob/" + mockEvent.Head.Hash
+	require.Equal([]string{
+		"Body comment",
+		"Comments on lines that are not part of the diff:\n\n" +
+			"**[dir/file name.go](" + url + "/dir/file%20name.go)**\n\n" +
+			"On [line 5](" + url + "/dir/file%20name.go#L5): not in diff\n\n" +
+			"**[main.go](" + url + "/main.go)**\n\n" +
+			"On [line 1](" + url + "/main.go#L1): before\n\n" +
+			"On [line 205](" + url + "/main.go#L205): after\n\n" +
+			"Suggested change for lines 205-205:\n```\nconst a = 1\n```",
+	}, bodyComments)
+}
+
 func TestConvertCommentsWrongFile(t *testing.T) {
 	require := require.New(t)
 
@@ -205,7 +360,7 @@ func TestConvertCommentsWrongFile(t *testing.T) {
 			Text: "Line comment",
 		}}
 
-	bodyComments, ghComments := convertComments(context.TODO(), input, dl)
+	bodyComments, ghComments := convertComments(context.TODO(), input, dl, outOfDiff{})
 
 	require.Len(bodyComments, 2)
 	require.Len(ghComments, 2)
@@ -215,17 +370,173 @@ func TestConvertCommentsWrongFile(t *testing.T) {
 		"Another global comment",
 	}, bodyComments)
 
-	require.Equal([]*github.DraftReviewComment{&github.DraftReviewComment{
+	require.Equal([]*reviewComment{&reviewComment{
 		Path:     strptr("main.go"),
 		Body:     strptr("File comment"),
 		Position: intptr(1),
-	}, &github.DraftReviewComment{
+	}, &reviewComment{
 		Path:     strptr("main.go"),
 		Position: intptr(3),
 		Body:     strptr("Line comment"),
 	}}, ghComments)
 }
 
+func TestConvertCommentsSuggestion(t *testing.T) {
+	require := require.New(t)
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{github.CommitFile{
+			Filename: strptr("main.go"),
+			Patch:    strptr(mockedPatch),
+		}}})
+
+	single := &lookout.Comment{File: "main.go", Text: "Use a constant"}
+	require.NoError(lookout.SuggestChange(single, lookout.Suggestion{
+		StartLine:   3,
+		EndLine:     3,
+		Replacement: "const a = 1",
+	}))
+
+	multi := &lookout.Comment{File: "main.go", Text: "Simplify"}
+	require.NoError(lookout.SuggestChange(multi, lookout.Suggestion{
+		StartLine:   4,
+		EndLine:     5,
+		Replacement: "x := ```y```",
+	}))
+
+	// the range starts out of the diff, so the comment is only on its last line
+	rangeOutOfDiff := &lookout.Comment{File: "main.go", Text: "Remove"}
+	require.NoError(lookout.SuggestChange(rangeOutOfDiff, lookout.Suggestion{
+		StartLine: 1,
+		EndLine:   6,
+	}))
+
+	global := &lookout.Comment{Text: "Global comment"}
+	require.NoError(lookout.SuggestChange(global, lookout.Suggestion{StartLine: 1, EndLine: 1}))
+
+	bodyComments, ghComments := convertComments(context.TODO(),
+		[]*lookout.Comment{single, multi, rangeOutOfDiff, global}, dl, outOfDiff{})
+
+	require.Equal([]string{"Global comment"}, bodyComments)
+	require.Equal([]*reviewComment{&reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(1),
+		Body:     strptr("Use a constant\n\n```suggestion\nconst a = 1\n```"),
+	}, &reviewComment{
+		Path:      strptr("main.go"),
+		Position:  intptr(3),
+		Body:      strptr("Simplify\n\n````suggestion\nx := ```y```\n````"),
+		StartLine: intptr(4),
+		Line:      intptr(5),
+	}, &reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(4),
+		Body:     strptr("Remove\n\nSuggested change for lines 1-6:\n```\n```"),
+	}}, ghComments)
+}
+
+func TestConvertCommentsRange(t *testing.T) {
+	require := require.New(t)
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{github.CommitFile{
+			Filename: strptr("main.go"),
+			Patch:    strptr(mockedPatch),
+		}}})
+
+	c := &lookout.Comment{File: "main.go", Text: "Long function"}
+	require.NoError(lookout.SetCommentRange(c, 3, 10))
+
+	_, ghComments := convertComments(context.TODO(), []*lookout.Comment{c}, dl, outOfDiff{})
+	require.Equal([]*reviewComment{&reviewComment{
+		Path:      strptr("main.go"),
+		Position:  intptr(8),
+		Body:      strptr("Long function"),
+		StartLine: intptr(3),
+		Line:      intptr(10),
+	}}, ghComments)
+
+	b, err := json.Marshal(ghComments)
+	require.NoError(err)
+	require.JSONEq(`[{
+		"path": "main.go",
+		"body": "Long function",
+		"start_line": 3,
+		"start_side": "RIGHT",
+		"line": 10,
+		"side": "RIGHT"
+	}]`, string(b))
+}
+
+func TestConvertCommentsRangeModified(t *testing.T) {
+	require := require.New(t)
+
+	patch := "@@ -1,5 +1,5 @@\n" +
+		" package main\n" +
+		" \n" +
+		"-func old() {\n" +
+		"+func new() {\n" +
+		" 	a := 1\n" +
+		" }\n" +
+		"@@ -20,2 +20,3 @@\n" +
+		" x\n" +
+		"+y\n" +
+		" z\n"
+
+	dl := newDiffLines(&github.CommitsComparison{
+		Files: []github.CommitFile{github.CommitFile{
+			Filename: strptr("main.go"),
+			Patch:    strptr(patch),
+		}}})
+
+	// the range includes the deleted line
+	modified := &lookout.Comment{File: "main.go", Text: "Renamed"}
+	require.NoError(lookout.SetCommentRange(modified, 1, 3))
+
+	// the range spans two hunks
+	twoHunks := &lookout.Comment{File: "main.go", Text: "Two hunks"}
+	require.NoError(lookout.SetCommentRange(twoHunks, 3, 21))
+
+	_, ghComments := convertComments(context.TODO(),
+		[]*lookout.Comment{modified, twoHunks}, dl, outOfDiff{})
+	require.Equal([]*reviewComment{&reviewComment{
+		Path:      strptr("main.go"),
+		Position:  intptr(4),
+		Body:      strptr("Renamed"),
+		StartLine: intptr(1),
+		Line:      intptr(3),
+	}, &reviewComment{
+		Path:     strptr("main.go"),
+		Position: intptr(9),
+		Body:     strptr("Two hunks"),
+	}}, ghComments)
+}
+
+func TestMergeCommentsRange(t *testing.T) {
+	require := require.New(t)
+
+	input := []*reviewComment{
+		{Path: strptr("file1"), Position: intptr(3), Body: strptr("range"),
+			StartLine: intptr(1), Line: intptr(3)},
+		{Path: strptr("file1"), Position: intptr(3), Body: strptr("line")},
+		{Path: strptr("file1"), Position: intptr(3), Body: strptr("other line")},
+	}
+
+	require.Equal([]*reviewComment{
+		{Path: strptr("file1"), Position: intptr(3),
+			Body: strptr("line" + commentsSeparator + "other line")},
+		{Path: strptr("file1"), Position: intptr(3), Body: strptr("range"),
+			StartLine: intptr(1), Line: intptr(3)},
+	}, mergeComments(input))
+}
+
+func TestAddSuggestionRemoval(t *testing.T) {
+	require := require.New(t)
+
+	body := addSuggestion("Unused", 7, 7, &lookout.Suggestion{StartLine: 7, EndLine: 7})
+	require.Equal("Unused\n\n```suggestion\n```", body)
+}
+
 func TestCouldNotExecuteFooterTemplate(t *testing.T) {
 	require := require.New(t)
 
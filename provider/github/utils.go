@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 
 	"github.com/meyskens/lookout"
@@ -141,3 +142,23 @@ func extractRepo(ref lookout.ReferencePointer) (repo string, err error) {
 
 	return
 }
+
+// FileContent returns the content of the file at path in the given ref of the
+// repository, or "" if it does not exist. An empty ref means the default branch
+func FileContent(ctx context.Context, client *github.Client, owner, repo, path, ref string) (string, error) {
+	file, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, path,
+		&github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if file == nil {
+		return "", nil
+	}
+
+	return file.GetContent()
+}
@@ -23,6 +23,10 @@ type ProviderConfig struct {
 	AppID                    int    `yaml:"app_id"`
 	InstallationSyncInterval string `yaml:"installation_sync_interval"`
 	WatchMinInterval         string `yaml:"watch_min_interval"`
+	// OutOfDiffComments defines what to do with the comments on lines that
+	// are not part of the pull request diff: OutOfDiffDrop, OutOfDiffNearest
+	// or OutOfDiffBody. Empty means OutOfDiffDrop.
+	OutOfDiffComments string `yaml:"out_of_diff_comments"`
 }
 
 // don't call github more often than
@@ -32,7 +32,25 @@ func (p *Poster) Post(ctx context.Context, e lookout.Event,
 
 	for _, a := range aCommentsList {
 		for _, c := range a.Comments {
-			if err := p.enc.Encode(commentToPrint{AnalyzerName: a.Config.Name, Comment: c}); err != nil {
+			// the extension is printed as its own fields
+			text, ext := lookout.SplitCommentExtension(c.Text)
+			cp := *c
+			cp.Text = text
+
+			toPrint := commentToPrint{
+				AnalyzerName: a.Config.Name,
+				Partial:      a.Partial,
+				Comment:      &cp,
+			}
+			if ext != nil {
+				toPrint.Suggestion = ext.Suggestion
+			}
+
+			if start, end := lookout.CommentRange(c); start < end {
+				toPrint.StartLine = start
+			}
+
+			if err := p.enc.Encode(toPrint); err != nil {
 				return err
 			}
 		}
@@ -51,5 +69,9 @@ func (p *Poster) Status(ctx context.Context, e lookout.Event,
 
 type commentToPrint struct {
 	AnalyzerName string `json:"analyzer-name"`
+	Partial      bool   `json:"partial,omitempty"`
 	*lookout.Comment
+	// StartLine is the first line of the comments on a range of lines
+	StartLine  int32               `json:"start_line,omitempty"`
+	Suggestion *lookout.Suggestion `json:"suggestion,omitempty"`
 }
@@ -53,6 +53,17 @@ func TestPoster_Post_OK(t *testing.T) {
 		Text: "This is a another global comment",
 	}}
 
+	suggestion := &lookout.Comment{
+		File: "main.go",
+		Text: "This is a suggestion",
+	}
+	require.NoError(lookout.SuggestChange(suggestion, lookout.Suggestion{
+		StartLine:   6,
+		EndLine:     7,
+		Replacement: "fmt.Println()",
+	}))
+	cs = append(cs, suggestion)
+
 	aCommentsList := []lookout.AnalyzerComments{lookout.AnalyzerComments{
 		Config: lookout.AnalyzerConfig{
 			Name: "mock",
@@ -67,6 +78,7 @@ func TestPoster_Post_OK(t *testing.T) {
 {"analyzer-name":"mock","file":"main.go","text":"This is a file comment"}
 {"analyzer-name":"mock","file":"main.go","line":5,"text":"This is a line comment"}
 {"analyzer-name":"mock","text":"This is a another global comment"}
+{"analyzer-name":"mock","file":"main.go","line":7,"text":"This is a suggestion","start_line":6,"suggestion":{"start_line":6,"end_line":7,"replacement":"fmt.Println()"}}
 `
 
 	require.Equal(expected, b.String())
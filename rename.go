@@ -0,0 +1,68 @@
+package lookout
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// The ChangesRequest of the SDK has no options for the rename detection, they
+// are sent in the gRPC metadata of the GetChanges calls instead. A renamed or
+// copied file is reported as one Change, with the Base and the Head at
+// different paths.
+const (
+	// RenameThresholdMetadata is the gRPC metadata key of the GetChanges
+	// calls with the similarity threshold of the rename detection
+	RenameThresholdMetadata = "lookout-rename-threshold"
+	// DetectCopiesMetadata is the gRPC metadata key of the GetChanges calls
+	// that also detect copies, "true" to enable it
+	DetectCopiesMetadata = "lookout-detect-copies"
+)
+
+// RenameDetection configures the detection of the renamed and copied files in
+// the changes of a GetChanges call
+type RenameDetection struct {
+	// Threshold is the minimum similarity, as a percentage from 1 to 100, of
+	// the contents of a deleted and an added file to report them as a
+	// rename. 100 only detects the files renamed without modifications, and
+	// 0 disables the rename detection.
+	Threshold int
+	// Copies also reports the added files similar to a modified or renamed
+	// file as copies of it, with the same Threshold. It is ignored if the
+	// rename detection is disabled.
+	Copies bool
+}
+
+// WithRenameDetection returns a copy of ctx that makes the GetChanges calls
+// made with it detect renames as configured by d
+func WithRenameDetection(ctx context.Context, d RenameDetection) context.Context {
+	ctx = metadata.AppendToOutgoingContext(ctx,
+		RenameThresholdMetadata, strconv.Itoa(d.Threshold))
+	if d.Copies {
+		ctx = metadata.AppendToOutgoingContext(ctx, DetectCopiesMetadata, "true")
+	}
+
+	return ctx
+}
+
+// RenameDetectionFromContext returns the rename detection sent by the
+// analyzer in the metadata of a GetChanges call. For the calls made inside the
+// same process, it falls back to the one set with WithRenameDetection. The
+// rename detection is disabled if it is not present.
+func RenameDetectionFromContext(ctx context.Context) (RenameDetection, error) {
+	var d RenameDetection
+	if val, ok := metadataValue(ctx, RenameThresholdMetadata); ok {
+		threshold, err := strconv.Atoi(val)
+		if err != nil || threshold < 0 || threshold > 100 {
+			return d, fmt.Errorf("invalid rename threshold %q", val)
+		}
+
+		d.Threshold = threshold
+	}
+
+	val, _ := metadataValue(ctx, DetectCopiesMetadata)
+	d.Copies = val == "true"
+	return d, nil
+}
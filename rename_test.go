@@ -0,0 +1,36 @@
+package lookout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRenameDetectionFromContext(t *testing.T) {
+	require := require.New(t)
+
+	d, err := RenameDetectionFromContext(context.Background())
+	require.NoError(err)
+	require.Equal(RenameDetection{}, d)
+
+	expected := RenameDetection{Threshold: 60, Copies: true}
+	out := WithRenameDetection(context.Background(), expected)
+
+	d, err = RenameDetectionFromContext(out)
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	// the metadata sent by the client is received by the server
+	md, _ := metadata.FromOutgoingContext(out)
+	in := metadata.NewIncomingContext(context.Background(), md)
+	d, err = RenameDetectionFromContext(in)
+	require.NoError(err)
+	require.Equal(expected, d)
+
+	in = metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(RenameThresholdMetadata, "101"))
+	_, err = RenameDetectionFromContext(in)
+	require.EqualError(err, `invalid rename threshold "101"`)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerOptions configures the circuit breaker of each analyzer
+type BreakerOptions struct {
+	// Threshold is the number of consecutive failed requests that opens the
+	// circuit of an analyzer. Zero disables the circuit breaker.
+	Threshold int
+	// ProbeInterval is the time an open circuit waits before letting one
+	// request through, to check if the analyzer is available again.
+	ProbeInterval time.Duration
+}
+
+// BreakerState is the state of the circuit breaker of an analyzer
+type BreakerState int
+
+const (
+	// BreakerClosed means the requests are sent to the analyzer
+	BreakerClosed BreakerState = iota
+	// BreakerOpen means the analyzer is skipped
+	BreakerOpen
+	// BreakerHalfOpen means one request is being sent to the analyzer to
+	// check if it is available again, and the rest are skipped
+	BreakerHalfOpen
+)
+
+func (st BreakerState) String() string {
+	names := [...]string{"closed", "open", "half-open"}
+	if st < BreakerClosed || st > BreakerHalfOpen {
+		return "unknown"
+	}
+
+	return names[st]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (st BreakerState) MarshalText() ([]byte, error) {
+	return []byte(st.String()), nil
+}
+
+// BreakerStatus is the status of the circuit breaker of an analyzer
+type BreakerStatus struct {
+	State BreakerState `json:"state"`
+	// Failures is the number of consecutive failed requests
+	Failures int `json:"failures"`
+}
+
+// isAnalyzerDown returns true if err means that the analyzer could not be
+// reached or did not reply in time. Other errors are returned by the analyzer
+// itself, so it is up.
+func isAnalyzerDown(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// breaker is the circuit breaker of one analyzer
+type breaker struct {
+	mutex    sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// allow returns true if a request can be sent to the analyzer. When it
+// returns true, done must be called with the result of the request.
+func (b *breaker) allow(opts BreakerOptions, now time.Time) bool {
+	if opts.Threshold <= 0 {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < opts.ProbeInterval {
+			return false
+		}
+
+		b.state = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// done records the result of a request allowed by allow. It returns the new
+// status, and whether the state changed.
+func (b *breaker) done(opts BreakerOptions, err error, now time.Time) (BreakerStatus, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	prev := b.state
+	switch {
+	case err == context.Canceled || status.Code(err) == codes.Canceled:
+		// the event processing was canceled, nothing is known about the
+		// analyzer
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerOpen
+		}
+	case !isAnalyzerDown(err):
+		b.state = BreakerClosed
+		b.failures = 0
+	default:
+		b.failures++
+		if opts.Threshold > 0 && (b.state == BreakerHalfOpen || b.failures >= opts.Threshold) {
+			b.state = BreakerOpen
+			b.openedAt = now
+		}
+	}
+
+	return BreakerStatus{State: b.state, Failures: b.failures}, b.state != prev
+}
+
+func (b *breaker) status() BreakerStatus {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return BreakerStatus{State: b.state, Failures: b.failures}
+}
+
+// breakerSet holds the circuit breakers by analyzer name. They are kept
+// across reloads, as long as the analyzer is still configured.
+type breakerSet struct {
+	mutex    sync.Mutex
+	opts     BreakerOptions
+	breakers map[string]*breaker
+}
+
+func newBreakerSet(opts BreakerOptions) *breakerSet {
+	return &breakerSet{
+		opts:     opts,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// get returns the breaker of the analyzer name, and the current options
+func (s *breakerSet) get(name string) (*breaker, BreakerOptions) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b, ok := s.breakers[name]
+	if !ok {
+		b = &breaker{}
+		s.breakers[name] = b
+	}
+
+	return b, s.opts
+}
+
+// reset replaces the options, and forgets the breakers of the analyzers that
+// are not in names
+func (s *breakerSet) reset(opts BreakerOptions, names map[string]bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.opts = opts
+	for name := range s.breakers {
+		if !names[name] {
+			delete(s.breakers, name)
+		}
+	}
+}
+
+// status returns the status of the breakers by analyzer name
+func (s *breakerSet) status() map[string]BreakerStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res := make(map[string]BreakerStatus, len(s.breakers))
+	for name, b := range s.breakers {
+		res[name] = b.status()
+	}
+
+	return res
+}
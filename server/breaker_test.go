@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	require := require.New(t)
+
+	opts := BreakerOptions{Threshold: 2, ProbeInterval: time.Minute}
+	unavailable := status.Error(codes.Unavailable, "down")
+	now := time.Now()
+
+	b := &breaker{}
+	require.True(b.allow(opts, now))
+	_, changed := b.done(opts, unavailable, now)
+	require.False(changed)
+
+	// an error returned by the analyzer resets the failures
+	require.True(b.allow(opts, now))
+	b.done(opts, status.Error(codes.InvalidArgument, "bad request"), now)
+	require.Equal(BreakerStatus{State: BreakerClosed}, b.status())
+
+	b.done(opts, unavailable, now)
+	st, changed := b.done(opts, status.Error(codes.DeadlineExceeded, "timeout"), now)
+	require.True(changed)
+	require.Equal(BreakerStatus{State: BreakerOpen, Failures: 2}, st)
+	require.False(b.allow(opts, now.Add(time.Second)))
+
+	// only one probe is allowed at a time
+	now = now.Add(time.Minute)
+	require.True(b.allow(opts, now))
+	require.Equal(BreakerHalfOpen, b.status().State)
+	require.False(b.allow(opts, now))
+
+	// a canceled probe does not tell anything about the analyzer
+	_, changed = b.done(opts, context.Canceled, now)
+	require.True(changed)
+	require.Equal(BreakerStatus{State: BreakerOpen, Failures: 2}, b.status())
+
+	require.True(b.allow(opts, now))
+	st, changed = b.done(opts, nil, now)
+	require.True(changed)
+	require.Equal(BreakerStatus{State: BreakerClosed}, st)
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	require := require.New(t)
+
+	opts := BreakerOptions{}
+	now := time.Now()
+
+	b := &breaker{}
+	for i := 0; i < 10; i++ {
+		require.True(b.allow(opts, now))
+		b.done(opts, status.Error(codes.Unavailable, "down"), now)
+	}
+
+	require.Equal(BreakerStatus{State: BreakerClosed, Failures: 10}, b.status())
+}
+
+func TestBreakerStateMarshalText(t *testing.T) {
+	require := require.New(t)
+
+	b, err := BreakerHalfOpen.MarshalText()
+	require.NoError(err)
+	require.Equal("half-open", string(b))
+	require.Equal("unknown", BreakerState(10).String())
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/meyskens/lookout"
+)
+
+// ResultCacheOptions configures the cache of the analyzer results
+type ResultCacheOptions struct {
+	// TTL is the time an analyzer result is reused. Zero disables the cache.
+	TTL time.Duration
+	// MaxEntries is the maximum number of analyzer results kept. Zero means
+	// no limit.
+	MaxEntries int
+}
+
+// resultKey identifies the result of an analyzer for an event
+type resultKey struct {
+	analyzer string
+	// version is the analyzer version that produced the result
+	version  string
+	event    string
+	base     string
+	head     string
+	settings string
+}
+
+type resultEntry struct {
+	comments []*lookout.Comment
+	expires  time.Time
+}
+
+// resultCache keeps the comments returned by the analyzers for a revision and
+// settings, so they can be reused when the same revision is analyzed again.
+// The results are only reused while the analyzer keeps reporting the same
+// version; when a new version is reported, all the results of the previous
+// one are dropped. The version is only known from the responses, so an
+// analyzer upgraded in place, at the same address, is detected on the next
+// miss, or after a reload, which forgets the versions reported so far.
+type resultCache struct {
+	mutex   sync.Mutex
+	opts    ResultCacheOptions
+	entries map[resultKey]resultEntry
+	// versions holds the last version reported by each analyzer
+	versions map[string]string
+}
+
+func newResultCache(opts ResultCacheOptions) *resultCache {
+	return &resultCache{
+		opts:     opts,
+		entries:  make(map[resultKey]resultEntry),
+		versions: make(map[string]string),
+	}
+}
+
+// key returns the cache key for the result of analyzer for e with settings.
+// The version is filled by get and set.
+func (c *resultCache) key(
+	analyzer string,
+	e lookout.Event,
+	settings map[string]interface{},
+) (resultKey, error) {
+	// encoding/json sorts the map keys, so equal settings have the same hash
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return resultKey{}, err
+	}
+
+	sum := sha1.Sum(b)
+	rev := e.Revision()
+	return resultKey{
+		analyzer: analyzer,
+		event:    reflect.TypeOf(e).String(),
+		base:     rev.Base.InternalRepositoryURL + "@" + rev.Base.Hash,
+		head:     rev.Head.InternalRepositoryURL + "@" + rev.Head.Hash,
+		settings: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// get returns a copy of the cached comments for key, using the last version
+// reported by the analyzer
+func (c *resultCache) get(key resultKey, now time.Time) ([]*lookout.Comment, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.opts.TTL <= 0 {
+		return nil, false
+	}
+
+	version, ok := c.versions[key.analyzer]
+	if !ok {
+		return nil, false
+	}
+
+	key.version = version
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	// the posting process must not modify the cached comments
+	comments := make([]*lookout.Comment, len(entry.comments))
+	for i, comment := range entry.comments {
+		cp := *comment
+		comments[i] = &cp
+	}
+
+	return comments, true
+}
+
+// set stores the comments returned by the analyzer with the given version
+func (c *resultCache) set(key resultKey, version string, comments []*lookout.Comment, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.opts.TTL <= 0 {
+		return
+	}
+
+	if prev, ok := c.versions[key.analyzer]; ok && prev != version {
+		c.dropAnalyzer(key.analyzer)
+	}
+	c.versions[key.analyzer] = version
+
+	if c.opts.MaxEntries > 0 && len(c.entries) >= c.opts.MaxEntries {
+		c.evict(now)
+	}
+
+	stored := make([]*lookout.Comment, len(comments))
+	for i, comment := range comments {
+		cp := *comment
+		stored[i] = &cp
+	}
+
+	key.version = version
+	c.entries[key] = resultEntry{
+		comments: stored,
+		expires:  now.Add(c.opts.TTL),
+	}
+}
+
+// evict removes the expired entries, or the one closest to expire if none is
+func (c *resultCache) evict(now time.Time) {
+	var oldest resultKey
+	var oldestExpires time.Time
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+
+		if oldestExpires.IsZero() || entry.expires.Before(oldestExpires) {
+			oldest = key
+			oldestExpires = entry.expires
+		}
+	}
+
+	if len(c.entries) >= c.opts.MaxEntries {
+		delete(c.entries, oldest)
+	}
+}
+
+// dropAnalyzer removes all the results of analyzer. It must be called with
+// the mutex locked.
+func (c *resultCache) dropAnalyzer(analyzer string) {
+	for key := range c.entries {
+		if key.analyzer == analyzer {
+			delete(c.entries, key)
+		}
+	}
+
+	delete(c.versions, analyzer)
+}
+
+// reset replaces the options, and drops the results of the analyzers that
+// were removed or whose address changed. The versions of the rest are
+// forgotten, the results are not reused until each analyzer reports its
+// version again, and only if it is the same one.
+func (c *resultCache) reset(opts ResultCacheOptions, prev, analyzers map[string]lookout.Analyzer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.opts = opts
+	if opts.TTL <= 0 {
+		c.entries = make(map[resultKey]resultEntry)
+		c.versions = make(map[string]string)
+		return
+	}
+
+	for name, a := range prev {
+		if newA, ok := analyzers[name]; !ok || newA.Config.Addr != a.Config.Addr {
+			c.dropAnalyzer(name)
+			continue
+		}
+
+		delete(c.versions, name)
+	}
+}
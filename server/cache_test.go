@@ -0,0 +1,138 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/meyskens/lookout"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultCache(t *testing.T) {
+	require := require.New(t)
+
+	c := newResultCache(ResultCacheOptions{TTL: time.Minute})
+	e := correctReviewEvent()
+	now := time.Now()
+
+	key, err := c.key("a", e, map[string]interface{}{"x": 1, "y": "z"})
+	require.NoError(err)
+	sameKey, err := c.key("a", e, map[string]interface{}{"y": "z", "x": 1})
+	require.NoError(err)
+	require.Equal(key, sameKey)
+
+	otherKey, err := c.key("a", e, map[string]interface{}{"x": 2, "y": "z"})
+	require.NoError(err)
+	require.NotEqual(key, otherKey)
+
+	_, ok := c.get(key, now)
+	require.False(ok)
+
+	comment := &lookout.Comment{Text: "foo"}
+	c.set(key, "v1", []*lookout.Comment{comment}, now)
+
+	cs, ok := c.get(key, now.Add(time.Second))
+	require.True(ok)
+	require.Equal([]*lookout.Comment{comment}, cs)
+
+	// the cached comments are copies
+	cs[0].Text = "modified"
+	cs, ok = c.get(key, now.Add(time.Second))
+	require.True(ok)
+	require.Equal("foo", cs[0].Text)
+
+	_, ok = c.get(otherKey, now)
+	require.False(ok)
+
+	// expired
+	_, ok = c.get(key, now.Add(time.Minute))
+	require.False(ok)
+	require.Empty(c.entries)
+
+	// a new version drops the results of the previous one
+	c.set(key, "v1", nil, now)
+	c.set(otherKey, "v2", nil, now)
+	_, ok = c.get(key, now)
+	require.False(ok)
+	_, ok = c.get(otherKey, now)
+	require.True(ok)
+}
+
+func TestResultCacheMaxEntries(t *testing.T) {
+	require := require.New(t)
+
+	c := newResultCache(ResultCacheOptions{TTL: time.Minute, MaxEntries: 2})
+	e := correctReviewEvent()
+	now := time.Now()
+
+	var keys []resultKey
+	for i := 0; i < 3; i++ {
+		key, err := c.key("a", e, map[string]interface{}{"i": i})
+		require.NoError(err)
+		keys = append(keys, key)
+
+		c.set(key, "v1", nil, now.Add(time.Duration(i)*time.Second))
+	}
+
+	require.Len(c.entries, 2)
+	_, ok := c.get(keys[0], now)
+	require.False(ok)
+	_, ok = c.get(keys[2], now)
+	require.True(ok)
+}
+
+func TestResultCacheDisabled(t *testing.T) {
+	require := require.New(t)
+
+	c := newResultCache(ResultCacheOptions{})
+	key, err := c.key("a", correctReviewEvent(), nil)
+	require.NoError(err)
+
+	c.set(key, "v1", nil, time.Now())
+	_, ok := c.get(key, time.Now())
+	require.False(ok)
+}
+
+func TestResultCacheReset(t *testing.T) {
+	require := require.New(t)
+
+	c := newResultCache(ResultCacheOptions{TTL: time.Minute})
+	e := correctReviewEvent()
+	now := time.Now()
+
+	keyA, err := c.key("a", e, nil)
+	require.NoError(err)
+	keyB, err := c.key("b", e, nil)
+	require.NoError(err)
+
+	c.set(keyA, "v1", nil, now)
+	c.set(keyB, "v1", nil, now)
+
+	analyzer := func(addr string) lookout.Analyzer {
+		return lookout.Analyzer{Config: lookout.AnalyzerConfig{Addr: addr}}
+	}
+
+	prev := map[string]lookout.Analyzer{"a": analyzer("a:1"), "b": analyzer("b:1")}
+	c.reset(c.opts, prev, map[string]lookout.Analyzer{
+		"a": analyzer("a:1"),
+		"b": analyzer("b:2"),
+	})
+
+	// the versions are forgotten until the analyzers report them again
+	_, ok := c.get(keyA, now)
+	require.False(ok)
+	_, ok = c.get(keyB, now)
+	require.False(ok)
+
+	otherKeyA, err := c.key("a", e, map[string]interface{}{"x": 1})
+	require.NoError(err)
+
+	// the same version reuses the results of the analyzer with the same address
+	c.set(otherKeyA, "v1", nil, now)
+	_, ok = c.get(keyA, now)
+	require.True(ok)
+
+	keyB.version = "v1"
+	require.NotContains(c.entries, keyB)
+}
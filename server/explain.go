@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/meyskens/lookout"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Names of the configuration layers, used by ExplainConfig to annotate each
+// value with the layer it comes from
+const (
+	// LayerGlobal is the server config.yml file
+	LayerGlobal = "config.yml"
+	// LayerOrganization is the organization configuration stored in the DB
+	LayerOrganization = "organization"
+	// LayerRepository is the repository configuration stored in the DB
+	LayerRepository = "repository"
+	// LayerLookoutYml is the .lookout.yml file of the repository
+	LayerLookoutYml = ".lookout.yml"
+)
+
+// ExplainedValue is a configuration value annotated with the layer it
+// comes from
+type ExplainedValue struct {
+	Value  interface{}
+	Source string
+}
+
+// ExplainedConfig is the effective configuration of an analyzer, with each
+// value annotated with the layer it comes from
+type ExplainedConfig struct {
+	Name     string
+	Disabled ExplainedValue
+	// Settings values are either an ExplainedValue or a nested
+	// map[string]interface{} with the same structure
+	Settings map[string]interface{}
+}
+
+// ExplainConfig returns the same configuration as EffectiveConfig, sorted by
+// analyzer name, but with every value annotated with the layer it comes from.
+// analyzers is the global configuration from config.yml, orgConfig the
+// organization config, repoConfig the repository config stored in the DB and
+// lookoutYml the content of the .lookout.yml file. Empty repoConfig and
+// lookoutYml are ignored.
+func ExplainConfig(
+	ctx context.Context,
+	analyzers []lookout.AnalyzerConfig,
+	orgConfig string,
+	repoConfig string,
+	lookoutYml string,
+) ([]ExplainedConfig, error) {
+	// Every leaf value is wrapped in an ExplainedValue before merging. They are
+	// not maps, so mergeMaps handles them exactly as the raw values, and the
+	// wrapper that survives the merge tells the layer the value comes from.
+	global := make(map[string]lookout.AnalyzerConfig, len(analyzers))
+	for _, aConf := range analyzers {
+		if aConf.Disabled {
+			continue
+		}
+
+		aConf.Settings = explainSettings(aConf.Settings, LayerGlobal)
+		global[aConf.Name] = aConf
+	}
+
+	parseLayer := func(content, source string) (map[string]lookout.AnalyzerConfig, error) {
+		conf, err := parseConfig(ctx, global, []byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+
+		for name, aConf := range conf {
+			aConf.Settings = explainSettings(aConf.Settings, source)
+			conf[name] = aConf
+		}
+
+		return conf, nil
+	}
+
+	orgConf, err := parseLayer(orgConfig, LayerOrganization)
+	if err != nil {
+		return nil, err
+	}
+
+	// mergeConfigs only merges the settings, the rest of the values come
+	// from the organization layer
+	conf := orgConf
+	for _, layer := range []struct{ content, source string }{
+		{repoConfig, LayerRepository},
+		{lookoutYml, LayerLookoutYml},
+	} {
+		if layer.content == "" {
+			continue
+		}
+
+		layerConf, err := parseLayer(layer.content, layer.source)
+		if err != nil {
+			return nil, err
+		}
+
+		conf = mergeConfigs(conf, layerConf)
+	}
+
+	var res []ExplainedConfig
+	for name, aConf := range effectiveConfig(global, conf) {
+		disabled := ExplainedValue{Value: false, Source: LayerGlobal}
+		if aConf.Disabled {
+			disabled = ExplainedValue{Value: true, Source: LayerOrganization}
+		}
+
+		res = append(res, ExplainedConfig{
+			Name:     name,
+			Disabled: disabled,
+			Settings: aConf.Settings,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+// explainSettings returns a copy of settings with every value that is not
+// already an ExplainedValue, or a map that mergeMaps merges recursively,
+// wrapped in an ExplainedValue from source
+func explainSettings(settings map[string]interface{}, source string) map[string]interface{} {
+	if settings == nil {
+		return nil
+	}
+
+	res := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		switch v := v.(type) {
+		case ExplainedValue:
+			res[k] = v
+		case map[string]interface{}:
+			res[k] = explainSettings(v, source)
+		default:
+			res[k] = ExplainedValue{Value: v, Source: source}
+		}
+	}
+
+	return res
+}
+
+// WriteExplainedConfig writes confs in YAML, with a comment next to each value
+// telling the layer it comes from
+func WriteExplainedConfig(w io.Writer, confs []ExplainedConfig) error {
+	ew := &errWriter{w: w}
+	ew.printf("analyzers:\n")
+	for _, conf := range confs {
+		ew.printf("  - name: %s\n", conf.Name)
+		writeExplainedValue(ew, "    ", "disabled", conf.Disabled)
+		if len(conf.Settings) == 0 {
+			continue
+		}
+
+		ew.printf("    settings:\n")
+		writeExplainedSettings(ew, "      ", conf.Settings)
+	}
+
+	return ew.err
+}
+
+func writeExplainedSettings(ew *errWriter, indent string, settings map[string]interface{}) {
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := settings[k].(type) {
+		case ExplainedValue:
+			writeExplainedValue(ew, indent, k, v)
+		case map[string]interface{}:
+			ew.printf("%s%s:\n", indent, k)
+			writeExplainedSettings(ew, indent+"  ", v)
+		}
+	}
+}
+
+func writeExplainedValue(ew *errWriter, indent, key string, v ExplainedValue) {
+	b, err := yaml.Marshal(v.Value)
+	if err != nil {
+		ew.err = err
+		return
+	}
+
+	value := strings.TrimSuffix(string(b), "\n")
+	if !strings.Contains(value, "\n") {
+		ew.printf("%s%s: %s  # %s\n", indent, key, value, v.Source)
+		return
+	}
+
+	ew.printf("%s%s:  # %s\n", indent, key, v.Source)
+	for _, line := range strings.Split(value, "\n") {
+		ew.printf("%s  %s\n", indent, line)
+	}
+}
+
+// errWriter keeps the first error of a sequence of writes
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, a ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+
+	_, ew.err = fmt.Fprintf(ew.w, format, a...)
+}
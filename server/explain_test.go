@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/meyskens/lookout"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainConfig(t *testing.T) {
+	require := require.New(t)
+
+	analyzers := []lookout.AnalyzerConfig{
+		{
+			Name: "mock",
+			Addr: "ipv4://localhost:9930",
+			Settings: map[string]interface{}{
+				"global_key": "global",
+				"reused_key": "global",
+				"nested": map[string]interface{}{
+					"global_key": 1,
+				},
+			},
+		},
+		{
+			Name: "other",
+			Addr: "ipv4://localhost:9931",
+		},
+		{
+			Name:     "disabled",
+			Disabled: true,
+		},
+	}
+
+	orgConfig := `
+analyzers:
+  - name: mock
+    settings:
+      org_key:    org
+      reused_key: org
+  - name: other
+    disabled: true
+`
+	repoConfig := `
+analyzers:
+  - name: mock
+    settings:
+      repo_key:   repo
+      reused_key: repo
+      list:       [a, b]
+`
+	lookoutYml := `
+analyzers:
+  - name: mock
+    settings:
+      local_key: local
+`
+
+	confs, err := ExplainConfig(context.TODO(), analyzers, orgConfig, repoConfig, lookoutYml)
+	require.NoError(err)
+	require.Equal([]ExplainedConfig{
+		{
+			Name:     "mock",
+			Disabled: ExplainedValue{false, LayerGlobal},
+			Settings: map[string]interface{}{
+				"global_key": ExplainedValue{"global", LayerGlobal},
+				"nested": map[string]interface{}{
+					"global_key": ExplainedValue{1, LayerGlobal},
+				},
+				"org_key":    ExplainedValue{"org", LayerOrganization},
+				"repo_key":   ExplainedValue{"repo", LayerRepository},
+				"reused_key": ExplainedValue{"repo", LayerRepository},
+				"list":       ExplainedValue{[]interface{}{"a", "b"}, LayerRepository},
+				"local_key":  ExplainedValue{"local", LayerLookoutYml},
+			},
+		},
+		{
+			Name:     "other",
+			Disabled: ExplainedValue{true, LayerOrganization},
+			Settings: map[string]interface{}{},
+		},
+	}, confs)
+
+	var buf bytes.Buffer
+	require.NoError(WriteExplainedConfig(&buf, confs))
+	require.Equal(`analyzers:
+  - name: mock
+    disabled: false  # config.yml
+    settings:
+      global_key: global  # config.yml
+      list:  # repository
+        - a
+        - b
+      local_key: local  # .lookout.yml
+      nested:
+        global_key: 1  # config.yml
+      org_key: org  # organization
+      repo_key: repo  # repository
+      reused_key: repo  # repository
+  - name: other
+    disabled: true  # organization
+`, buf.String())
+
+	_, err = ExplainConfig(context.TODO(), analyzers, "", "not: [valid", "")
+	require.Error(err)
+}
+
+func TestExplainConfigMatchesEffectiveConfig(t *testing.T) {
+	require := require.New(t)
+
+	analyzers := []lookout.AnalyzerConfig{
+		{
+			Name: "mock",
+			Settings: map[string]interface{}{
+				"global_key": "global",
+				"reused_key": "global",
+			},
+		},
+	}
+
+	// the .lookout.yml does not configure the analyzer, so the global
+	// settings are applied again on top of the organization ones
+	orgConfig := `
+analyzers:
+  - name: mock
+    settings:
+      reused_key: org
+`
+	lookoutYml := `
+analyzers: []
+`
+
+	effective, err := EffectiveConfig(context.TODO(), analyzers, orgConfig, "", lookoutYml)
+	require.NoError(err)
+
+	confs, err := ExplainConfig(context.TODO(), analyzers, orgConfig, "", lookoutYml)
+	require.NoError(err)
+	require.Len(confs, 1)
+	require.Equal(effective["mock"].Settings["reused_key"], "global")
+	require.Equal(ExplainedValue{"global", LayerGlobal}, confs[0].Settings["reused_key"])
+}
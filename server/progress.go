@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meyskens/lookout"
+	"github.com/meyskens/lookout/util/ctxlog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// progressStatusInterval is the minimum time between two progress statuses
+// posted for the same event
+const progressStatusInterval = 10 * time.Second
+
+// errMissedHeartbeats is returned for the analyzers that stopped reporting
+// their progress. It counts as a timeout for the circuit breaker.
+var errMissedHeartbeats = status.Error(codes.DeadlineExceeded, "the analyzer stopped sending heartbeats")
+
+// analyzerProgress is the last progress reported by an analyzer for an event
+type analyzerProgress struct {
+	percent     float32
+	description string
+
+	// timer cancels the request when the heartbeats stop, it is only set
+	// after the first report
+	timeout   time.Duration
+	timer     *time.Timer
+	heartbeat time.Time
+	missed    bool
+	cancel    context.CancelFunc
+	// done is set once the analyzer replied
+	done bool
+}
+
+// String returns the description shown in the event status, or "" if the
+// analyzer only reported heartbeats
+func (p *analyzerProgress) String() string {
+	switch {
+	case p.percent > 0 && p.description != "":
+		return fmt.Sprintf("%.0f%% %s", p.percent, p.description)
+	case p.percent > 0:
+		return fmt.Sprintf("%.0f%%", p.percent)
+	default:
+		return p.description
+	}
+}
+
+// eventProgress holds the progress of the analyzers processing an event
+type eventProgress struct {
+	ctx       context.Context
+	e         lookout.Event
+	analyzers map[string]*analyzerProgress
+
+	lastPosted time.Time
+	// postMutex is held while posting a progress status, so the final status
+	// is never overwritten by a progress status still in flight
+	postMutex sync.Mutex
+	finished  bool
+}
+
+// progressTracker keeps the progress reported by the analyzers for the events
+// being processed, and cancels the requests of the analyzers whose heartbeats
+// stop
+type progressTracker struct {
+	mutex  sync.Mutex
+	events map[string]*eventProgress
+	// minInterval is the minimum time between two progress statuses posted
+	// for the same event
+	minInterval time.Duration
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{
+		events:      make(map[string]*eventProgress),
+		minInterval: progressStatusInterval,
+	}
+}
+
+// start begins tracking the progress of e. The returned function must be
+// called once the analyzers replied, before posting the final status.
+func (t *progressTracker) start(ctx context.Context, e lookout.Event) func() {
+	id := e.ID().String()
+	ep := &eventProgress{
+		ctx:       ctx,
+		e:         e,
+		analyzers: make(map[string]*analyzerProgress),
+	}
+
+	t.mutex.Lock()
+	t.events[id] = ep
+	t.mutex.Unlock()
+
+	return func() {
+		t.mutex.Lock()
+		if t.events[id] == ep {
+			delete(t.events, id)
+		}
+		for _, p := range ep.analyzers {
+			if p.timer != nil {
+				p.timer.Stop()
+			}
+		}
+		t.mutex.Unlock()
+
+		ep.postMutex.Lock()
+		ep.finished = true
+		ep.postMutex.Unlock()
+	}
+}
+
+// watch registers the request to the analyzer name for e. The returned
+// context is canceled if the analyzer stops sending heartbeats for longer than
+// timeout, once it has sent the first one. The returned function must be
+// called when the request finishes, it returns errMissedHeartbeats if the
+// request was canceled because of that, or err otherwise.
+func (t *progressTracker) watch(
+	ctx context.Context,
+	e lookout.Event,
+	name string,
+	timeout time.Duration,
+) (context.Context, func(err error) error) {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &analyzerProgress{timeout: timeout, cancel: cancel}
+
+	t.mutex.Lock()
+	if ep, ok := t.events[e.ID().String()]; ok {
+		ep.analyzers[name] = p
+	}
+	t.mutex.Unlock()
+
+	return ctx, func(err error) error {
+		defer cancel()
+
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		p.done = true
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+
+		if p.missed {
+			return errMissedHeartbeats
+		}
+
+		return err
+	}
+}
+
+// report records the progress r, and returns the event it belongs to
+func (t *progressTracker) report(r *lookout.ProgressReport, now time.Time) (*eventProgress, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ep, ok := t.events[r.EventID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "event %s is not being analyzed", r.EventID)
+	}
+
+	name := r.Analyzer
+	if name == "" && len(ep.analyzers) == 1 {
+		for n := range ep.analyzers {
+			name = n
+		}
+	}
+
+	p, ok := ep.analyzers[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "analyzer %q is not analyzing event %s", name, r.EventID)
+	}
+
+	if p.missed {
+		return nil, status.Error(codes.DeadlineExceeded, "the analysis was canceled because of missed heartbeats")
+	}
+
+	if p.done {
+		return nil, status.Errorf(codes.FailedPrecondition, "analyzer %q already replied to event %s", name, r.EventID)
+	}
+
+	p.heartbeat = now
+	if p.timeout > 0 {
+		if p.timer == nil {
+			p.timer = time.AfterFunc(p.timeout, func() { t.expire(p) })
+		} else {
+			p.timer.Reset(p.timeout)
+		}
+	}
+
+	if r.Percent > 0 || r.Description != "" {
+		p.percent = r.Percent
+		p.description = r.Description
+	}
+
+	return ep, nil
+}
+
+// expire cancels the request of p, unless a heartbeat arrived while the timer
+// was firing
+func (t *progressTracker) expire(p *analyzerProgress) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if time.Since(p.heartbeat) < p.timeout {
+		return
+	}
+
+	p.missed = true
+	p.cancel()
+}
+
+// description returns the progress of all the analyzers of ep, sorted by
+// analyzer name
+func (t *progressTracker) description(ep *eventProgress) string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	names := make([]string, 0, len(ep.analyzers))
+	for name := range ep.analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		if desc := ep.analyzers[name].String(); desc != "" {
+			parts = append(parts, fmt.Sprintf("analyzer %s: %s", name, desc))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// ReportProgress implements lookout.ProgressReporter. The progress is posted
+// as the description of the pending status, if the poster supports it, and
+// the report counts as a heartbeat of the analyzer.
+func (s *Server) ReportProgress(ctx context.Context, r *lookout.ProgressReport) error {
+	ep, err := s.progress.report(r, time.Now())
+	if err != nil {
+		return err
+	}
+
+	p, ok := s.poster.(lookout.ProgressPoster)
+	if !ok {
+		return nil
+	}
+
+	desc := s.progress.description(ep)
+	if desc == "" {
+		return nil
+	}
+
+	ep.postMutex.Lock()
+	defer ep.postMutex.Unlock()
+
+	now := time.Now()
+	if ep.finished || now.Sub(ep.lastPosted) < s.progress.minInterval {
+		return nil
+	}
+	ep.lastPosted = now
+
+	if err := p.StatusProgress(ep.ctx, ep.e, desc); err != nil {
+		ctxlog.Get(ep.ctx).With(log.Fields{"progress": desc}).Errorf(err, "posting progress status failed")
+	}
+
+	return nil
+}
+
+var _ lookout.ProgressReporter = &Server{}
@@ -3,7 +3,11 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/meyskens/lookout"
@@ -11,6 +15,7 @@ import (
 	"github.com/meyskens/lookout/store/models"
 	"github.com/meyskens/lookout/util/ctxlog"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
 
@@ -27,6 +32,8 @@ var grpcErrorMessages = map[lookout.EventType]map[codes.Code]string{
 	},
 }
 
+const heartbeatErrorMessage = "the analyzer stopped reporting its progress, try increasing analyzer_heartbeat in config.yml"
+
 // Config is a server configuration
 type Config struct {
 	Analyzers []lookout.AnalyzerConfig
@@ -36,23 +43,107 @@ type reqSent func(
 	ctx context.Context,
 	client lookout.AnalyzerClient,
 	settings map[string]interface{},
-) ([]*lookout.Comment, error)
+) (*lookout.EventResponse, error)
 
 // Server implements glue between providers / data-server / analyzers
 type Server struct {
 	poster         lookout.Poster
 	fileGetter     lookout.FileGetter
-	analyzers      map[string]lookout.Analyzer
 	eventOp        store.EventOperator
 	commentOp      store.CommentOperator
 	organizationOp store.OrganizationOperator
+	repositoryOp   store.RepositoryOperator
+
+	// analyzers is replaced on Reload, each event uses the one that was
+	// current when its processing started
+	analyzers      *analyzerSet
+	analyzersMutex sync.RWMutex
 
-	analyzerReviewTimeout time.Duration
-	analyzerPushTimeout   time.Duration
+	// breakers holds the circuit breakers of the analyzers
+	breakers *breakerSet
+	// results caches the analyzer results
+	results *resultCache
+	// progress holds the progress reported by the analyzers
+	progress *progressTracker
 
 	exitOnError bool
 }
 
+// analyzerSet holds the analyzers and their timeouts
+type analyzerSet struct {
+	analyzers     map[string]lookout.Analyzer
+	reviewTimeout time.Duration
+	pushTimeout   time.Duration
+	// heartbeatTimeout is the time an analyzer that reported its progress
+	// can go without reporting it again
+	heartbeatTimeout time.Duration
+
+	// active counts the events being processed with this set
+	active sync.WaitGroup
+
+	// unaryOnly holds the clients of the analyzers that do not implement the
+	// streaming RPCs
+	unaryOnly sync.Map
+}
+
+// config returns the global configuration of the analyzers
+func (a *analyzerSet) config() map[string]lookout.AnalyzerConfig {
+	res := make(map[string]lookout.AnalyzerConfig, len(a.analyzers))
+	for name, an := range a.analyzers {
+		res[name] = an.Config
+	}
+
+	return res
+}
+
+// release must be called once the event that acquired the set is processed
+func (a *analyzerSet) release() {
+	a.active.Done()
+}
+
+// notify calls the streaming RPC with stream if client implements
+// lookout.AnalyzerStreamClient, or the unary RPC with unary otherwise. The
+// analyzers that reply Unimplemented to the streaming RPC only receive unary
+// calls afterwards. If the stream fails, the comments received before the
+// error are returned along with it.
+func (a *analyzerSet) notify(
+	client lookout.AnalyzerClient,
+	stream func(lookout.AnalyzerStreamClient) (lookout.EventResponseStream, error),
+	unary func() (*lookout.EventResponse, error),
+) (*lookout.EventResponse, error) {
+	sc, ok := client.(lookout.AnalyzerStreamClient)
+	if _, unaryOnly := a.unaryOnly.Load(client); !ok || unaryOnly {
+		return unary()
+	}
+
+	resp := &lookout.EventResponse{}
+	s, err := stream(sc)
+	for err == nil {
+		var r *lookout.EventResponse
+		r, err = s.Recv()
+		if err != nil {
+			break
+		}
+
+		if r.AnalyzerVersion != "" {
+			resp.AnalyzerVersion = r.AnalyzerVersion
+		}
+
+		resp.Comments = append(resp.Comments, r.Comments...)
+	}
+
+	if err == io.EOF {
+		return resp, nil
+	}
+
+	if status.Code(err) == codes.Unimplemented && len(resp.Comments) == 0 {
+		a.unaryOnly.Store(client, true)
+		return unary()
+	}
+
+	return resp, err
+}
+
 // Options defines the options for NewServer
 type Options struct {
 	Poster     lookout.Poster
@@ -65,6 +156,8 @@ type Options struct {
 	CommentOp store.CommentOperator
 	// OrganizationOp is the operator for the Organization persistence. Can be left unset.
 	OrganizationOp store.OrganizationOperator
+	// RepositoryOp is the operator for the Repository persistence. Can be left unset.
+	RepositoryOp store.RepositoryOperator
 
 	// ReviewTimeout is the timeout for an analyzer to reply a NotifyReviewEvent.
 	// Zero means no timeout.
@@ -72,6 +165,17 @@ type Options struct {
 	// PushTimeout is the timeout for an analyzer to reply a NotifyPushEvent.
 	// Zero means no timeout.
 	PushTimeout time.Duration
+	// HeartbeatTimeout is the time an analyzer that reported the progress of
+	// an event can go without reporting it again before its request is
+	// canceled. Zero means no timeout.
+	HeartbeatTimeout time.Duration
+
+	// Breaker configures the circuit breaker that skips the analyzers that
+	// are down. The zero value disables it.
+	Breaker BreakerOptions
+	// ResultCache configures the cache that reuses the analyzer results when
+	// the same revision is analyzed again. The zero value disables it.
+	ResultCache ResultCacheOptions
 
 	// ExitOnError set to true will stop the server and return an error
 	// if any analyzer Notify* call or a posting call fails
@@ -81,15 +185,22 @@ type Options struct {
 // NewServer creates a new Server with the given options
 func NewServer(opt Options) *Server {
 	server := Server{
-		poster:                opt.Poster,
-		fileGetter:            opt.FileGetter,
-		analyzers:             opt.Analyzers,
-		eventOp:               opt.EventOp,
-		commentOp:             opt.CommentOp,
-		organizationOp:        opt.OrganizationOp,
-		analyzerReviewTimeout: opt.ReviewTimeout,
-		analyzerPushTimeout:   opt.PushTimeout,
-		exitOnError:           opt.ExitOnError,
+		poster:         opt.Poster,
+		fileGetter:     opt.FileGetter,
+		eventOp:        opt.EventOp,
+		commentOp:      opt.CommentOp,
+		organizationOp: opt.OrganizationOp,
+		repositoryOp:   opt.RepositoryOp,
+		analyzers: &analyzerSet{
+			analyzers:        opt.Analyzers,
+			reviewTimeout:    opt.ReviewTimeout,
+			pushTimeout:      opt.PushTimeout,
+			heartbeatTimeout: opt.HeartbeatTimeout,
+		},
+		breakers:    newBreakerSet(opt.Breaker),
+		results:     newResultCache(opt.ResultCache),
+		progress:    newProgressTracker(),
+		exitOnError: opt.ExitOnError,
 	}
 
 	if opt.EventOp == nil {
@@ -104,9 +215,83 @@ func NewServer(opt Options) *Server {
 		server.organizationOp = &store.NoopOrganizationOperator{}
 	}
 
+	if opt.RepositoryOp == nil {
+		server.repositoryOp = &store.NoopRepositoryOperator{}
+	}
+
 	return &server
 }
 
+// ReloadOptions defines the options for Reload, they have the same meaning
+// as in Options
+type ReloadOptions struct {
+	Analyzers        map[string]lookout.Analyzer
+	ReviewTimeout    time.Duration
+	PushTimeout      time.Duration
+	HeartbeatTimeout time.Duration
+	Breaker          BreakerOptions
+	ResultCache      ResultCacheOptions
+}
+
+// Reload replaces the analyzers, the timeouts, the circuit breaker and the
+// result cache options used for new events. Events already being processed
+// keep using the previous analyzers and timeouts. The returned channel is
+// closed once all of them finish, after that it is safe to close the
+// connections of the analyzers that are not used anymore.
+func (s *Server) Reload(opt ReloadOptions) <-chan struct{} {
+	names := make(map[string]bool, len(opt.Analyzers))
+	for name := range opt.Analyzers {
+		names[name] = true
+	}
+
+	s.breakers.reset(opt.Breaker, names)
+
+	s.analyzersMutex.Lock()
+	prev := s.analyzers
+	s.analyzers = &analyzerSet{
+		analyzers:        opt.Analyzers,
+		reviewTimeout:    opt.ReviewTimeout,
+		pushTimeout:      opt.PushTimeout,
+		heartbeatTimeout: opt.HeartbeatTimeout,
+	}
+	s.analyzersMutex.Unlock()
+
+	s.results.reset(opt.ResultCache, prev.analyzers, opt.Analyzers)
+
+	done := make(chan struct{})
+	go func() {
+		prev.active.Wait()
+		close(done)
+	}()
+
+	return done
+}
+
+// acquireAnalyzers returns the current analyzers. The caller must call
+// release on the returned set once the event is processed
+func (s *Server) acquireAnalyzers() *analyzerSet {
+	s.analyzersMutex.RLock()
+	defer s.analyzersMutex.RUnlock()
+
+	s.analyzers.active.Add(1)
+	return s.analyzers
+}
+
+// BreakerStatus returns the status of the circuit breaker of each analyzer
+func (s *Server) BreakerStatus() map[string]BreakerStatus {
+	s.analyzersMutex.RLock()
+	analyzers := s.analyzers.analyzers
+	s.analyzersMutex.RUnlock()
+
+	breakers := s.breakers.status()
+	res := make(map[string]BreakerStatus, len(analyzers))
+	for name := range analyzers {
+		res[name] = breakers[name]
+	}
+
+	return res
+}
+
 // HandleEvent processes the event calling the analyzers, and posting the results
 func (s *Server) HandleEvent(ctx context.Context, e lookout.Event) error {
 	ctx, logger := ctxlog.WithLogFields(ctx, log.Fields{
@@ -176,53 +361,51 @@ func (s *Server) HandleReview(ctx context.Context, e *lookout.ReviewEvent, safeP
 		return err
 	}
 
-	repoConf, err := s.getConfig(ctx, e)
-	if err != nil {
-		return err
-	}
+	analyzers := s.acquireAnalyzers()
+	defer analyzers.release()
 
-	orgConf, err := s.getOrgConfig(ctx, e)
+	conf, err := s.getMergedConfig(ctx, analyzers, e)
 	if err != nil {
 		return err
 	}
 
-	conf := mergeConfigs(orgConf, repoConf)
-
 	s.status(ctx, e, lookout.PendingAnalysisStatus)
 
 	send := func(
 		ctx context.Context,
 		a lookout.AnalyzerClient,
 		settings map[string]interface{},
-	) ([]*lookout.Comment, error) {
+	) (*lookout.EventResponse, error) {
 		st := pb.ToStruct(settings)
 		if st != nil {
 			e.Configuration = *st
 		}
 
-		if s.analyzerReviewTimeout > 0 {
+		if analyzers.reviewTimeout > 0 {
 			var cancel context.CancelFunc
-			ctx, cancel = context.WithTimeout(ctx, s.analyzerReviewTimeout)
+			ctx, cancel = context.WithTimeout(ctx, analyzers.reviewTimeout)
 			defer cancel()
 		}
 
-		resp, err := a.NotifyReviewEvent(ctx, &e.ReviewEvent)
-		if err != nil {
-			return nil, err
-		}
-		return resp.Comments, nil
+		return analyzers.notify(a,
+			func(sc lookout.AnalyzerStreamClient) (lookout.EventResponseStream, error) {
+				return sc.NotifyReviewEventStream(ctx, &e.ReviewEvent)
+			},
+			func() (*lookout.EventResponse, error) {
+				return a.NotifyReviewEvent(ctx, &e.ReviewEvent)
+			})
 	}
-	comments, err := s.concurrentRequest(ctx, conf, send, grpcErrorMessages[pb.ReviewEventType])
+	comments, skipped, err := s.concurrentRequest(ctx, e, analyzers, conf, send, grpcErrorMessages[pb.ReviewEventType])
 	if err != nil {
 		return err
 	}
 
 	if err := s.post(ctx, e, comments, safePosting); err != nil {
-		s.status(ctx, e, lookout.ErrorAnalysisStatus)
+		s.statusSkipped(ctx, e, lookout.ErrorAnalysisStatus, skipped)
 		return fmt.Errorf("posting analysis failed: %s", err)
 	}
 
-	s.status(ctx, e, lookout.SuccessAnalysisStatus)
+	s.statusSkipped(ctx, e, lookout.SuccessAnalysisStatus, skipped)
 
 	return nil
 }
@@ -238,57 +421,85 @@ func (s *Server) HandlePush(ctx context.Context, e *lookout.PushEvent, safePosti
 		return err
 	}
 
-	repoConf, err := s.getConfig(ctx, e)
-	if err != nil {
-		return err
-	}
+	analyzers := s.acquireAnalyzers()
+	defer analyzers.release()
 
-	orgConf, err := s.getOrgConfig(ctx, e)
+	conf, err := s.getMergedConfig(ctx, analyzers, e)
 	if err != nil {
 		return err
 	}
 
-	conf := mergeConfigs(orgConf, repoConf)
-
 	s.status(ctx, e, lookout.PendingAnalysisStatus)
 
 	send := func(
 		ctx context.Context,
 		a lookout.AnalyzerClient,
 		settings map[string]interface{},
-	) ([]*lookout.Comment, error) {
+	) (*lookout.EventResponse, error) {
 		st := pb.ToStruct(settings)
 		if st != nil {
 			e.Configuration = *st
 		}
 
-		if s.analyzerPushTimeout > 0 {
+		if analyzers.pushTimeout > 0 {
 			var cancel context.CancelFunc
-			ctx, cancel = context.WithTimeout(ctx, s.analyzerPushTimeout)
+			ctx, cancel = context.WithTimeout(ctx, analyzers.pushTimeout)
 			defer cancel()
 		}
 
-		resp, err := a.NotifyPushEvent(ctx, &e.PushEvent)
-		if err != nil {
-			return nil, err
-		}
-		return resp.Comments, nil
+		return analyzers.notify(a,
+			func(sc lookout.AnalyzerStreamClient) (lookout.EventResponseStream, error) {
+				return sc.NotifyPushEventStream(ctx, &e.PushEvent)
+			},
+			func() (*lookout.EventResponse, error) {
+				return a.NotifyPushEvent(ctx, &e.PushEvent)
+			})
 	}
-	comments, err := s.concurrentRequest(ctx, conf, send, grpcErrorMessages[pb.PushEventType])
+	comments, skipped, err := s.concurrentRequest(ctx, e, analyzers, conf, send, grpcErrorMessages[pb.PushEventType])
 	if err != nil {
 		return err
 	}
 
 	if err := s.post(ctx, e, comments, safePosting); err != nil {
-		s.status(ctx, e, lookout.ErrorAnalysisStatus)
+		s.statusSkipped(ctx, e, lookout.ErrorAnalysisStatus, skipped)
 		return fmt.Errorf("posting analysis failed: %s", err)
 	}
-	s.status(ctx, e, lookout.SuccessAnalysisStatus)
+	s.statusSkipped(ctx, e, lookout.SuccessAnalysisStatus, skipped)
 
 	return nil
 }
 
-func (s *Server) getConfig(ctx context.Context, e lookout.Event) (map[string]lookout.AnalyzerConfig, error) {
+// getMergedConfig returns the configuration for the event, merging in order
+// the organization config, the repository config stored in the DB and the
+// repository .lookout.yml file
+func (s *Server) getMergedConfig(ctx context.Context, analyzers *analyzerSet, e lookout.Event) (map[string]lookout.AnalyzerConfig, error) {
+	global := analyzers.config()
+
+	repoConf, err := s.getConfig(ctx, global, e)
+	if err != nil {
+		return nil, err
+	}
+
+	repoDBConf, err := s.getRepositoryConfig(ctx, global, e)
+	if err != nil {
+		return nil, err
+	}
+
+	orgConf, err := s.getOrgConfig(ctx, global, e)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := mergeConfigs(mergeConfigs(orgConf, repoDBConf), repoConf)
+
+	ctxlog.Get(ctx).With(log.Fields{
+		"config": effectiveConfig(global, conf),
+	}).Debugf("effective configuration")
+
+	return conf, nil
+}
+
+func (s *Server) getConfig(ctx context.Context, global map[string]lookout.AnalyzerConfig, e lookout.Event) (map[string]lookout.AnalyzerConfig, error) {
 	rev := e.Revision()
 	ctxlog.Get(ctx).Debugf("getting .lookout.yml")
 	scanner, err := s.fileGetter.GetFiles(ctx, &lookout.FilesRequest{
@@ -314,7 +525,7 @@ func (s *Server) getConfig(ctx context.Context, e lookout.Event) (map[string]loo
 	}
 
 	parseCtx, _ := ctxlog.WithLogFields(ctx, log.Fields{"config-file": "repository .lookout.yml"})
-	conf, err := s.parseConfig(parseCtx, configContent)
+	conf, err := parseConfig(parseCtx, global, configContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the local .lookout.yml file from the repository: %s", err)
 	}
@@ -322,18 +533,18 @@ func (s *Server) getConfig(ctx context.Context, e lookout.Event) (map[string]loo
 	return conf, nil
 }
 
-func (s *Server) parseConfig(ctx context.Context, configContent []byte) (map[string]lookout.AnalyzerConfig, error) {
+func parseConfig(ctx context.Context, global map[string]lookout.AnalyzerConfig, configContent []byte) (map[string]lookout.AnalyzerConfig, error) {
 	var conf Config
 	if err := yaml.Unmarshal(configContent, &conf); err != nil {
 		return nil, fmt.Errorf("can't parse configuration file: %s", err)
 	}
 
-	res := make(map[string]lookout.AnalyzerConfig, len(s.analyzers))
-	for name, a := range s.analyzers {
-		res[name] = a.Config
+	res := make(map[string]lookout.AnalyzerConfig, len(global))
+	for name, aConf := range global {
+		res[name] = aConf
 	}
 	for _, aConf := range conf.Analyzers {
-		if _, ok := s.analyzers[aConf.Name]; !ok {
+		if _, ok := global[aConf.Name]; !ok {
 			ctxlog.Get(ctx).Warningf("analyzer '%s' required by configuration file isn't enabled on server", aConf.Name)
 			continue
 		}
@@ -343,14 +554,68 @@ func (s *Server) parseConfig(ctx context.Context, configContent []byte) (map[str
 	return res, nil
 }
 
-func (s *Server) getOrgConfig(ctx context.Context, e lookout.Event) (map[string]lookout.AnalyzerConfig, error) {
+// EffectiveConfig returns the configuration that each analyzer receives,
+// following the same merging rules used by the Server. analyzers is the
+// global configuration from config.yml, orgConfig the organization config,
+// and repoConfigs the repository configs applied on top of it in order, the
+// one stored in the DB and the .lookout.yml file. Empty repoConfigs are ignored.
+func EffectiveConfig(
+	ctx context.Context,
+	analyzers []lookout.AnalyzerConfig,
+	orgConfig string,
+	repoConfigs ...string,
+) (map[string]lookout.AnalyzerConfig, error) {
+	global := make(map[string]lookout.AnalyzerConfig, len(analyzers))
+	for _, aConf := range analyzers {
+		if aConf.Disabled {
+			continue
+		}
+
+		global[aConf.Name] = aConf
+	}
+
+	conf, err := parseConfig(ctx, global, []byte(orgConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repoConfig := range repoConfigs {
+		if repoConfig == "" {
+			continue
+		}
+
+		repoConf, err := parseConfig(ctx, global, []byte(repoConfig))
+		if err != nil {
+			return nil, err
+		}
+
+		conf = mergeConfigs(conf, repoConf)
+	}
+
+	return effectiveConfig(global, conf), nil
+}
+
+// effectiveConfig applies the merged configuration conf over the global
+// analyzers configuration, the same way concurrentRequest does
+func effectiveConfig(global, conf map[string]lookout.AnalyzerConfig) map[string]lookout.AnalyzerConfig {
+	res := make(map[string]lookout.AnalyzerConfig, len(global))
+	for name, aConf := range global {
+		aConf.Disabled = aConf.Disabled || conf[name].Disabled
+		aConf.Settings = mergeSettings(aConf.Settings, conf[name].Settings)
+		res[name] = aConf
+	}
+
+	return res
+}
+
+func (s *Server) getOrgConfig(ctx context.Context, global map[string]lookout.AnalyzerConfig, e lookout.Event) (map[string]lookout.AnalyzerConfig, error) {
 	configContent, err := s.organizationOp.Config(ctx, e.GetProvider(), e.GetOrganizationID())
 	if err != nil {
 		return nil, fmt.Errorf("could not load default configuration for organization from the DB: %s", err)
 	}
 
 	parseCtx, _ := ctxlog.WithLogFields(ctx, log.Fields{"config-file": "organization default"})
-	conf, err := s.parseConfig(parseCtx, []byte(configContent))
+	conf, err := parseConfig(parseCtx, global, []byte(configContent))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the organization default configuration from the DB: %s", err)
 	}
@@ -358,22 +623,71 @@ func (s *Server) getOrgConfig(ctx context.Context, e lookout.Event) (map[string]
 	return conf, nil
 }
 
-func (s *Server) concurrentRequest(ctx context.Context, conf map[string]lookout.AnalyzerConfig, send reqSent, logErrorMessages map[codes.Code]string) ([]lookout.AnalyzerComments, error) {
+func (s *Server) getRepositoryConfig(ctx context.Context, global map[string]lookout.AnalyzerConfig, e lookout.Event) (map[string]lookout.AnalyzerConfig, error) {
+	repoID := e.Revision().Head.InternalRepositoryURL
+	configContent, err := s.repositoryOp.Config(ctx, e.GetProvider(), repoID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load configuration for repository from the DB: %s", err)
+	}
+
+	if configContent == "" {
+		return nil, nil
+	}
+
+	parseCtx, _ := ctxlog.WithLogFields(ctx, log.Fields{"config-file": "repository DB"})
+	conf, err := parseConfig(parseCtx, global, []byte(configContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the repository configuration from the DB: %s", err)
+	}
+
+	return conf, nil
+}
+
+// concurrentRequest sends the request for e to all the enabled analyzers, and
+// returns their comments, and the names of the analyzers skipped because their
+// circuit breaker is open. The cached results are used instead of sending the
+// request when possible.
+func (s *Server) concurrentRequest(ctx context.Context, e lookout.Event, analyzers *analyzerSet, conf map[string]lookout.AnalyzerConfig, send reqSent, logErrorMessages map[codes.Code]string) ([]lookout.AnalyzerComments, []string, error) {
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
 
-	commentsCh := make(chan *lookout.AnalyzerComments, len(s.analyzers))
+	finishProgress := s.progress.start(ctx, e)
+	defer finishProgress()
+
+	commentsCh := make(chan *lookout.AnalyzerComments, len(analyzers.analyzers))
 	errCh := make(chan error)
 
-	for name, a := range s.analyzers {
+	var skipped []string
+	for name, a := range analyzers.analyzers {
 		if a.Config.Disabled || conf[name].Disabled {
 			ctxlog.Get(ctx).Infof("analyzer %s disabled by local repository configuration", name)
 			commentsCh <- nil
 			continue
 		}
 
-		go func(name string, a lookout.Analyzer) {
+		settings := mergeSettings(a.Config.Settings, conf[name].Settings)
+		key, err := s.results.key(name, e, settings)
+		if err != nil {
+			ctxlog.Get(ctx).Errorf(err, "can't build the result cache key for analyzer %s", name)
+		} else if cs, ok := s.results.get(key, time.Now()); ok {
+			ctxlog.Get(ctx).With(log.Fields{
+				"analyzer": name,
+				"comments": len(cs),
+			}).Infof("reusing cached analyzer result")
+			commentsCh <- analyzerComments(a, cs)
+			continue
+		}
+
+		b, breakerOpts := s.breakers.get(name)
+		if !b.allow(breakerOpts, time.Now()) {
+			ctxlog.Get(ctx).Warningf("analyzer %s skipped, its circuit breaker is open", name)
+			skipped = append(skipped, name)
+			commentsCh <- nil
+			continue
+		}
+
+		go func(name string, a lookout.Analyzer, settings map[string]interface{}, cacheable bool) {
 			var result *lookout.AnalyzerComments
 			defer func() { commentsCh <- result }()
 
@@ -381,14 +695,24 @@ func (s *Server) concurrentRequest(ctx context.Context, conf map[string]lookout.
 				"analyzer": name,
 			})
 
-			settings := mergeSettings(a.Config.Settings, conf[name].Settings)
+			// the analyzer needs its name to report the progress
+			sendCtx := metadata.AppendToOutgoingContext(ctx, lookout.AnalyzerNameMetadata, name)
+			sendCtx, stopWatch := s.progress.watch(sendCtx, e, name, analyzers.heartbeatTimeout)
+			resp, err := send(sendCtx, a.Client, settings)
+			err = stopWatch(err)
+			if st, changed := b.done(breakerOpts, err, time.Now()); changed {
+				aLogger.With(log.Fields{
+					"failures": st.Failures,
+				}).Infof("circuit breaker %s", st.State)
+			}
 
-			cs, err := send(ctx, a.Client, settings)
 			if err != nil {
 				grpcStatus := status.Convert(err)
 				errMessage := "analysis failed"
 				friendlyMessage, ok := logErrorMessages[grpcStatus.Code()]
-				if ok {
+				if err == errMissedHeartbeats {
+					errMessage = fmt.Sprintf("%s: %s", errMessage, heartbeatErrorMessage)
+				} else if ok {
 					errMessage = fmt.Sprintf("%s: %s", errMessage, friendlyMessage)
 				}
 
@@ -396,28 +720,40 @@ func (s *Server) concurrentRequest(ctx context.Context, conf map[string]lookout.
 
 				if s.exitOnError {
 					errCh <- err
+					return
+				}
+
+				// the comments streamed before the error are posted
+				if resp != nil && len(resp.Comments) > 0 {
+					aLogger.With(log.Fields{
+						"comments": len(resp.Comments),
+					}).Warningf("posting the partial analysis result")
+
+					result = analyzerComments(a, resp.Comments)
+					result.Partial = true
 				}
 
 				return
 			}
 
-			if len(cs) == 0 {
+			if cacheable {
+				s.results.set(key, resp.AnalyzerVersion, resp.Comments, time.Now())
+			}
+
+			if len(resp.Comments) == 0 {
 				aLogger.Infof("no comments were produced")
 				return
 			}
 
-			result = &lookout.AnalyzerComments{
-				Config:   a.Config,
-				Comments: cs,
-			}
-		}(name, a)
+			result = analyzerComments(a, resp.Comments)
+		}(name, a, settings, err == nil)
 	}
 
 	var comments []lookout.AnalyzerComments
-	for i := 0; i < len(s.analyzers); i++ {
+	for i := 0; i < len(analyzers.analyzers); i++ {
 		select {
 		case err := <-errCh:
-			return nil, err
+			return nil, nil, err
 		case cs := <-commentsCh:
 			if cs != nil {
 				comments = append(comments, *cs)
@@ -425,7 +761,20 @@ func (s *Server) concurrentRequest(ctx context.Context, conf map[string]lookout.
 		}
 	}
 
-	return comments, nil
+	sort.Strings(skipped)
+	return comments, skipped, nil
+}
+
+// analyzerComments returns the comments cs made by a, or nil if there are none
+func analyzerComments(a lookout.Analyzer, cs []*lookout.Comment) *lookout.AnalyzerComments {
+	if len(cs) == 0 {
+		return nil
+	}
+
+	return &lookout.AnalyzerComments{
+		Config:   a.Config,
+		Comments: cs,
+	}
 }
 
 func mergeConfigs(global, local map[string]lookout.AnalyzerConfig) map[string]lookout.AnalyzerConfig {
@@ -538,6 +887,20 @@ func (s *Server) status(ctx context.Context, e lookout.Event, st lookout.Analysi
 	}
 }
 
+// statusSkipped posts the status with the names of the skipped analyzers, if
+// there are any and the poster supports it
+func (s *Server) statusSkipped(ctx context.Context, e lookout.Event, st lookout.AnalysisStatus, skipped []string) {
+	p, ok := s.poster.(lookout.SkippedAnalyzersPoster)
+	if !ok || len(skipped) == 0 {
+		s.status(ctx, e, st)
+		return
+	}
+
+	if err := p.StatusSkipped(ctx, e, st, skipped); err != nil {
+		ctxlog.Get(ctx).With(log.Fields{"status": st}).Errorf(err, "posting status failed")
+	}
+}
+
 type LogPoster struct {
 	Log log.Logger
 }
@@ -546,9 +909,21 @@ func (p *LogPoster) Post(ctx context.Context, e lookout.Event,
 	aCommentsList []lookout.AnalyzerComments, safe bool) error {
 	for _, aComments := range aCommentsList {
 		for _, c := range aComments.Comments {
+			text, ext := lookout.SplitCommentExtension(c.Text)
 			logger := p.Log.With(log.Fields{
-				"text": c.Text,
+				"text": text,
 			})
+			if aComments.Partial {
+				logger = logger.With(log.Fields{"partial": true})
+			}
+
+			if ext != nil && ext.Suggestion != nil {
+				logger = logger.With(log.Fields{
+					"suggestion": fmt.Sprintf("lines %d-%d: %q", ext.Suggestion.StartLine,
+						ext.Suggestion.EndLine, ext.Suggestion.Replacement),
+				})
+			}
+
 			if c.File == "" {
 				logger.Infof("global comment")
 				continue
@@ -560,6 +935,14 @@ func (p *LogPoster) Post(ctx context.Context, e lookout.Event,
 				continue
 			}
 
+			if start, end := lookout.CommentRange(c); start < end {
+				logger.With(log.Fields{
+					"start-line": start,
+					"line":       end,
+				}).Infof("line range comment")
+				continue
+			}
+
 			logger.With(log.Fields{"line": c.Line}).Infof("line comment")
 		}
 	}
@@ -573,4 +956,18 @@ func (p *LogPoster) Status(ctx context.Context, e lookout.Event,
 	return nil
 }
 
-var _ lookout.Poster = &LogPoster{}
+func (p *LogPoster) StatusSkipped(ctx context.Context, e lookout.Event,
+	status lookout.AnalysisStatus, skipped []string) error {
+	p.Log.Infof("status: %s, skipped analyzers: %s", status, strings.Join(skipped, ", "))
+	return nil
+}
+
+var _ lookout.SkippedAnalyzersPoster = &LogPoster{}
+
+func (p *LogPoster) StatusProgress(ctx context.Context, e lookout.Event,
+	progress string) error {
+	p.Log.Infof("status: %s, %s", lookout.PendingAnalysisStatus, progress)
+	return nil
+}
+
+var _ lookout.ProgressPoster = &LogPoster{}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -13,8 +14,11 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
-	log "gopkg.in/src-d/go-log.v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+	log "gopkg.in/src-d/go-log.v1"
 )
 
 func correctReviewEvent() *lookout.ReviewEvent {
@@ -177,6 +181,348 @@ func (s *ServerTestSuite) TestPushTimeout() {
 	require.Len(comments, 1)
 }
 
+func (s *ServerTestSuite) TestReload() {
+	require := s.Require()
+
+	oldClient := &AnalyzerClientMock{
+		CommentsBuilder: makeComments,
+		ReviewSleep:     200 * time.Millisecond,
+	}
+	poster := &PosterMock{}
+	srv := NewServer(Options{
+		Poster:     poster,
+		FileGetter: &FileGetterMock{},
+		Analyzers: map[string]lookout.Analyzer{
+			"old": lookout.Analyzer{Client: oldClient},
+		},
+	})
+
+	reviewEvent := correctReviewEvent()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.HandleReview(context.TODO(), reviewEvent, false) }()
+
+	// wait for the review to reach the analyzer
+	time.Sleep(50 * time.Millisecond)
+
+	newClient := &AnalyzerClientMock{
+		CommentsBuilder: func(lookout.Event, lookout.ReferencePointer, lookout.ReferencePointer) []*lookout.Comment {
+			return []*lookout.Comment{makeCommentFromString("new")}
+		},
+	}
+	drained := srv.Reload(ReloadOptions{
+		Analyzers: map[string]lookout.Analyzer{
+			"new": lookout.Analyzer{Client: newClient},
+		},
+	})
+
+	select {
+	case <-drained:
+		require.Fail("the previous analyzers are drained while a review is in progress")
+	default:
+	}
+
+	// the review in progress is not affected by the reload
+	require.NoError(<-errCh)
+	require.Equal([]*lookout.Comment{
+		makeComment(reviewEvent.CommitRevision.Base, reviewEvent.CommitRevision.Head),
+	}, poster.PopComments())
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		require.Fail("the previous analyzers are not drained")
+	}
+
+	// new events use the new analyzers
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Equal([]*lookout.Comment{makeCommentFromString("new")}, poster.PopComments())
+	require.Len(oldClient.PopReviewEvents(), 1)
+	require.Len(newClient.PopReviewEvents(), 1)
+}
+
+func (s *ServerTestSuite) TestCircuitBreaker() {
+	require := s.Require()
+
+	down := &AnalyzerClientMock{
+		CommentsBuilder: makeComments,
+		Err:             status.Error(codes.Unavailable, "connection refused"),
+	}
+	up := &AnalyzerClientMock{
+		CommentsBuilder: func(lookout.Event, lookout.ReferencePointer, lookout.ReferencePointer) []*lookout.Comment {
+			return []*lookout.Comment{makeCommentFromString("up")}
+		},
+	}
+	poster := &SkippedPosterMock{}
+	srv := NewServer(Options{
+		Poster:     poster,
+		FileGetter: &FileGetterMock{},
+		Analyzers: map[string]lookout.Analyzer{
+			"down": lookout.Analyzer{Client: down},
+			"up":   lookout.Analyzer{Client: up},
+		},
+		Breaker: BreakerOptions{Threshold: 2, ProbeInterval: 100 * time.Millisecond},
+	})
+
+	reviewEvent := correctReviewEvent()
+
+	// the circuit opens after 2 consecutive failures
+	for i := 0; i < 2; i++ {
+		require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+		require.Empty(poster.PopSkipped())
+	}
+	require.Len(down.PopReviewEvents(), 2)
+	require.Equal(map[string]BreakerStatus{
+		"down": {State: BreakerOpen, Failures: 2},
+		"up":   {State: BreakerClosed},
+	}, srv.BreakerStatus())
+
+	// the analyzer is skipped while the circuit is open
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Equal([]string{"down"}, poster.PopSkipped())
+	require.Equal(lookout.SuccessAnalysisStatus, poster.PopStatus())
+	require.Len(down.PopReviewEvents(), 0)
+	require.Equal([]*lookout.Comment{makeCommentFromString("up")}, poster.PopComments())
+
+	// the probe fails, and the circuit opens again
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Empty(poster.PopSkipped())
+	require.Len(down.PopReviewEvents(), 1)
+	require.Equal(BreakerOpen, srv.BreakerStatus()["down"].State)
+
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Equal([]string{"down"}, poster.PopSkipped())
+
+	// the probe succeeds, and the circuit closes
+	down.Err = nil
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Empty(poster.PopSkipped())
+	require.Len(down.PopReviewEvents(), 1)
+	require.Equal(BreakerStatus{State: BreakerClosed}, srv.BreakerStatus()["down"])
+
+	// the breakers of removed analyzers are forgotten
+	srv.Reload(ReloadOptions{
+		Analyzers: map[string]lookout.Analyzer{
+			"up": lookout.Analyzer{Client: up},
+		},
+	})
+	require.Equal(map[string]BreakerStatus{
+		"up": {State: BreakerClosed},
+	}, srv.BreakerStatus())
+}
+
+func (s *ServerTestSuite) TestResultCache() {
+	require := s.Require()
+
+	client := &AnalyzerClientMock{
+		CommentsBuilder: makeComments,
+		Version:         "1",
+	}
+	poster := &PosterMock{}
+	srv := NewServer(Options{
+		Poster:     poster,
+		FileGetter: &FileGetterMock{},
+		Analyzers: map[string]lookout.Analyzer{
+			"mock": lookout.Analyzer{Client: client},
+		},
+		ResultCache: ResultCacheOptions{TTL: time.Minute},
+	})
+
+	reviewEvent := correctReviewEvent()
+	expected := []*lookout.Comment{
+		makeComment(reviewEvent.CommitRevision.Base, reviewEvent.CommitRevision.Head),
+	}
+
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Equal(expected, poster.PopComments())
+	require.Len(client.PopReviewEvents(), 1)
+
+	// the same revision reuses the result
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Equal(expected, poster.PopComments())
+	require.Len(client.PopReviewEvents(), 0)
+
+	// a push for the same revision is a different request
+	pushEvent := correctPushEvent()
+	require.NoError(srv.HandlePush(context.TODO(), pushEvent, false))
+	require.Len(client.PopPushEvents(), 1)
+
+	// other head, a new analyzer version is reported
+	client.Version = "2"
+	otherEvent := correctReviewEvent()
+	otherEvent.CommitRevision.Head.Hash = "other"
+	require.NoError(srv.HandleReview(context.TODO(), otherEvent, false))
+	require.Len(client.PopReviewEvents(), 1)
+
+	// the results of the previous version are not used anymore
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Len(client.PopReviewEvents(), 1)
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Len(client.PopReviewEvents(), 0)
+
+	// the results are dropped when the analyzer address changes
+	srv.Reload(ReloadOptions{
+		Analyzers: map[string]lookout.Analyzer{
+			"mock": lookout.Analyzer{
+				Client: client,
+				Config: lookout.AnalyzerConfig{Addr: "ipv4://other:9930"},
+			},
+		},
+		ResultCache: ResultCacheOptions{TTL: time.Minute},
+	})
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Len(client.PopReviewEvents(), 1)
+}
+
+func (s *ServerTestSuite) TestStreamReview() {
+	require := s.Require()
+
+	client := &StreamAnalyzerClientMock{
+		AnalyzerClientMock: AnalyzerClientMock{CommentsBuilder: makeComments},
+		Responses: []*lookout.EventResponse{
+			{Comments: []*lookout.Comment{makeCommentFromString("first")}},
+			{Comments: []*lookout.Comment{makeCommentFromString("second")}},
+		},
+	}
+	watcher, poster := setupMockedServer(mockedServerParams{
+		AnalyzerClient: client,
+	})
+
+	require.NoError(watcher.Send(correctReviewEvent()))
+	require.Equal([]*lookout.Comment{
+		makeCommentFromString("first"),
+		makeCommentFromString("second"),
+	}, poster.PopComments())
+	require.False(poster.partial)
+	require.Len(client.PopReviewEvents(), 0)
+	require.Equal(1, client.streams)
+}
+
+func (s *ServerTestSuite) TestStreamPartialReview() {
+	require := s.Require()
+
+	client := &StreamAnalyzerClientMock{
+		AnalyzerClientMock: AnalyzerClientMock{CommentsBuilder: makeComments},
+		Responses: []*lookout.EventResponse{
+			{Comments: []*lookout.Comment{makeCommentFromString("first")}},
+		},
+		Err: status.Error(codes.DeadlineExceeded, "timeout"),
+	}
+	watcher, poster := setupMockedServer(mockedServerParams{
+		AnalyzerClient: client,
+		ResultCache:    ResultCacheOptions{TTL: time.Minute},
+	})
+
+	// the comments sent before the timeout are posted
+	require.NoError(watcher.Send(correctReviewEvent()))
+	require.Equal([]*lookout.Comment{makeCommentFromString("first")}, poster.PopComments())
+	require.True(poster.partial)
+
+	// partial results are not cached
+	require.NoError(watcher.Send(correctPushEvent()))
+	require.NoError(watcher.Send(correctReviewEvent()))
+	require.Equal(3, client.streams)
+}
+
+func (s *ServerTestSuite) TestStreamUnimplemented() {
+	require := s.Require()
+
+	client := &StreamAnalyzerClientMock{
+		AnalyzerClientMock: AnalyzerClientMock{CommentsBuilder: makeComments},
+		Err:                status.Error(codes.Unimplemented, "unknown service"),
+	}
+	watcher, poster := setupMockedServer(mockedServerParams{
+		AnalyzerClient: client,
+	})
+
+	reviewEvent := correctReviewEvent()
+	for i := 0; i < 2; i++ {
+		require.NoError(watcher.Send(reviewEvent))
+		require.Equal([]*lookout.Comment{
+			makeComment(reviewEvent.CommitRevision.Base, reviewEvent.CommitRevision.Head),
+		}, poster.PopComments())
+	}
+
+	// the unary RPC is used directly after the first Unimplemented
+	require.Len(client.PopReviewEvents(), 2)
+	require.Equal(1, client.streams)
+}
+
+func (s *ServerTestSuite) TestProgress() {
+	require := s.Require()
+
+	client := &ProgressAnalyzerClientMock{
+		AnalyzerClientMock: AnalyzerClientMock{CommentsBuilder: makeComments},
+		Reports: []*lookout.ProgressReport{
+			{Percent: 40, Description: "of files"},
+			{},
+			{Percent: 80, Description: "of files"},
+		},
+	}
+	poster := &ProgressPosterMock{}
+	srv := NewServer(Options{
+		Poster:     poster,
+		FileGetter: &FileGetterMock{},
+		Analyzers: map[string]lookout.Analyzer{
+			"mock": lookout.Analyzer{Client: client},
+		},
+		HeartbeatTimeout: time.Minute,
+	})
+	srv.progress.minInterval = 0
+	client.Reporter = srv
+
+	reviewEvent := correctReviewEvent()
+	require.NoError(srv.HandleReview(context.TODO(), reviewEvent, false))
+	require.Equal("mock", client.analyzer)
+	require.Equal([]string{
+		"analyzer mock: 40% of files",
+		"analyzer mock: 40% of files",
+		"analyzer mock: 80% of files",
+	}, poster.progress)
+	require.Equal(lookout.SuccessAnalysisStatus, poster.PopStatus())
+
+	// the event is not being analyzed anymore
+	err := srv.ReportProgress(context.TODO(), &lookout.ProgressReport{
+		EventID: reviewEvent.ID().String(),
+	})
+	require.Equal(codes.NotFound, status.Code(err))
+}
+
+func (s *ServerTestSuite) TestMissedHeartbeats() {
+	require := s.Require()
+
+	client := &ProgressAnalyzerClientMock{
+		AnalyzerClientMock: AnalyzerClientMock{CommentsBuilder: makeComments},
+		Reports:            []*lookout.ProgressReport{{Percent: 10}},
+		Hang:               true,
+	}
+	poster := &PosterMock{}
+	srv := NewServer(Options{
+		Poster:     poster,
+		FileGetter: &FileGetterMock{},
+		Analyzers: map[string]lookout.Analyzer{
+			"mock": lookout.Analyzer{Client: client},
+		},
+		ReviewTimeout:    time.Minute,
+		HeartbeatTimeout: 50 * time.Millisecond,
+		Breaker:          BreakerOptions{Threshold: 5, ProbeInterval: time.Minute},
+	})
+	client.Reporter = srv
+
+	// the analysis fails without waiting for the review timeout
+	start := time.Now()
+	require.NoError(srv.HandleReview(context.TODO(), correctReviewEvent(), false))
+	require.True(time.Since(start) < 10*time.Second)
+	require.Len(poster.PopComments(), 0)
+
+	// missed heartbeats count as a timeout
+	require.Equal(map[string]BreakerStatus{
+		"mock": {State: BreakerClosed, Failures: 1},
+	}, srv.BreakerStatus())
+}
+
 func (s *ServerTestSuite) TestPersistedReview() {
 	require := s.Require()
 
@@ -323,6 +669,7 @@ func (s *ServerTestSuite) TestMergeConfig() {
 		AnalyzerConfig *lookout.AnalyzerConfig
 		FileGetter     lookout.FileGetter
 		OrganizationOp store.OrganizationOperator
+		RepositoryOp   store.RepositoryOperator
 		expectedMap    map[string]interface{}
 	}{
 		{
@@ -355,6 +702,36 @@ func (s *ServerTestSuite) TestMergeConfig() {
 				"reused_key": "local",
 			},
 		},
+		{
+			name:         "repo",
+			RepositoryOp: &RepositoryOperatorMock{},
+			expectedMap: map[string]interface{}{
+				"repo_key":   "repo",
+				"reused_key": "repo",
+			},
+		},
+		{
+			name:           "org,repo",
+			OrganizationOp: &OrganizationOperatorMock{},
+			RepositoryOp:   &RepositoryOperatorMock{},
+			expectedMap: map[string]interface{}{
+				"org_key":    "org",
+				"repo_key":   "repo",
+				"reused_key": "repo",
+			},
+		},
+		{
+			name:           "org,repo,local",
+			FileGetter:     fileGetter,
+			OrganizationOp: &OrganizationOperatorMock{},
+			RepositoryOp:   &RepositoryOperatorMock{},
+			expectedMap: map[string]interface{}{
+				"local_key":  "local",
+				"org_key":    "org",
+				"repo_key":   "repo",
+				"reused_key": "local",
+			},
+		},
 		{
 			name:           "global",
 			AnalyzerConfig: &globalConfig,
@@ -395,6 +772,20 @@ func (s *ServerTestSuite) TestMergeConfig() {
 				"reused_key": "local",
 			},
 		},
+		{
+			name:           "global,org,repo,local",
+			AnalyzerConfig: &globalConfig,
+			FileGetter:     fileGetter,
+			OrganizationOp: &OrganizationOperatorMock{},
+			RepositoryOp:   &RepositoryOperatorMock{},
+			expectedMap: map[string]interface{}{
+				"global_key": "global",
+				"local_key":  "local",
+				"org_key":    "org",
+				"repo_key":   "repo",
+				"reused_key": "local",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -406,6 +797,7 @@ func (s *ServerTestSuite) TestMergeConfig() {
 				AnalyzerConfig: tc.AnalyzerConfig,
 				FileGetter:     tc.FileGetter,
 				OrganizationOp: tc.OrganizationOp,
+				RepositoryOp:   tc.RepositoryOp,
 			})
 
 			t.Run("review", func(t *testing.T) {
@@ -441,6 +833,58 @@ func TestServerTestSuite(t *testing.T) {
 	suite.Run(t, new(ServerTestSuite))
 }
 
+func TestEffectiveConfig(t *testing.T) {
+	require := require.New(t)
+
+	analyzers := []lookout.AnalyzerConfig{
+		{
+			Name: "mock",
+			Addr: "ipv4://localhost:9930",
+			Settings: map[string]interface{}{
+				"global_key": "global",
+				"reused_key": "global",
+			},
+		},
+		{
+			Name:     "disabled",
+			Disabled: true,
+		},
+	}
+
+	orgConfig := `
+analyzers:
+  - name: mock
+    settings:
+      org_key:    org
+      reused_key: org
+`
+	repoConfig := `
+analyzers:
+  - name: mock
+    settings:
+      repo_key:   repo
+      reused_key: repo
+`
+
+	conf, err := EffectiveConfig(context.TODO(), analyzers, orgConfig, repoConfig, "")
+	require.NoError(err)
+	require.Equal(map[string]lookout.AnalyzerConfig{
+		"mock": lookout.AnalyzerConfig{
+			Name: "mock",
+			Addr: "ipv4://localhost:9930",
+			Settings: map[string]interface{}{
+				"global_key": "global",
+				"org_key":    "org",
+				"repo_key":   "repo",
+				"reused_key": "repo",
+			},
+		},
+	}, conf)
+
+	_, err = EffectiveConfig(context.TODO(), analyzers, "", "not: [valid")
+	require.Error(err)
+}
+
 func TestConfigMerger(t *testing.T) {
 	require := require.New(t)
 
@@ -499,8 +943,10 @@ type mockedServerParams struct {
 	EventOp        store.EventOperator
 	CommentOp      store.CommentOperator
 	OrganizationOp store.OrganizationOperator
+	RepositoryOp   store.RepositoryOperator
 	ReviewTimeout  time.Duration
 	PushTimeout    time.Duration
+	ResultCache    ResultCacheOptions
 	Persist        bool
 }
 
@@ -559,8 +1005,10 @@ func setupMockedServer(params mockedServerParams) (*WatcherMock, *PosterMock) {
 		EventOp:        eventOp,
 		CommentOp:      commentOp,
 		OrganizationOp: organizationOp,
+		RepositoryOp:   params.RepositoryOp,
 		ReviewTimeout:  params.ReviewTimeout,
 		PushTimeout:    params.PushTimeout,
+		ResultCache:    params.ResultCache,
 	})
 
 	watcher.Watch(context.TODO(), srv.HandleEvent)
@@ -586,12 +1034,16 @@ var _ lookout.Poster = &PosterMock{}
 type PosterMock struct {
 	comments []*lookout.Comment
 	status   lookout.AnalysisStatus
+	// partial is true if any of the posted groups was partial
+	partial bool
 }
 
 func (p *PosterMock) Post(_ context.Context, e lookout.Event, aCommentsList []lookout.AnalyzerComments, safe bool) error {
 	cs := make([]*lookout.Comment, 0)
+	p.partial = false
 	for _, aComments := range aCommentsList {
 		cs = append(cs, aComments.Comments...)
+		p.partial = p.partial || aComments.Partial
 	}
 	p.comments = cs
 	return nil
@@ -614,6 +1066,37 @@ func (p *PosterMock) PopStatus() lookout.AnalysisStatus {
 	return st
 }
 
+var _ lookout.SkippedAnalyzersPoster = &SkippedPosterMock{}
+
+type SkippedPosterMock struct {
+	PosterMock
+	skipped []string
+}
+
+func (p *SkippedPosterMock) StatusSkipped(_ context.Context, e lookout.Event, st lookout.AnalysisStatus, skipped []string) error {
+	p.status = st
+	p.skipped = skipped
+	return nil
+}
+
+func (p *SkippedPosterMock) PopSkipped() []string {
+	skipped := p.skipped
+	p.skipped = nil
+	return skipped
+}
+
+var _ lookout.ProgressPoster = &ProgressPosterMock{}
+
+type ProgressPosterMock struct {
+	PosterMock
+	progress []string
+}
+
+func (p *ProgressPosterMock) StatusProgress(_ context.Context, e lookout.Event, progress string) error {
+	p.progress = append(p.progress, progress)
+	return nil
+}
+
 type FileGetterMock struct {
 }
 
@@ -654,12 +1137,35 @@ analyzers:
 
 var _ store.OrganizationOperator = &OrganizationOperatorMock{}
 
+type RepositoryOperatorMock struct{}
+
+func (o *RepositoryOperatorMock) Save(ctx context.Context, provider string, repoID string, config string) error {
+	return nil
+}
+
+func (o *RepositoryOperatorMock) Config(ctx context.Context, provider string, repoID string) (string, error) {
+	val := `
+analyzers:
+  - name: mock
+    settings:
+      repo_key:   repo
+      reused_key: repo
+`
+	return val, nil
+}
+
+var _ store.RepositoryOperator = &RepositoryOperatorMock{}
+
 type AnalyzerClientMock struct {
 	reviewEvents    []*pb.ReviewEvent
 	pushEvents      []*pb.PushEvent
 	CommentsBuilder func(ev lookout.Event, from, to lookout.ReferencePointer) []*lookout.Comment
 	ReviewSleep     time.Duration
 	PushSleep       time.Duration
+	// Err is returned by the Notify* calls if it is not nil
+	Err error
+	// Version is returned as the AnalyzerVersion
+	Version string
 }
 
 func (a *AnalyzerClientMock) NotifyReviewEvent(ctx context.Context, in *pb.ReviewEvent, opts ...grpc.CallOption) (*lookout.EventResponse, error) {
@@ -672,7 +1178,12 @@ func (a *AnalyzerClientMock) NotifyReviewEvent(ctx context.Context, in *pb.Revie
 	}
 
 	a.reviewEvents = append(a.reviewEvents, in)
+	if a.Err != nil {
+		return nil, a.Err
+	}
+
 	return &lookout.EventResponse{
+		AnalyzerVersion: a.Version,
 		Comments: a.CommentsBuilder(&lookout.ReviewEvent{ReviewEvent: *in},
 			in.CommitRevision.Base, in.CommitRevision.Head),
 	}, nil
@@ -689,6 +1200,7 @@ func (a *AnalyzerClientMock) NotifyPushEvent(ctx context.Context, in *pb.PushEve
 
 	a.pushEvents = append(a.pushEvents, in)
 	return &lookout.EventResponse{
+		AnalyzerVersion: a.Version,
 		Comments: a.CommentsBuilder(&lookout.PushEvent{PushEvent: *in},
 			in.CommitRevision.Base, in.CommitRevision.Head),
 	}, nil
@@ -706,6 +1218,81 @@ func (a *AnalyzerClientMock) PopPushEvents() []*pb.PushEvent {
 	return res
 }
 
+var _ lookout.AnalyzerStreamClient = &StreamAnalyzerClientMock{}
+
+// StreamAnalyzerClientMock streams Responses, and then returns Err, or
+// io.EOF if it is nil
+type StreamAnalyzerClientMock struct {
+	AnalyzerClientMock
+	Responses []*lookout.EventResponse
+	Err       error
+
+	streams int
+}
+
+func (a *StreamAnalyzerClientMock) NotifyReviewEventStream(ctx context.Context, in *pb.ReviewEvent, opts ...grpc.CallOption) (lookout.EventResponseStream, error) {
+	a.streams++
+	return &eventResponseStreamMock{responses: a.Responses, err: a.Err}, nil
+}
+
+func (a *StreamAnalyzerClientMock) NotifyPushEventStream(ctx context.Context, in *pb.PushEvent, opts ...grpc.CallOption) (lookout.EventResponseStream, error) {
+	a.streams++
+	return &eventResponseStreamMock{responses: a.Responses, err: a.Err}, nil
+}
+
+// ProgressAnalyzerClientMock sends the Reports to the Reporter before
+// replying to the review events
+type ProgressAnalyzerClientMock struct {
+	AnalyzerClientMock
+	Reporter lookout.ProgressReporter
+	Reports  []*lookout.ProgressReport
+	// Hang makes the analyzer stop replying after sending the Reports
+	Hang bool
+
+	analyzer string
+}
+
+func (a *ProgressAnalyzerClientMock) NotifyReviewEvent(ctx context.Context, in *pb.ReviewEvent, opts ...grpc.CallOption) (*lookout.EventResponse, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if names := md.Get(lookout.AnalyzerNameMetadata); len(names) > 0 {
+		a.analyzer = names[0]
+	}
+
+	for _, r := range a.Reports {
+		r.EventID = in.ID().String()
+		if err := a.Reporter.ReportProgress(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.Hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	return a.AnalyzerClientMock.NotifyReviewEvent(ctx, in, opts...)
+}
+
+type eventResponseStreamMock struct {
+	grpc.ClientStream
+	responses []*lookout.EventResponse
+	err       error
+}
+
+func (s *eventResponseStreamMock) Recv() (*lookout.EventResponse, error) {
+	if len(s.responses) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+
+		return nil, io.EOF
+	}
+
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
 func makeComment(from, to lookout.ReferencePointer) *lookout.Comment {
 	return makeCommentFromString(fmt.Sprintf("%s > %s", from.Hash, to.Hash))
 }
@@ -101,8 +101,18 @@ type BaseScanner struct {
 	err          error
 }
 
-func (s *BaseScanner) processFile(f *lookout.File) error {
-	if f == nil {
+// processFile parses f, a file of the current file or change of the
+// underlying scanner
+func (s *BaseScanner) processFile(underlying interface{}, f *lookout.File) error {
+	// the content of a symbolic link is its target, and the one of a Git LFS
+	// pointer that was not fetched is the pointer, not code
+	if f == nil || lookout.IsSymlink(f) || lookout.IsSubmodule(f) ||
+		lookout.IsLFSPointer(f) {
+		return nil
+	}
+
+	// the content was not loaded, see lookout.ContentLimits
+	if lookout.ScannerSkipReason(underlying, f) != "" {
 		return nil
 	}
 
@@ -166,12 +176,12 @@ func (s *ChangeScanner) Next() bool {
 
 	s.val = s.underlying.Change()
 
-	if err := s.processFile(s.val.Base); err != nil {
+	if err := s.processFile(s.underlying, s.val.Base); err != nil {
 		s.err = err
 		return false
 	}
 
-	if err := s.processFile(s.val.Head); err != nil {
+	if err := s.processFile(s.underlying, s.val.Head); err != nil {
 		s.err = err
 		return false
 	}
@@ -191,6 +201,14 @@ func (s *ChangeScanner) Change() *lookout.Change {
 	return s.val
 }
 
+func (s *ChangeScanner) Hunks() []*lookout.Hunk {
+	return lookout.ScannerHunks(s.underlying)
+}
+
+func (s *ChangeScanner) SkipReason(f *lookout.File) string {
+	return lookout.ScannerSkipReason(s.underlying, f)
+}
+
 func (s *ChangeScanner) Close() error {
 	return s.underlying.Close()
 }
@@ -213,7 +231,7 @@ func (s *FileScanner) Next() bool {
 
 	s.val = s.underlying.File()
 
-	if err := s.processFile(s.val); err != nil {
+	if err := s.processFile(s.underlying, s.val); err != nil {
 		s.err = err
 		return false
 	}
@@ -233,6 +251,10 @@ func (s *FileScanner) File() *lookout.File {
 	return s.val
 }
 
+func (s *FileScanner) SkipReason(f *lookout.File) string {
+	return lookout.ScannerSkipReason(s.underlying, f)
+}
+
 func (s *FileScanner) Close() error {
 	return s.underlying.Close()
 }
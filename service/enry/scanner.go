@@ -38,12 +38,22 @@ func newFileExcludeVendorScanner(scanner lookout.FileScanner) *lookout.FnFileSca
 	}
 }
 
+// language returns the language of f, the symbolic links and submodules have
+// none
+func language(f *lookout.File) string {
+	if lookout.IsSymlink(f) || lookout.IsSubmodule(f) {
+		return ""
+	}
+
+	return enry.GetLanguage(f.Path, f.Content)
+}
+
 func getLanguage(f *lookout.File) (bool, error) {
 	if f == nil {
 		return false, nil
 	}
 
-	f.Language = enry.GetLanguage(f.Path, f.Content)
+	f.Language = language(f)
 
 	return false, nil
 }
@@ -91,7 +101,7 @@ func (s *languageFilter) Fn(f *lookout.File) (bool, error) {
 
 	lang := f.Language
 	if s.detect {
-		lang = enry.GetLanguage(f.Path, f.Content)
+		lang = language(f)
 	}
 
 	allowed, _ := s.allow[strings.ToLower(lang)]
@@ -174,3 +174,28 @@ func (s *ScannerSuite) TestChangeFilterLanguageScanner() {
 
 	require.Len(changes, 3)
 }
+
+func (s *ScannerSuite) TestFileLanguageScannerLinks() {
+	require := s.Require()
+
+	files := []*lookout.File{
+		{Path: "main.go", Mode: 0100644, Content: []byte("package main")},
+		// the content of a symbolic link is its target
+		{Path: "link.go", Mode: 0120000, Content: []byte("main.go")},
+		{Path: "lib.go", Mode: 0160000},
+	}
+
+	fs := newFileLanguageScanner(&mock.SliceFileScanner{Files: files})
+
+	languages := make(map[string]string)
+	for fs.Next() {
+		languages[fs.File().Path] = fs.File().Language
+	}
+
+	require.NoError(fs.Err())
+	require.Equal(map[string]string{
+		"main.go": "Go",
+		"link.go": "",
+		"lib.go":  "",
+	}, languages)
+}
@@ -0,0 +1,414 @@
+package git
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/meyskens/lookout"
+	"github.com/meyskens/lookout/util/ctxlog"
+
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+	"gopkg.in/src-d/go-billy.v4/util"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// pullRefPrefix is the prefix of the pull request references fetched by
+// LibrarySyncer. They are pruned, like the references under forkRefPrefix,
+// once they are not active.
+const pullRefPrefix = "refs/pull/"
+
+// maxPacks is the number of packfiles of a repository above which the
+// Janitor repacks it even if no reference was pruned
+const maxPacks = 10
+
+// ReferencesProvider returns the references of the repositories that are
+// still in use
+type ReferencesProvider interface {
+	// ActiveReferences returns the pull request references of repo that must
+	// be kept, like the heads of its open pull requests, including the ones
+	// fetched from forks, see ForkReferenceName
+	ActiveReferences(ctx context.Context, repo *lookout.RepositoryInfo) (
+		[]plumbing.ReferenceName, error)
+}
+
+// JanitorOptions holds the configuration of a Janitor
+type JanitorOptions struct {
+	// Interval between two cleanups
+	Interval time.Duration
+	// MaxSize is the maximum size of the library, in bytes. Once it is
+	// exceeded, the least recently used repositories are removed. Zero means
+	// no limit.
+	MaxSize int64
+	// GracePeriod is the time the repositories used, and the objects and
+	// packfiles written, are kept after that
+	GracePeriod time.Duration
+}
+
+// Janitor cleans up the repositories of a Library: it prunes the references
+// of the pull requests that are not active anymore, removes the unreachable
+// objects, and removes the least recently used repositories when the library
+// exceeds its maximum size. The repositories are locked while they are
+// cleaned up, so they are not fetched at the same time by a LibrarySyncer of
+// the same Library.
+type Janitor struct {
+	library *Library
+	refs    ReferencesProvider
+	opts    JanitorOptions
+}
+
+// NewJanitor creates a new Janitor for the library l. refs can be nil, then
+// the references are never pruned.
+func NewJanitor(l *Library, refs ReferencesProvider, opts JanitorOptions) *Janitor {
+	return &Janitor{library: l, refs: refs, opts: opts}
+}
+
+// Run cleans up the library every Interval until ctx is canceled
+func (j *Janitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := j.Clean(ctx); err != nil {
+				ctxlog.Get(ctx).Errorf(err, "library cleanup failed")
+			}
+		}
+	}
+}
+
+// libraryRepository is a repository found in the library
+type libraryRepository struct {
+	path string
+	// info is nil if it can't be parsed from the path
+	info *lookout.RepositoryInfo
+	size int64
+	// fork is true if it reads objects from other repositories of the
+	// library, and forked if other repositories read objects from it
+	fork, forked bool
+}
+
+// Clean cleans up the library once. The errors cleaning a repository are
+// logged, the returned error is only for the errors listing the repositories.
+func (j *Janitor) Clean(ctx context.Context) error {
+	start := time.Now()
+	repos, err := j.repositories("")
+	if err != nil {
+		return err
+	}
+
+	if err := j.findForks(repos); err != nil {
+		return err
+	}
+
+	var size int64
+	for _, r := range repos {
+		logger := ctxlog.Get(ctx).With(log.Fields{"repository": r.path})
+		if err := j.cleanRepository(ctx, r); err != nil {
+			logger.Errorf(err, "repository could not be cleaned up")
+		}
+
+		if r.size, err = j.size(r.path); err != nil {
+			logger.Errorf(err, "repository size could not be calculated")
+		}
+
+		size += r.size
+	}
+
+	if j.opts.MaxSize > 0 && size > j.opts.MaxSize {
+		size = j.evict(ctx, repos, size)
+	}
+
+	ctxlog.Get(ctx).With(log.Fields{
+		"repositories": len(repos),
+		"size":         size,
+		"duration":     time.Since(start),
+	}).Debugf("library cleaned up")
+
+	return nil
+}
+
+// repositories returns the repositories in dir and its subdirectories
+func (j *Janitor) repositories(dir string) ([]*libraryRepository, error) {
+	fs := j.library.fs
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var isRepo bool
+	for _, e := range entries {
+		if e.Name() == "HEAD" && !e.IsDir() {
+			isRepo = true
+			break
+		}
+	}
+
+	if isRepo {
+		info, err := pb.ParseRepositoryInfo("https://" + dir)
+		if err != nil {
+			info = nil
+		}
+
+		return []*libraryRepository{{path: dir, info: info}}, nil
+	}
+
+	var repos []*libraryRepository
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		rs, err := j.repositories(path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		repos = append(repos, rs...)
+	}
+
+	return repos, nil
+}
+
+// findForks sets which repositories share objects with others
+func (j *Janitor) findForks(repos []*libraryRepository) error {
+	byPath := make(map[string]*libraryRepository, len(repos))
+	for _, r := range repos {
+		byPath[pathKey(r.path)] = r
+	}
+
+	for _, r := range repos {
+		paths, err := j.library.alternates(r.path)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range paths {
+			r.fork = true
+			if parent, ok := byPath[pathKey(p)]; ok {
+				parent.forked = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// cleanRepository prunes the references of the repository that are not
+// active anymore, and removes its unreachable objects unless it is shallow or
+// it has forks
+func (j *Janitor) cleanRepository(ctx context.Context, lr *libraryRepository) error {
+	var active map[plumbing.ReferenceName]bool
+	if j.refs != nil && lr.info != nil {
+		refs, err := j.refs.ActiveReferences(ctx, lr.info)
+		if err != nil {
+			return err
+		}
+
+		active = make(map[plumbing.ReferenceName]bool, len(refs))
+		for _, ref := range refs {
+			active[ref] = true
+		}
+	}
+
+	unlock := j.library.lockPath(lr.path)
+	defer unlock()
+
+	s, err := j.library.pathStorer(lr.path)
+	if err != nil {
+		return err
+	}
+
+	r, err := git.Open(s, nil)
+	if err != nil {
+		return err
+	}
+
+	var pruned []plumbing.ReferenceName
+	if active != nil {
+		refs, err := r.References()
+		if err != nil {
+			return err
+		}
+
+		err = refs.ForEach(func(ref *plumbing.Reference) error {
+			name := ref.Name()
+			prunable := strings.HasPrefix(name.String(), pullRefPrefix) ||
+				strings.HasPrefix(name.String(), forkRefPrefix)
+			if prunable && !active[name] {
+				pruned = append(pruned, name)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, name := range pruned {
+			if err := r.Storer.RemoveReference(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger := ctxlog.Get(ctx).With(log.Fields{"repository": lr.path})
+	if len(pruned) > 0 {
+		logger.With(log.Fields{"references": pruned}).Debugf("references pruned")
+	}
+
+	// go-git can't walk the history of shallow repositories to find the
+	// reachable objects, so their objects are kept
+	shallows, err := s.Shallow()
+	if err != nil {
+		return err
+	}
+
+	if len(shallows) > 0 {
+		logger.Debugf("shallow repository, objects not pruned")
+		return nil
+	}
+
+	// the forks may need the objects that are not reachable anymore
+	if lr.forked {
+		logger.Debugf("repository with forks, objects not pruned")
+		return nil
+	}
+
+	deadline := time.Now().Add(-j.opts.GracePeriod)
+
+	var packs []plumbing.Hash
+	if pos, ok := s.(storer.PackedObjectStorer); ok {
+		if packs, err = pos.ObjectPacks(); err != nil {
+			return err
+		}
+	}
+
+	// repacking a fork would copy the objects it shares into its packfile
+	if !lr.fork && (len(pruned) > 0 || len(packs) > maxPacks) {
+		err := r.RepackObjects(&git.RepackConfig{OnlyDeletePacksOlderThan: deadline})
+		if err != nil {
+			return err
+		}
+
+		logger.With(log.Fields{"packs": len(packs)}).Debugf("objects repacked")
+	}
+
+	return r.Prune(git.PruneOptions{
+		OnlyObjectsOlderThan: deadline,
+		Handler:              r.DeleteObject,
+	})
+}
+
+// size returns the size of the files in dir and its subdirectories
+func (j *Janitor) size(dir string) (int64, error) {
+	entries, err := j.library.fs.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, e := range entries {
+		if !e.IsDir() {
+			size += e.Size()
+			continue
+		}
+
+		s, err := j.size(path.Join(dir, e.Name()))
+		if err != nil {
+			return 0, err
+		}
+
+		size += s
+	}
+
+	return size, nil
+}
+
+// evict removes the least recently used repositories until the library size
+// is below the maximum, and returns the new size. The repositories used during
+// the grace period, and the ones with forks, are kept.
+func (j *Janitor) evict(ctx context.Context, repos []*libraryRepository, size int64) int64 {
+	used := make(map[string]time.Time, len(repos))
+	for _, r := range repos {
+		t, err := j.library.lastUsed(r.path)
+		if err != nil {
+			ctxlog.Get(ctx).With(log.Fields{"repository": r.path}).
+				Errorf(err, "repository last use could not be read")
+			continue
+		}
+
+		used[r.path] = t
+	}
+
+	sorted := make([]*libraryRepository, 0, len(used))
+	for _, r := range repos {
+		// the objects of the repositories with forks are still needed
+		if _, ok := used[r.path]; ok && !r.forked {
+			sorted = append(sorted, r)
+		}
+	}
+
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return used[sorted[a].path].Before(used[sorted[b].path])
+	})
+
+	deadline := time.Now().Add(-j.opts.GracePeriod)
+	for _, r := range sorted {
+		if size <= j.opts.MaxSize {
+			break
+		}
+
+		if !used[r.path].Before(deadline) {
+			break
+		}
+
+		logger := ctxlog.Get(ctx).With(log.Fields{
+			"repository": r.path,
+			"size":       r.size,
+			"last-used":  used[r.path],
+		})
+
+		removed, err := j.remove(r.path, deadline)
+		if err != nil {
+			logger.Errorf(err, "repository could not be removed")
+			continue
+		}
+
+		if !removed {
+			continue
+		}
+
+		logger.Infof("repository removed from the library, the library size exceeds its maximum")
+		size -= r.size
+	}
+
+	return size
+}
+
+// remove removes the repository at path, unless it was used after deadline.
+// It returns whether the repository was removed.
+func (j *Janitor) remove(path string, deadline time.Time) (bool, error) {
+	unlock := j.library.lockPath(path)
+	defer unlock()
+
+	// it may have been used while the library was cleaned up
+	if t, err := j.library.lastUsed(path); err == nil && !t.Before(deadline) {
+		return false, nil
+	}
+
+	if err := util.RemoveAll(j.library.fs, path); err != nil {
+		return false, err
+	}
+
+	j.library.used.Delete(pathKey(path))
+	return true, nil
+}
@@ -0,0 +1,290 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/meyskens/lookout"
+
+	fixtures "github.com/src-d/go-git-fixtures"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+type JanitorSuite struct {
+	suite.Suite
+	dir     string
+	library *Library
+}
+
+func TestJanitorSuite(t *testing.T) {
+	suite.Run(t, new(JanitorSuite))
+}
+
+func (s *JanitorSuite) SetupTest() {
+	// go-git loops forever locking the packed-refs file of a memfs
+	// repository to remove a reference
+	dir, err := ioutil.TempDir("", "lookout-janitor")
+	s.Require().NoError(err)
+
+	s.dir = dir
+	s.library = NewLibrary(osfs.New(dir))
+}
+
+func (s *JanitorSuite) TearDownTest() {
+	s.Require().NoError(os.RemoveAll(s.dir))
+}
+
+func (s *JanitorSuite) TearDownSuite() {
+	s.Require().NoError(fixtures.Clean())
+}
+
+// addRepository copies the basic fixture to the library as the repository
+// url, with the pull request references pulls
+func (s *JanitorSuite) addRepository(url string, pulls ...int) *lookout.RepositoryInfo {
+	require := s.Require()
+
+	info, err := pb.ParseRepositoryInfo(url)
+	require.NoError(err)
+
+	dir := s.library.repositoryPath(info)
+	require.NoError(copyDir(fixtures.Basic().One().DotGit(), s.library.fs, "", dir))
+
+	r, err := s.library.get(info)
+	require.NoError(err)
+
+	for _, n := range pulls {
+		name := plumbing.ReferenceName(fmt.Sprintf("refs/pull/%d/head", n))
+		ref := plumbing.NewHashReference(name,
+			plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881"))
+		require.NoError(r.Storer.SetReference(ref))
+	}
+
+	return info
+}
+
+func copyDir(src, dst billy.Filesystem, srcDir, dstDir string) error {
+	entries, err := src.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	if err := dst.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		srcPath := path.Join(srcDir, e.Name())
+		dstPath := path.Join(dstDir, e.Name())
+		if e.IsDir() {
+			if err := copyDir(src, dst, srcPath, dstPath); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := copyFile(src, dst, srcPath, dstPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(src, dst billy.Filesystem, srcPath, dstPath string) error {
+	in, err := src.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := dst.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+type mockReferencesProvider map[string][]plumbing.ReferenceName
+
+func (p mockReferencesProvider) ActiveReferences(ctx context.Context,
+	repo *lookout.RepositoryInfo) ([]plumbing.ReferenceName, error) {
+	return p[repo.FullName], nil
+}
+
+func (s *JanitorSuite) TestCleanPrunesReferences() {
+	require := s.Require()
+
+	info := s.addRepository("https://github.com/foo/bar", 1, 2)
+	r, err := s.library.get(info)
+	require.NoError(err)
+
+	hash := plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881")
+	for _, name := range []plumbing.ReferenceName{
+		"refs/forks/github.com-baz-bar/heads/open",
+		"refs/forks/github.com-baz-bar/heads/closed",
+	} {
+		require.NoError(r.Storer.SetReference(plumbing.NewHashReference(name, hash)))
+	}
+
+	refs := mockReferencesProvider{
+		"foo/bar": {"refs/pull/1/head", "refs/forks/github.com-baz-bar/heads/open"},
+	}
+
+	j := NewJanitor(s.library, refs, JanitorOptions{})
+	require.NoError(j.Clean(context.Background()))
+
+	r, err = s.library.get(info)
+	require.NoError(err)
+
+	_, err = r.Reference("refs/pull/1/head", false)
+	require.NoError(err)
+	_, err = r.Reference("refs/pull/2/head", false)
+	require.Equal(plumbing.ErrReferenceNotFound, err)
+	_, err = r.Reference("refs/forks/github.com-baz-bar/heads/open", false)
+	require.NoError(err)
+	_, err = r.Reference("refs/forks/github.com-baz-bar/heads/closed", false)
+	require.Equal(plumbing.ErrReferenceNotFound, err)
+
+	// the other references and their objects are kept
+	ref, err := r.Reference("refs/heads/master", false)
+	require.NoError(err)
+	_, err = r.CommitObject(ref.Hash())
+	require.NoError(err)
+	_, err = r.CommitObject(plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881"))
+	require.NoError(err)
+}
+
+func (s *JanitorSuite) TestCleanWithoutProvider() {
+	require := s.Require()
+
+	info := s.addRepository("https://github.com/foo/bar", 1)
+
+	j := NewJanitor(s.library, nil, JanitorOptions{})
+	require.NoError(j.Clean(context.Background()))
+
+	r, err := s.library.get(info)
+	require.NoError(err)
+	_, err = r.Reference("refs/pull/1/head", false)
+	require.NoError(err)
+}
+
+func (s *JanitorSuite) TestCleanEvicts() {
+	require := s.Require()
+
+	old := s.addRepository("https://github.com/foo/old")
+	recent := s.addRepository("https://github.com/foo/recent")
+	current := s.addRepository("https://github.com/foo/current")
+
+	now := time.Now()
+	s.library.used.Store(pathKey(s.library.repositoryPath(old)), now.Add(-3*time.Hour))
+	s.library.used.Store(pathKey(s.library.repositoryPath(recent)), now.Add(-2*time.Hour))
+	s.library.touch(s.library.repositoryPath(current))
+
+	j := NewJanitor(s.library, nil, JanitorOptions{})
+	size, err := j.size(s.library.repositoryPath(old))
+	require.NoError(err)
+
+	// two repositories fit, the least recently used one is removed
+	j.opts = JanitorOptions{MaxSize: 2 * size, GracePeriod: time.Hour}
+	require.NoError(j.Clean(context.Background()))
+
+	has, err := s.library.Has(old)
+	require.NoError(err)
+	require.False(has)
+
+	has, err = s.library.Has(recent)
+	require.NoError(err)
+	require.True(has)
+
+	has, err = s.library.Has(current)
+	require.NoError(err)
+	require.True(has)
+
+	// the current repository is not evicted even if the library is still too
+	// big
+	j.opts.MaxSize = 1
+	require.NoError(j.Clean(context.Background()))
+
+	has, err = s.library.Has(recent)
+	require.NoError(err)
+	require.False(has)
+
+	has, err = s.library.Has(current)
+	require.NoError(err)
+	require.True(has)
+}
+
+func (s *JanitorSuite) TestCleanShallow() {
+	require := s.Require()
+
+	info := s.addRepository("https://github.com/foo/bar", 1)
+	r, err := s.library.get(info)
+	require.NoError(err)
+
+	// the references of a shallow repository are pruned, but its objects
+	// are not walked
+	shallow := plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	require.NoError(r.Storer.SetShallow([]plumbing.Hash{shallow}))
+
+	j := NewJanitor(s.library, mockReferencesProvider{}, JanitorOptions{})
+	require.NoError(j.Clean(context.Background()))
+
+	_, err = r.Reference("refs/pull/1/head", false)
+	require.Equal(plumbing.ErrReferenceNotFound, err)
+	_, err = r.CommitObject(shallow)
+	require.NoError(err)
+}
+
+func (s *JanitorSuite) TestCleanForks() {
+	require := s.Require()
+
+	parent := s.addRepository("https://github.com/foo/bar", 1)
+	fork, err := pb.ParseRepositoryInfo("https://github.com/baz/bar")
+	require.NoError(err)
+	_, err = s.library.Init(context.Background(), fork)
+	require.NoError(err)
+	require.NoError(s.library.ShareObjects(fork, parent))
+
+	old := time.Now().Add(-2 * time.Hour)
+	s.library.used.Store(pathKey(s.library.repositoryPath(parent)), old)
+	s.library.used.Store(pathKey(s.library.repositoryPath(fork)), old)
+
+	// the objects of the parent are not pruned, and it is not removed while
+	// the fork exists
+	refs := mockReferencesProvider{}
+	j := NewJanitor(s.library, refs, JanitorOptions{MaxSize: 1, GracePeriod: time.Hour})
+	require.NoError(j.Clean(context.Background()))
+
+	has, err := s.library.Has(parent)
+	require.NoError(err)
+	require.True(has)
+
+	has, err = s.library.Has(fork)
+	require.NoError(err)
+	require.False(has)
+
+	r, err := s.library.get(parent)
+	require.NoError(err)
+	_, err = r.Reference("refs/pull/1/head", false)
+	require.Equal(plumbing.ErrReferenceNotFound, err)
+
+	require.NoError(j.Clean(context.Background()))
+
+	has, err = s.library.Has(parent)
+	require.NoError(err)
+	require.False(has)
+}
@@ -0,0 +1,596 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/meyskens/lookout"
+	"github.com/meyskens/lookout/util/ctxlog"
+
+	billy "gopkg.in/src-d/go-billy.v4"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/utils/binary"
+	gitioutil "gopkg.in/src-d/go-git.v4/utils/ioutil"
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// LFSResolver fetches the objects of the files stored with Git LFS
+type LFSResolver interface {
+	// Resolve returns the contents of the objects the pointers ps of the
+	// repository repo point to, by OID. The objects that can not be fetched
+	// are not returned, an error is returned if none can be fetched because
+	// of a failure of the whole request.
+	Resolve(ctx context.Context, repo *lookout.RepositoryInfo,
+		ps []*lookout.LFSPointer) (map[string][]byte, error)
+}
+
+// lfsMediaType is the media type of the requests and responses of the Git LFS
+// batch API
+const lfsMediaType = "application/vnd.git-lfs+json"
+
+// lfsMaxBatchObjects is the maximum number of objects of a request to the
+// batch API, the limit of most of the Git LFS servers
+const lfsMaxBatchObjects = 100
+
+// HTTPLFSResolver fetches the objects from the Git LFS server of each
+// repository, at <clone URL>/info/lfs, with the batch API
+type HTTPLFSResolver struct {
+	client *http.Client
+	auth   AuthProvider
+}
+
+var _ LFSResolver = &HTTPLFSResolver{}
+
+// NewHTTPLFSResolver creates a new HTTPLFSResolver that sends the requests
+// with client, http.DefaultClient if it is nil, and the credentials of
+// authProvider, it can be nil
+func NewHTTPLFSResolver(client *http.Client, authProvider AuthProvider) *HTTPLFSResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPLFSResolver{client: client, auth: authProvider}
+}
+
+type lfsBatchRequest struct {
+	Operation string           `json:"operation"`
+	Transfers []string         `json:"transfers"`
+	Objects   []lfsBatchObject `json:"objects"`
+}
+
+type lfsBatchResponse struct {
+	Objects []lfsBatchObject `json:"objects"`
+}
+
+type lfsBatchObject struct {
+	OID     string `json:"oid"`
+	Size    int64  `json:"size"`
+	Actions *struct {
+		Download *struct {
+			Href   string            `json:"href"`
+			Header map[string]string `json:"header"`
+		} `json:"download"`
+	} `json:"actions,omitempty"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Resolve implements LFSResolver. It requests the download actions of the
+// objects to the batch API, in groups of up to 100, and downloads each object.
+func (r *HTTPLFSResolver) Resolve(ctx context.Context,
+	repo *lookout.RepositoryInfo, ps []*lookout.LFSPointer) (map[string][]byte, error) {
+
+	contents := make(map[string][]byte, len(ps))
+	for len(ps) > 0 {
+		batch := ps
+		if len(batch) > lfsMaxBatchObjects {
+			batch = batch[:lfsMaxBatchObjects]
+		}
+
+		ps = ps[len(batch):]
+		if err := r.resolveBatch(ctx, repo, batch, contents); err != nil {
+			return nil, err
+		}
+	}
+
+	return contents, nil
+}
+
+// resolveBatch adds the contents of the objects of ps to contents with a
+// single request to the batch API
+func (r *HTTPLFSResolver) resolveBatch(ctx context.Context,
+	repo *lookout.RepositoryInfo, ps []*lookout.LFSPointer, contents map[string][]byte) error {
+
+	objects := make([]lfsBatchObject, len(ps))
+	pointers := make(map[string]*lookout.LFSPointer, len(ps))
+	for i, p := range ps {
+		objects[i] = lfsBatchObject{OID: p.OID, Size: p.Size}
+		pointers[p.OID] = p
+	}
+
+	body, err := json.Marshal(&lfsBatchRequest{
+		Operation: "download",
+		Transfers: []string{"basic"},
+		Objects:   objects,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		lfsEndpoint(repo)+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	r.setAuth(ctx, repo, req)
+
+	var batch lfsBatchResponse
+	if err := r.do(ctx, req, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&batch)
+	}); err != nil {
+		return err
+	}
+
+	for _, obj := range batch.Objects {
+		p, ok := pointers[obj.OID]
+		if !ok {
+			continue
+		}
+
+		content, err := r.download(ctx, p, obj)
+		if err != nil {
+			ctxlog.Get(ctx).With(log.Fields{
+				"oid": p.OID,
+				"err": err,
+			}).Warningf("lfs object not fetched")
+			continue
+		}
+
+		contents[p.OID] = content
+	}
+
+	return nil
+}
+
+// download fetches the object of p with the download action of obj
+func (r *HTTPLFSResolver) download(ctx context.Context,
+	p *lookout.LFSPointer, obj lfsBatchObject) ([]byte, error) {
+
+	if obj.Error != nil {
+		return nil, fmt.Errorf("lfs object %s: %s", p.OID, obj.Error.Message)
+	}
+
+	if obj.Actions == nil || obj.Actions.Download == nil {
+		return nil, fmt.Errorf("lfs object %s can not be downloaded", p.OID)
+	}
+
+	download := obj.Actions.Download
+	req, err := http.NewRequest(http.MethodGet, download.Href, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the server sends the credentials of the download if they are needed
+	for k, v := range download.Header {
+		req.Header.Set(k, v)
+	}
+
+	var content []byte
+	err = r.do(ctx, req, func(body io.Reader) (err error) {
+		content, err = readLFSObject(body, p)
+		return err
+	})
+
+	return content, err
+}
+
+func (r *HTTPLFSResolver) do(ctx context.Context, req *http.Request,
+	read func(io.Reader) error) (err error) {
+
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	defer gitioutil.CheckClose(resp.Body, &err)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("lfs request %s %s failed: %s", req.Method, req.URL, resp.Status)
+	}
+
+	return read(resp.Body)
+}
+
+// setAuth sets the credentials of the git fetches of repo to req, if they are
+// HTTP credentials
+func (r *HTTPLFSResolver) setAuth(ctx context.Context,
+	repo *lookout.RepositoryInfo, req *http.Request) {
+	if r.auth == nil {
+		return
+	}
+
+	switch auth := r.auth.GitAuth(ctx, repo).(type) {
+	case *githttp.BasicAuth:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case *githttp.TokenAuth:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	}
+}
+
+// lfsEndpoint returns the default Git LFS endpoint of repo
+func lfsEndpoint(repo *lookout.RepositoryInfo) string {
+	url := strings.TrimSuffix(repo.CloneURL, "/")
+	if !strings.HasSuffix(url, ".git") {
+		url += ".git"
+	}
+
+	return url + "/info/lfs"
+}
+
+// DirLFSResolver reads the objects from a local directory with the layout of
+// the lfs/objects directory of a git repository, <oid[0:2]>/<oid[2:4]>/<oid>,
+// whatever their repository is. It is a stand-in of a Git LFS server for tests
+// or for setups where the objects are replicated locally.
+type DirLFSResolver struct {
+	fs billy.Filesystem
+}
+
+var _ LFSResolver = &DirLFSResolver{}
+
+// NewDirLFSResolver creates a new DirLFSResolver for the objects in fs
+func NewDirLFSResolver(fs billy.Filesystem) *DirLFSResolver {
+	return &DirLFSResolver{fs: fs}
+}
+
+// Resolve implements LFSResolver
+func (r *DirLFSResolver) Resolve(ctx context.Context,
+	repo *lookout.RepositoryInfo, ps []*lookout.LFSPointer) (map[string][]byte, error) {
+
+	contents := make(map[string][]byte, len(ps))
+	for _, p := range ps {
+		content, err := r.read(p)
+		if err != nil {
+			ctxlog.Get(ctx).With(log.Fields{
+				"oid": p.OID,
+				"err": err,
+			}).Warningf("lfs object not fetched")
+			continue
+		}
+
+		contents[p.OID] = content
+	}
+
+	return contents, nil
+}
+
+func (r *DirLFSResolver) read(p *lookout.LFSPointer) (content []byte, err error) {
+	f, err := r.fs.Open(path.Join(p.OID[0:2], p.OID[2:4], p.OID))
+	if err != nil {
+		return nil, err
+	}
+
+	defer gitioutil.CheckClose(f, &err)
+	return readLFSObject(f, p)
+}
+
+// readLFSObject reads the object of p from r, checking its size and hash
+func readLFSObject(r io.Reader, p *lookout.LFSPointer) ([]byte, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(r, p.Size+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(content)) != p.Size {
+		return nil, fmt.Errorf("lfs object %s has not size %d", p.OID, p.Size)
+	}
+
+	hash := sha256.Sum256(content)
+	if hex.EncodeToString(hash[:]) != p.OID {
+		return nil, fmt.Errorf("lfs object %s has a different hash", p.OID)
+	}
+
+	return content, nil
+}
+
+// lfsBatchSize is the number of files or changes read ahead by the Git LFS
+// scanners to fetch the objects of their pointers together
+const lfsBatchSize = 100
+
+// lfsFile is a Git LFS pointer of a file read ahead
+type lfsFile struct {
+	repo *lookout.RepositoryInfo
+	file *lookout.File
+	p    *lookout.LFSPointer
+}
+
+// lfsFetcher replaces the Git LFS pointers of the files read ahead with the
+// content of their objects. The reasons of the files whose object was not
+// fetched, and the ones returned by the underlying scanner, are kept in
+// skipped.
+type lfsFetcher struct {
+	ctx      context.Context
+	resolver LFSResolver
+	limits   lookout.ContentLimits
+	files    []lfsFile
+	skipped  contentSkips
+}
+
+func newLFSFetcher(ctx context.Context, resolver LFSResolver, limits lookout.ContentLimits) *lfsFetcher {
+	return &lfsFetcher{
+		ctx:      ctx,
+		resolver: resolver,
+		limits:   limits,
+		skipped:  make(contentSkips),
+	}
+}
+
+// reset forgets the files of the previous batch
+func (l *lfsFetcher) reset() {
+	l.files = nil
+	l.skipped.reset()
+}
+
+// add adds f, a file of the repository repo, to the batch if it is a Git LFS
+// pointer within the limits. reason is why the underlying scanner did not
+// load its content, if it did not.
+func (l *lfsFetcher) add(repo *lookout.RepositoryInfo, f *lookout.File, reason string) {
+	if f == nil {
+		return
+	}
+
+	if reason != "" {
+		l.skipped[f] = reason
+		return
+	}
+
+	if repo == nil {
+		return
+	}
+
+	p, ok := lookout.ParseLFSPointer(f.Content)
+	if !ok {
+		return
+	}
+
+	if l.limits.MaxSize > 0 && p.Size > l.limits.MaxSize {
+		l.skip(f, lookout.SkipTooBig)
+		return
+	}
+
+	l.files = append(l.files, lfsFile{repo: repo, file: f, p: p})
+}
+
+// skip removes the pointer of f, whose object is not fetched for reason
+func (l *lfsFetcher) skip(f *lookout.File, reason string) {
+	f.Content = nil
+	l.skipped[f] = reason
+}
+
+// fetch replaces the pointers of the batch with the content of their objects,
+// with a single call to the resolver for each repository
+func (l *lfsFetcher) fetch() {
+	var repos []*lookout.RepositoryInfo
+	pointers := make(map[string][]*lookout.LFSPointer)
+	for _, lf := range l.files {
+		url := lf.repo.CloneURL
+		if _, ok := pointers[url]; !ok {
+			repos = append(repos, lf.repo)
+		}
+
+		if !containsPointer(pointers[url], lf.p) {
+			pointers[url] = append(pointers[url], lf.p)
+		}
+	}
+
+	contents := make(map[string]map[string][]byte, len(repos))
+	for _, repo := range repos {
+		ps := pointers[repo.CloneURL]
+		objs, err := l.resolver.Resolve(l.ctx, repo, ps)
+		if err != nil {
+			ctxlog.Get(l.ctx).With(log.Fields{
+				"repository": repo.CloneURL,
+				"objects":    len(ps),
+				"err":        err,
+			}).Warningf("lfs objects not fetched")
+		}
+
+		contents[repo.CloneURL] = objs
+	}
+
+	for _, lf := range l.files {
+		content, ok := contents[lf.repo.CloneURL][lf.p.OID]
+		if !ok {
+			l.skip(lf.file, lookout.SkipLFSUnavailable)
+			continue
+		}
+
+		if l.limits.ExcludeBinary {
+			isBinary, err := binary.IsBinary(bytes.NewReader(content))
+			if err != nil || isBinary {
+				l.skip(lf.file, lookout.SkipBinary)
+				continue
+			}
+		}
+
+		lf.file.Content = content
+	}
+}
+
+func containsPointer(ps []*lookout.LFSPointer, p *lookout.LFSPointer) bool {
+	for _, other := range ps {
+		if other.OID == p.OID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FileLFSScanner is a FileScanner that replaces the Git LFS pointers with the
+// content of their objects, if they are within limits, and returns why the
+// objects were not fetched. The files are read ahead in batches, so the
+// objects of each batch are fetched together.
+type FileLFSScanner struct {
+	scanner lookout.FileScanner
+	fetcher *lfsFetcher
+	repo    *lookout.RepositoryInfo
+	files   []*lookout.File
+	val     *lookout.File
+	done    bool
+}
+
+var _ lookout.SkipScanner = &FileLFSScanner{}
+
+// NewFileLFSScanner creates new FileLFSScanner for the files of the
+// repository repo. It must wrap a scanner that adds the contents of the files.
+func NewFileLFSScanner(ctx context.Context, scanner lookout.FileScanner,
+	resolver LFSResolver, limits lookout.ContentLimits, repo *lookout.RepositoryInfo) *FileLFSScanner {
+	return &FileLFSScanner{
+		scanner: scanner,
+		fetcher: newLFSFetcher(ctx, resolver, limits),
+		repo:    repo,
+	}
+}
+
+func (s *FileLFSScanner) Next() bool {
+	if len(s.files) == 0 && !s.done {
+		s.fetcher.reset()
+		for len(s.files) < lfsBatchSize {
+			if !s.scanner.Next() {
+				s.done = true
+				break
+			}
+
+			f := s.scanner.File()
+			s.fetcher.add(s.repo, f, lookout.ScannerSkipReason(s.scanner, f))
+			s.files = append(s.files, f)
+		}
+
+		s.fetcher.fetch()
+	}
+
+	if len(s.files) == 0 {
+		s.val = nil
+		return false
+	}
+
+	s.val, s.files = s.files[0], s.files[1:]
+	return true
+}
+
+func (s *FileLFSScanner) Err() error {
+	return s.scanner.Err()
+}
+
+func (s *FileLFSScanner) File() *lookout.File {
+	return s.val
+}
+
+func (s *FileLFSScanner) SkipReason(f *lookout.File) string {
+	return s.fetcher.skipped[f]
+}
+
+func (s *FileLFSScanner) Close() error {
+	return s.scanner.Close()
+}
+
+// ChangeLFSScanner is a ChangeScanner that replaces the Git LFS pointers with
+// the content of their objects, if they are within limits, and returns why the
+// objects were not fetched. The changes are read ahead in batches, so the
+// objects of each batch are fetched together.
+type ChangeLFSScanner struct {
+	scanner            lookout.ChangeScanner
+	fetcher            *lfsFetcher
+	baseRepo, headRepo *lookout.RepositoryInfo
+	changes            []*lookout.Change
+	// hunks of the changes read ahead, returned by the underlying scanner
+	hunks map[*lookout.Change][]*lookout.Hunk
+	val   *lookout.Change
+	done  bool
+}
+
+var _ lookout.SkipScanner = &ChangeLFSScanner{}
+var _ lookout.HunkScanner = &ChangeLFSScanner{}
+
+// NewChangeLFSScanner creates new ChangeLFSScanner. The objects of the base
+// files are fetched from baseRepo, and the ones of the head files from
+// headRepo. It must wrap a scanner that adds the contents of the files.
+func NewChangeLFSScanner(ctx context.Context, scanner lookout.ChangeScanner,
+	resolver LFSResolver, limits lookout.ContentLimits, baseRepo, headRepo *lookout.RepositoryInfo) *ChangeLFSScanner {
+	return &ChangeLFSScanner{
+		scanner:  scanner,
+		fetcher:  newLFSFetcher(ctx, resolver, limits),
+		baseRepo: baseRepo,
+		headRepo: headRepo,
+		hunks:    make(map[*lookout.Change][]*lookout.Hunk),
+	}
+}
+
+func (s *ChangeLFSScanner) Next() bool {
+	if len(s.changes) == 0 && !s.done {
+		s.fetcher.reset()
+		for ch := range s.hunks {
+			delete(s.hunks, ch)
+		}
+
+		for len(s.changes) < lfsBatchSize {
+			if !s.scanner.Next() {
+				s.done = true
+				break
+			}
+
+			ch := s.scanner.Change()
+			s.fetcher.add(s.baseRepo, ch.Base, lookout.ScannerSkipReason(s.scanner, ch.Base))
+			s.fetcher.add(s.headRepo, ch.Head, lookout.ScannerSkipReason(s.scanner, ch.Head))
+			if hunks := lookout.ScannerHunks(s.scanner); hunks != nil {
+				s.hunks[ch] = hunks
+			}
+
+			s.changes = append(s.changes, ch)
+		}
+
+		s.fetcher.fetch()
+	}
+
+	if len(s.changes) == 0 {
+		s.val = nil
+		return false
+	}
+
+	s.val, s.changes = s.changes[0], s.changes[1:]
+	return true
+}
+
+func (s *ChangeLFSScanner) Err() error {
+	return s.scanner.Err()
+}
+
+func (s *ChangeLFSScanner) Change() *lookout.Change {
+	return s.val
+}
+
+func (s *ChangeLFSScanner) Hunks() []*lookout.Hunk {
+	return s.hunks[s.val]
+}
+
+func (s *ChangeLFSScanner) SkipReason(f *lookout.File) string {
+	return s.fetcher.skipped[f]
+}
+
+func (s *ChangeLFSScanner) Close() error {
+	return s.scanner.Close()
+}
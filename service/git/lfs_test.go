@@ -0,0 +1,312 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/meyskens/lookout"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// lfsPointer returns the pointer of an object with the given content
+func lfsPointer(content string) *lookout.LFSPointer {
+	hash := sha256.Sum256([]byte(content))
+	return &lookout.LFSPointer{
+		OID:  hex.EncodeToString(hash[:]),
+		Size: int64(len(content)),
+	}
+}
+
+// lfsObjects returns a DirLFSResolver with the objects of contents
+func lfsObjects(t *testing.T, contents ...string) *DirLFSResolver {
+	t.Helper()
+
+	fs := memfs.New()
+	for _, content := range contents {
+		oid := lfsPointer(content).OID
+		require.NoError(t, util.WriteFile(fs,
+			path.Join(oid[0:2], oid[2:4], oid), []byte(content), 0644))
+	}
+
+	return NewDirLFSResolver(fs)
+}
+
+var lfsRepo = &lookout.RepositoryInfo{CloneURL: "https://github.com/foo/bar"}
+
+func TestDirLFSResolver(t *testing.T) {
+	require := require.New(t)
+
+	r := lfsObjects(t, "image\n", "other\n")
+	ctx := context.Background()
+
+	// the object of the last pointer does not match it
+	mismatch := lfsPointer("other\n")
+	mismatch.Size++
+
+	contents, err := r.Resolve(ctx, lfsRepo, []*lookout.LFSPointer{
+		lfsPointer("image\n"),
+		lfsPointer("missing\n"),
+		mismatch,
+	})
+	require.NoError(err)
+	require.Equal(map[string][]byte{
+		lfsPointer("image\n").OID: []byte("image\n"),
+	}, contents)
+}
+
+// countingLFSResolver counts the calls to the underlying resolver
+type countingLFSResolver struct {
+	LFSResolver
+	calls    int
+	pointers int
+}
+
+func (r *countingLFSResolver) Resolve(ctx context.Context,
+	repo *lookout.RepositoryInfo, ps []*lookout.LFSPointer) (map[string][]byte, error) {
+	r.calls++
+	r.pointers += len(ps)
+	return r.LFSResolver.Resolve(ctx, repo, ps)
+}
+
+type lfsAuthProvider struct{}
+
+func (lfsAuthProvider) GitAuth(ctx context.Context, repoInfo *lookout.RepositoryInfo) transport.AuthMethod {
+	return &githttp.BasicAuth{Username: "user", Password: "secret"}
+}
+
+func TestHTTPLFSResolver(t *testing.T) {
+	require := require.New(t)
+
+	content := "image\n"
+	p := lfsPointer(content)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var batches []int
+	mux.HandleFunc("/foo/bar.git/info/lfs/objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req lfsBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+			req.Operation != "download" || len(req.Objects) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		batches = append(batches, len(req.Objects))
+
+		var objects []interface{}
+		for _, obj := range req.Objects {
+			if obj.OID != p.OID {
+				objects = append(objects, map[string]interface{}{
+					"oid":   obj.OID,
+					"size":  obj.Size,
+					"error": map[string]interface{}{"code": 404, "message": "not found"},
+				})
+				continue
+			}
+
+			objects = append(objects, map[string]interface{}{
+				"oid":  obj.OID,
+				"size": obj.Size,
+				"actions": map[string]interface{}{"download": map[string]interface{}{
+					"href":   srv.URL + "/objects/" + obj.OID,
+					"header": map[string]string{"Authorization": "token download"},
+				}},
+			})
+		}
+
+		w.Header().Set("Content-Type", lfsMediaType)
+		json.NewEncoder(w).Encode(map[string]interface{}{"objects": objects})
+	})
+
+	mux.HandleFunc("/objects/"+p.OID, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token download" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(content))
+	})
+
+	ctx := context.Background()
+	repo := &lookout.RepositoryInfo{CloneURL: srv.URL + "/foo/bar"}
+
+	r := NewHTTPLFSResolver(srv.Client(), lfsAuthProvider{})
+	contents, err := r.Resolve(ctx, repo, []*lookout.LFSPointer{p, lfsPointer("other\n")})
+	require.NoError(err)
+	require.Equal(map[string][]byte{p.OID: []byte(content)}, contents)
+	require.Equal([]int{2}, batches)
+
+	// the batch requests have up to lfsMaxBatchObjects objects
+	batches = nil
+	ps := []*lookout.LFSPointer{p}
+	for i := 0; i < lfsMaxBatchObjects; i++ {
+		ps = append(ps, lfsPointer(fmt.Sprintf("other %d\n", i)))
+	}
+
+	contents, err = r.Resolve(ctx, repo, ps)
+	require.NoError(err)
+	require.Len(contents, 1)
+	require.Equal([]int{lfsMaxBatchObjects, 1}, batches)
+
+	// without credentials
+	r = NewHTTPLFSResolver(srv.Client(), nil)
+	_, err = r.Resolve(ctx, repo, []*lookout.LFSPointer{p})
+	require.Error(err)
+}
+
+func TestFileLFSScanner(t *testing.T) {
+	require := require.New(t)
+
+	big := lines(100)
+	tree := buildTree(t, memory.NewStorage(), map[string]string{
+		"a.go":     "package a\n",
+		"big.go":   big,
+		"big.bin":  lfsPointer(big).String(),
+		"img.png":  lfsPointer("image\n").String(),
+		"copy.png": lfsPointer("image\n").String(),
+		"data.bin": lfsPointer("\x00binary\n").String(),
+		"missing":  lfsPointer("missing\n").String(),
+	})
+
+	ctx := context.Background()
+	resolver := &countingLFSResolver{
+		LFSResolver: lfsObjects(t, "image\n", "\x00binary\n", big),
+	}
+
+	// the pointers are smaller than the limit of the contents of the files
+	var scanner lookout.FileScanner = NewTreeScanner(tree)
+	scanner = NewFileContentScanner(ctx, scanner, tree, lookout.ContentLimits{MaxSize: 200})
+	scanner = NewFileLFSScanner(ctx, scanner, resolver,
+		lookout.ContentLimits{MaxSize: 100, ExcludeBinary: true}, lfsRepo)
+
+	files := make(map[string]*lookout.File)
+	reasons := make(map[string]string)
+	for scanner.Next() {
+		f := scanner.File()
+		files[f.Path] = f
+		reasons[f.Path] = lookout.ScannerSkipReason(scanner, f)
+	}
+
+	require.NoError(scanner.Err())
+	require.Len(files, 7)
+
+	require.Equal("package a\n", string(files["a.go"].Content))
+	require.Equal("image\n", string(files["img.png"].Content))
+	require.Equal("image\n", string(files["copy.png"].Content))
+
+	// the objects are fetched in a single batch, without duplicates
+	require.Equal(1, resolver.calls)
+	require.Equal(3, resolver.pointers)
+
+	// the pointers of the objects that are not fetched are removed
+	require.Nil(files["big.bin"].Content)
+	require.Nil(files["data.bin"].Content)
+	require.Nil(files["missing"].Content)
+	require.Equal(map[string]string{
+		"a.go":     "",
+		"big.go":   lookout.SkipTooBig,
+		"img.png":  "",
+		"copy.png": "",
+		"big.bin":  lookout.SkipTooBig,
+		"data.bin": lookout.SkipBinary,
+		"missing":  lookout.SkipLFSUnavailable,
+	}, reasons)
+}
+
+func TestFileLFSScannerBatches(t *testing.T) {
+	require := require.New(t)
+
+	files := make(map[string]string)
+	var contents []string
+	for i := 0; i < lfsBatchSize+1; i++ {
+		content := fmt.Sprintf("image %d\n", i)
+		files[fmt.Sprintf("%03d.png", i)] = lfsPointer(content).String()
+		contents = append(contents, content)
+	}
+
+	tree := buildTree(t, memory.NewStorage(), files)
+
+	ctx := context.Background()
+	resolver := &countingLFSResolver{LFSResolver: lfsObjects(t, contents...)}
+
+	var scanner lookout.FileScanner = NewTreeScanner(tree)
+	scanner = NewFileBlobScanner(ctx, scanner, tree)
+	scanner = NewFileLFSScanner(ctx, scanner, resolver, lookout.ContentLimits{}, lfsRepo)
+
+	var n int
+	for scanner.Next() {
+		require.Equal(contents[n], string(scanner.File().Content))
+		n++
+	}
+
+	require.NoError(scanner.Err())
+	require.Equal(lfsBatchSize+1, n)
+	require.Equal(2, resolver.calls)
+}
+
+func TestChangeLFSScanner(t *testing.T) {
+	require := require.New(t)
+
+	sto := memory.NewStorage()
+	base := buildTree(t, sto, map[string]string{
+		"img.png":  lfsPointer("base\n").String(),
+		"gone.png": lfsPointer("gone\n").String(),
+	})
+	head := buildTree(t, sto, map[string]string{
+		"img.png":  lfsPointer("head\n").String(),
+		"gone.png": lfsPointer("missing\n").String(),
+	})
+
+	ctx := context.Background()
+	resolver := &countingLFSResolver{
+		LFSResolver: lfsObjects(t, "base\n", "head\n", "gone\n"),
+	}
+
+	var scanner lookout.ChangeScanner = NewDiffTreeScanner(base, head)
+	scanner = NewChangeBlobScanner(ctx, scanner, base, head)
+	scanner = NewChangeLFSScanner(ctx, scanner, resolver,
+		lookout.ContentLimits{}, lfsRepo, lfsRepo)
+
+	changes := make(map[string]*lookout.Change)
+	for scanner.Next() {
+		ch := scanner.Change()
+		changes[ch.Head.Path] = ch
+
+		if ch.Head.Path == "gone.png" {
+			require.Equal("", lookout.ScannerSkipReason(scanner, ch.Base))
+			require.Equal(lookout.SkipLFSUnavailable, lookout.ScannerSkipReason(scanner, ch.Head))
+		}
+	}
+
+	require.NoError(scanner.Err())
+	require.Len(changes, 2)
+
+	require.Equal("base\n", string(changes["img.png"].Base.Content))
+	require.Equal("head\n", string(changes["img.png"].Head.Content))
+	require.Equal("gone\n", string(changes["gone.png"].Base.Content))
+	require.Nil(changes["gone.png"].Head.Content)
+
+	// the base and head objects of the same repository are fetched together
+	require.Equal(1, resolver.calls)
+	require.Equal(4, resolver.pointers)
+}
@@ -1,10 +1,15 @@
 package git
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/meyskens/lookout"
 	"github.com/meyskens/lookout/util/ctxlog"
@@ -31,15 +36,42 @@ type ReposCollectionHandler interface {
 	Get(context.Context, *lookout.RepositoryInfo) (*git.Repository, error)
 }
 
+// LibraryOptions holds the configuration of a Library
+type LibraryOptions struct {
+	// ObjectCacheSize is the size of the object cache shared by all the
+	// repositories. Zero means cache.DefaultMaxSize.
+	ObjectCacheSize cache.FileSize
+}
+
 // Library controls the persistence of multiple git repositories.
 type Library struct {
 	m  sync.Mutex
 	fs billy.Filesystem
+	// cache is shared by the storers of all the repositories, the objects
+	// are only read from it once they are found in the repository
+	cache cache.Object
+
+	// locks holds a mutex per repository path, held while the repository
+	// is fetched or cleaned up
+	locks sync.Map
+	// used holds the last time each repository path was opened
+	used sync.Map
 }
 
 // NewLibrary creates a new Library based on the given filesystem.
 func NewLibrary(fs billy.Filesystem) *Library {
-	return &Library{fs: fs}
+	return NewLibraryWithOptions(fs, LibraryOptions{})
+}
+
+// NewLibraryWithOptions creates a new Library based on the given filesystem
+// and options.
+func NewLibraryWithOptions(fs billy.Filesystem, opts LibraryOptions) *Library {
+	size := opts.ObjectCacheSize
+	if size == 0 {
+		size = cache.DefaultMaxSize
+	}
+
+	return &Library{fs: fs, cache: cache.NewObjectLRU(size)}
 }
 
 // GetOrInit get the requested repository based on the given URL, or inits a
@@ -61,6 +93,8 @@ func (l *Library) GetOrInit(ctx context.Context, url *lookout.RepositoryInfo) (
 // Init inits a new repository for the given URL.
 func (l *Library) Init(ctx context.Context, url *lookout.RepositoryInfo) (*git.Repository, error) {
 	ctxlog.Get(ctx).Infof("creating local repository for: %s", url.CloneURL)
+	l.touch(l.repositoryPath(url))
+
 	l.m.Lock()
 	defer l.m.Unlock()
 
@@ -115,6 +149,7 @@ func (l *Library) Has(url *lookout.RepositoryInfo) (bool, error) {
 
 // Get get the requested repository based on the given URL.
 func (l *Library) Get(ctx context.Context, url *lookout.RepositoryInfo) (*git.Repository, error) {
+	l.touch(l.repositoryPath(url))
 	r, err := l.get(url)
 
 	// it can happen if the repository in a broken state
@@ -145,20 +180,174 @@ func (l *Library) get(url *lookout.RepositoryInfo) (*git.Repository, error) {
 
 func (l *Library) repositoryStorer(url *lookout.RepositoryInfo) (
 	storage.Storer, error) {
-	fs, err := l.fs.Chroot(l.repositoryPath(url))
+	return l.pathStorer(l.repositoryPath(url))
+}
+
+func (l *Library) pathStorer(path string) (storage.Storer, error) {
+	fs, err := l.fs.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return filesystem.NewStorage(fs, l.cache), nil
+}
+
+// ShareObjects makes the repository fork read the objects it does not have
+// from the repository parent, using git alternates. The references of parent
+// are also sent as known to the remote when fork is fetched, so the objects
+// they share are not downloaded again. Both repositories must exist, and the
+// library must be on the local filesystem, go-git reads the alternates from
+// it.
+func (l *Library) ShareObjects(fork, parent *lookout.RepositoryInfo) error {
+	for _, url := range []*lookout.RepositoryInfo{fork, parent} {
+		has, err := l.Has(url)
+		if err != nil {
+			return err
+		}
+
+		if !has {
+			return ErrRepositoryNotExists.New(url.CloneURL)
+		}
+	}
+
+	forkPath, parentPath := l.repositoryPath(fork), l.repositoryPath(parent)
+	objects := filepath.Join(l.fs.Root(), filepath.FromSlash(parentPath), "objects")
+	if _, err := os.Stat(objects); err != nil {
+		return fmt.Errorf("the objects of %s are not on the local filesystem: %s",
+			parent.CloneURL, err)
+	}
+
+	unlock := l.lockPath(forkPath)
+	defer unlock()
+
+	paths, err := l.alternates(forkPath)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		if pathKey(p) == pathKey(parentPath) {
+			return nil
+		}
+	}
+
+	f, err := l.fs.OpenFile(path.Join(forkPath, alternatesFile),
+		os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(f, objects); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// alternatesFile is the path of the alternates file in a repository
+const alternatesFile = "objects/info/alternates"
+
+// alternates returns the paths of the library repositories whose objects
+// are shared with the repository at path. The alternates outside of the
+// library are ignored.
+func (l *Library) alternates(repoPath string) ([]string, error) {
+	f, err := l.fs.Open(path.Join(repoPath, alternatesFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
+
+	root := filepath.Clean(l.fs.Root()) + string(filepath.Separator)
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		objects := filepath.Clean(scanner.Text())
+		if !strings.HasPrefix(objects, root) || filepath.Base(objects) != "objects" {
+			continue
+		}
+
+		rel := filepath.ToSlash(strings.TrimPrefix(filepath.Dir(objects), root))
+		paths = append(paths, rel)
+	}
+
+	return paths, scanner.Err()
+}
+
+// alternateStorers returns the storers of the library repositories whose
+// objects are shared with the repository of url
+func (l *Library) alternateStorers(url *lookout.RepositoryInfo) ([]storage.Storer, error) {
+	paths, err := l.alternates(l.repositoryPath(url))
+	if err != nil {
+		return nil, err
+	}
+
+	var storers []storage.Storer
+	for _, p := range paths {
+		if _, err := l.fs.Stat(p); os.IsNotExist(err) {
+			continue
+		}
+
+		s, err := l.pathStorer(p)
+		if err != nil {
+			return nil, err
+		}
+
+		storers = append(storers, s)
+	}
 
-	// TODO(carlosms) take cache size from config
-	cache := cache.NewObjectLRU(cache.DefaultMaxSize)
-	return filesystem.NewStorage(fs, cache), nil
+	return storers, nil
 }
 
 func (l *Library) repositoryPath(url *lookout.RepositoryInfo) string {
 	return fmt.Sprintf("%s/%s", url.Host, url.FullName)
 }
 
+// pathKey returns the key of the repository path in locks and used
+func pathKey(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// lock locks the repository of url for exclusive use, and returns the
+// function to unlock it
+func (l *Library) lock(url *lookout.RepositoryInfo) func() {
+	return l.lockPath(l.repositoryPath(url))
+}
+
+func (l *Library) lockPath(path string) func() {
+	mi, _ := l.locks.LoadOrStore(pathKey(path), &sync.Mutex{})
+	mutex := mi.(*sync.Mutex)
+	mutex.Lock()
+
+	return mutex.Unlock
+}
+
+// touch records that the repository at path is being used
+func (l *Library) touch(path string) {
+	l.used.Store(pathKey(path), time.Now())
+}
+
+// lastUsed returns the last time the repository at path was opened, or the
+// modification time of its directory if it was not opened since the library
+// was created
+func (l *Library) lastUsed(path string) (time.Time, error) {
+	if t, ok := l.used.Load(pathKey(path)); ok {
+		return t.(time.Time), nil
+	}
+
+	fi, err := l.fs.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return fi.ModTime(), nil
+}
+
 func (l *Library) recreate(url *lookout.RepositoryInfo) (*git.Repository, error) {
 	l.m.Lock()
 	defer l.m.Unlock()
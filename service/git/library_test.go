@@ -2,10 +2,15 @@ package git
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
 	"testing"
 
+	fixtures "github.com/src-d/go-git-fixtures"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
 )
 
@@ -81,3 +86,56 @@ func TestLibrary_GetOrInit(t *testing.T) {
 	require.NoError(err)
 	require.NotNil(r)
 }
+
+func TestLibrary_ShareObjects(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	dir, err := ioutil.TempDir("", "lookout-library")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	library := NewLibrary(osfs.New(dir))
+	parent, _ := pb.ParseRepositoryInfo("https://github.com/foo/bar")
+	fork, _ := pb.ParseRepositoryInfo("https://github.com/baz/bar")
+
+	require.NoError(copyDir(fixtures.Basic().One().DotGit(), library.fs,
+		"", library.repositoryPath(parent)))
+	r, err := library.Init(context.Background(), fork)
+	require.NoError(err)
+
+	hash := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	_, err = r.CommitObject(hash)
+	require.Equal(plumbing.ErrObjectNotFound, err)
+
+	require.NoError(library.ShareObjects(fork, parent))
+	require.NoError(library.ShareObjects(fork, parent))
+
+	paths, err := library.alternates(library.repositoryPath(fork))
+	require.NoError(err)
+	require.Equal([]string{"github.com/foo/bar"}, paths)
+
+	r, err = library.Get(context.Background(), fork)
+	require.NoError(err)
+	_, err = r.CommitObject(hash)
+	require.NoError(err)
+}
+
+func TestLibrary_ShareObjectsMissing(t *testing.T) {
+	require := require.New(t)
+
+	library := NewLibrary(memfs.New())
+	parent, _ := pb.ParseRepositoryInfo("https://github.com/foo/bar")
+	fork, _ := pb.ParseRepositoryInfo("https://github.com/baz/bar")
+
+	_, err := library.Init(context.Background(), fork)
+	require.NoError(err)
+
+	err = library.ShareObjects(fork, parent)
+	require.True(ErrRepositoryNotExists.Is(err))
+
+	// the objects of a memfs library can't be read by go-git as alternates
+	_, err = library.Init(context.Background(), parent)
+	require.NoError(err)
+	require.Error(library.ShareObjects(fork, parent))
+}
@@ -3,12 +3,16 @@ package git
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/meyskens/lookout"
 
+	billy "gopkg.in/src-d/go-billy.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
 )
 
 type CommitLoader interface {
@@ -36,14 +40,9 @@ func (l *LibraryCommitLoader) LoadCommits(
 		return nil, nil
 	}
 
+	// the commits of other repositories are fetched into the repository of
+	// the first one
 	frp := rps[0]
-	for _, orp := range rps[1:] {
-		if orp.InternalRepositoryURL != frp.InternalRepositoryURL {
-			return nil, fmt.Errorf(
-				"loading commits from multiple repositories is not supported")
-		}
-	}
-
 	if err := l.Syncer.Sync(ctx, rps...); err != nil {
 		return nil, err
 	}
@@ -57,6 +56,140 @@ func (l *LibraryCommitLoader) LoadCommits(
 	return storerCl.LoadCommits(ctx, rps...)
 }
 
+// MirrorCommitLoader loads the commits from the bare repositories of a local
+// mirror, like the ones kept up to date by a gitolite or Gerrit replication.
+// The mirror is never fetched, the commits must already be in it.
+type MirrorCommitLoader struct {
+	fs    billy.Filesystem
+	cache cache.Object
+}
+
+// NewMirrorCommitLoader creates a new MirrorCommitLoader for the mirror in the
+// given filesystem. The repository with full name owner/name is read from
+// owner/name.git, or from owner/name if it does not exist.
+func NewMirrorCommitLoader(fs billy.Filesystem) *MirrorCommitLoader {
+	return &MirrorCommitLoader{
+		fs:    fs,
+		cache: cache.NewObjectLRUDefault(),
+	}
+}
+
+func (l *MirrorCommitLoader) LoadCommits(ctx context.Context,
+	rps ...lookout.ReferencePointer) ([]*object.Commit, error) {
+
+	if len(rps) == 0 {
+		return nil, nil
+	}
+
+	storers := make(map[string]storer.Storer)
+	first, err := l.storer(storers, rps[0])
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]*object.Commit, len(rps))
+	for i, rp := range rps {
+		// the commits of other repositories, like the heads of pull
+		// requests from forks, are also looked up in the first one, the
+		// replication usually keeps them in its refs/pull/*
+		s, err := l.storer(storers, rp)
+		if ErrRepositoryNotExists.Is(err) {
+			s = first
+		} else if err != nil {
+			return nil, err
+		}
+
+		hash := plumbing.NewHash(rp.Hash)
+		commit, err := object.GetCommit(s, hash)
+		if err == plumbing.ErrObjectNotFound && s != first {
+			commit, err = object.GetCommit(first, hash)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		commits[i] = commit
+	}
+
+	return commits, nil
+}
+
+func (l *MirrorCommitLoader) storer(storers map[string]storer.Storer,
+	rp lookout.ReferencePointer) (storer.Storer, error) {
+	info := rp.Repository()
+	if info == nil {
+		return nil, fmt.Errorf("invalid repository URL %q", rp.InternalRepositoryURL)
+	}
+
+	if s, ok := storers[info.CloneURL]; ok {
+		return s, nil
+	}
+
+	path, err := l.repositoryPath(info)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := l.fs.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := filesystem.NewStorage(fs, l.cache)
+	storers[info.CloneURL] = s
+	return s, nil
+}
+
+func (l *MirrorCommitLoader) repositoryPath(info *lookout.RepositoryInfo) (
+	string, error) {
+	for _, path := range []string{info.FullName + ".git", info.FullName} {
+		_, err := l.fs.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	return "", ErrRepositoryNotExists.New(info.CloneURL)
+}
+
+// HostCommitLoader loads the commits with the CommitLoader of the host of the
+// repository of the first ReferencePointer, or with the default one if the
+// host has none.
+type HostCommitLoader struct {
+	Default CommitLoader
+	Hosts   map[string]CommitLoader
+}
+
+// NewHostCommitLoader creates a new HostCommitLoader with the default
+// CommitLoader def and the CommitLoaders of each host.
+func NewHostCommitLoader(def CommitLoader, hosts map[string]CommitLoader) *HostCommitLoader {
+	return &HostCommitLoader{
+		Default: def,
+		Hosts:   hosts,
+	}
+}
+
+func (l *HostCommitLoader) LoadCommits(ctx context.Context,
+	rps ...lookout.ReferencePointer) ([]*object.Commit, error) {
+
+	if len(rps) == 0 {
+		return nil, nil
+	}
+
+	if info := rps[0].Repository(); info != nil {
+		if cl, ok := l.Hosts[info.Host]; ok {
+			return cl.LoadCommits(ctx, rps...)
+		}
+	}
+
+	return l.Default.LoadCommits(ctx, rps...)
+}
+
 type StorerCommitLoader struct {
 	Storer storer.Storer
 }
@@ -7,9 +7,13 @@ import (
 
 	"github.com/meyskens/lookout"
 
+	fixtures "github.com/src-d/go-git-fixtures"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/src-d/go-billy.v4/memfs"
 	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
@@ -98,15 +102,26 @@ type LibraryCommitLoaderTestSuite struct {
 	suite.Suite
 }
 
-func (s *LibraryCommitLoaderTestSuite) TestErrorOnMultiRepos() {
+func (s *LibraryCommitLoaderTestSuite) TestMultiRepos() {
 	require := s.Require()
 
-	cl := NewLibraryCommitLoader(&Library{}, &LibrarySyncer{})
+	ctx := context.TODO()
+
+	// the commits are synced together and loaded from the first repository
+	ms := new(MockSyncer)
+	ml := new(MockLibrary)
+	ms.On("Sync", ctx, rpsDifferentRepos).Return(nil)
+	ml.On("GetOrInit", ctx, rpsDifferentRepos[0].Repository()).Return(
+		nil, errors.New("get or init mock error"))
+
+	cl := NewLibraryCommitLoader(ml, ms)
 
-	commits, err := cl.LoadCommits(context.TODO(), rpsDifferentRepos...)
+	commits, err := cl.LoadCommits(ctx, rpsDifferentRepos...)
 
 	require.Nil(commits)
-	require.Errorf(err, "loading commits from multiple repositories is not supported")
+	require.EqualError(err, "get or init mock error")
+	ms.AssertExpectations(s.T())
+	ml.AssertExpectations(s.T())
 }
 
 func (s *LibraryCommitLoaderTestSuite) TestErrorOnSync() {
@@ -163,3 +178,110 @@ func (s *LibraryCommitLoaderTestSuite) TestEmpty() {
 func TestLibraryCommitLoaderTestSuite(t *testing.T) {
 	suite.Run(t, new(LibraryCommitLoaderTestSuite))
 }
+
+type MirrorCommitLoaderTestSuite struct {
+	suite.Suite
+}
+
+func TestMirrorCommitLoaderTestSuite(t *testing.T) {
+	suite.Run(t, new(MirrorCommitLoaderTestSuite))
+}
+
+func (s *MirrorCommitLoaderTestSuite) TearDownSuite() {
+	s.Require().NoError(fixtures.Clean())
+}
+
+// loader returns a MirrorCommitLoader with the basic fixture mirrored at path
+func (s *MirrorCommitLoaderTestSuite) loader(path string) *MirrorCommitLoader {
+	fs := memfs.New()
+	s.Require().NoError(copyDir(fixtures.Basic().One().DotGit(), fs, "", path))
+	return NewMirrorCommitLoader(fs)
+}
+
+func (s *MirrorCommitLoaderTestSuite) TestLoadCommits() {
+	require := s.Require()
+
+	rps := []lookout.ReferencePointer{{
+		InternalRepositoryURL: "https://github.com/foo/bar",
+		ReferenceName:         "refs/heads/master",
+		Hash:                  "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	}, {
+		InternalRepositoryURL: "https://github.com/foo/bar",
+		ReferenceName:         "refs/remotes/origin/branch",
+		Hash:                  "e8d3ffab552895c19b9fcf7aa264d277cde33881",
+	}}
+
+	for _, path := range []string{"foo/bar.git", "foo/bar"} {
+		commits, err := s.loader(path).LoadCommits(context.TODO(), rps...)
+		require.NoError(err, path)
+		require.Len(commits, 2)
+		require.Equal(rps[0].Hash, commits[0].Hash.String())
+		require.Equal(rps[1].Hash, commits[1].Hash.String())
+	}
+}
+
+func (s *MirrorCommitLoaderTestSuite) TestLoadCommitsFork() {
+	require := s.Require()
+
+	// the fork is not mirrored, its commit is read from the first repository
+	rps := []lookout.ReferencePointer{{
+		InternalRepositoryURL: "https://github.com/foo/bar",
+		ReferenceName:         "refs/heads/master",
+		Hash:                  "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	}, {
+		InternalRepositoryURL: "https://github.com/baz/bar",
+		ReferenceName:         "refs/heads/branch",
+		Hash:                  "e8d3ffab552895c19b9fcf7aa264d277cde33881",
+	}}
+
+	commits, err := s.loader("foo/bar.git").LoadCommits(context.TODO(), rps...)
+	require.NoError(err)
+	require.Len(commits, 2)
+	require.Equal(rps[1].Hash, commits[1].Hash.String())
+}
+
+func (s *MirrorCommitLoaderTestSuite) TestLoadCommitsMissing() {
+	require := s.Require()
+
+	cl := s.loader("foo/bar.git")
+
+	_, err := cl.LoadCommits(context.TODO(), lookout.ReferencePointer{
+		InternalRepositoryURL: "https://github.com/foo/baz",
+		ReferenceName:         "refs/heads/master",
+		Hash:                  "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	})
+	require.True(ErrRepositoryNotExists.Is(err))
+
+	_, err = cl.LoadCommits(context.TODO(), lookout.ReferencePointer{
+		InternalRepositoryURL: "https://github.com/foo/bar",
+		ReferenceName:         "refs/heads/master",
+		Hash:                  "0000000000000000000000000000000000000001",
+	})
+	require.Equal(plumbing.ErrObjectNotFound, err)
+}
+
+func TestHostCommitLoader(t *testing.T) {
+	ctx := context.TODO()
+	rpsGithub := []lookout.ReferencePointer{{
+		InternalRepositoryURL: "https://github.com/foo/bar",
+	}}
+	rpsGitlab := []lookout.ReferencePointer{{
+		InternalRepositoryURL: "https://gitlab.com/foo/bar",
+	}}
+
+	def := new(MockCommitLoader)
+	def.On("LoadCommits", ctx, rpsGitlab).Return(nil, errors.New("default"))
+	mirror := new(MockCommitLoader)
+	mirror.On("LoadCommits", ctx, rpsGithub).Return(nil, errors.New("mirror"))
+
+	cl := NewHostCommitLoader(def, map[string]CommitLoader{"github.com": mirror})
+
+	_, err := cl.LoadCommits(ctx, rpsGithub...)
+	require.EqualError(t, err, "mirror")
+
+	_, err = cl.LoadCommits(ctx, rpsGitlab...)
+	require.EqualError(t, err, "default")
+
+	def.AssertExpectations(t)
+	mirror.AssertExpectations(t)
+}
@@ -0,0 +1,419 @@
+package git
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/meyskens/lookout"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/diff"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// maxCachedDiffs is the number of pairs of trees kept by PatchLoader, so the
+// patches of several files of the same diff load the commits only once
+const maxCachedDiffs = 16
+
+// PatchLoader computes the diff between two revisions from the repositories of
+// a CommitLoader. Like the diff of a pull request, it is computed from the
+// merge base of both revisions, not from the base revision itself.
+type PatchLoader struct {
+	Loader CommitLoader
+
+	mutex sync.Mutex
+	// diffs holds the last loaded trees, by base and head hashes, and keys
+	// their order from the oldest to the newest
+	diffs map[string]*treeDiff
+	keys  []string
+}
+
+type treeDiff struct {
+	base, head *object.Tree
+}
+
+// NewPatchLoader creates a new PatchLoader
+func NewPatchLoader(loader CommitLoader) *PatchLoader {
+	return &PatchLoader{
+		Loader: loader,
+		diffs:  make(map[string]*treeDiff),
+	}
+}
+
+// Changes returns the files changed from the merge base of base and head to
+// head, with their path, mode
+// and hash but without content. Renames are not detected, a renamed file is
+// returned as a removed file and an added one.
+func (l *PatchLoader) Changes(ctx context.Context,
+	base, head lookout.ReferencePointer) ([]*lookout.Change, error) {
+
+	d, err := l.load(ctx, base, head, true)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := NewDiffTreeScanner(d.base, d.head)
+	defer scanner.Close()
+
+	var changes []*lookout.Change
+	for scanner.Next() {
+		changes = append(changes, scanner.Change())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// FilePatch returns the hunks of the unified diff of the file from in the
+// merge base of base and head, and the file to in head, see FilePatch
+func (l *PatchLoader) FilePatch(ctx context.Context,
+	base, head lookout.ReferencePointer, from, to string) (string, error) {
+
+	d, err := l.load(ctx, base, head, true)
+	if err != nil {
+		return "", err
+	}
+
+	return FilePatch(ctx, d.base, d.head, from, to)
+}
+
+// RevisionPatch returns the hunks of the unified diff of the file path between
+// the revisions from and to, compared directly instead of from their merge
+// base. It is used to follow the lines of a file when a branch is updated.
+func (l *PatchLoader) RevisionPatch(ctx context.Context,
+	from, to lookout.ReferencePointer, path string) (string, error) {
+
+	d, err := l.load(ctx, from, to, false)
+	if err != nil {
+		return "", err
+	}
+
+	return FilePatch(ctx, d.base, d.head, path, path)
+}
+
+// load returns the trees of head and, if fromMergeBase is true, of the merge
+// base of base and head, or of base otherwise
+func (l *PatchLoader) load(ctx context.Context,
+	base, head lookout.ReferencePointer, fromMergeBase bool) (*treeDiff, error) {
+
+	key := base.Hash + ".." + head.Hash
+	if fromMergeBase {
+		key = base.Hash + "..." + head.Hash
+	}
+
+	l.mutex.Lock()
+	d, ok := l.diffs[key]
+	l.mutex.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	commits, err := l.Loader.LoadCommits(ctx, base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	mb := commits[0]
+	if fromMergeBase {
+		if mb, err = mergeBase(commits[0], commits[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	d = &treeDiff{}
+	if d.base, err = mb.Tree(); err != nil {
+		return nil, err
+	}
+
+	if d.head, err = commits[1].Tree(); err != nil {
+		return nil, err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.diffs[key]; !ok {
+		if len(l.keys) >= maxCachedDiffs {
+			delete(l.diffs, l.keys[0])
+			l.keys = l.keys[1:]
+		}
+
+		l.keys = append(l.keys, key)
+	}
+
+	l.diffs[key] = d
+	return d, nil
+}
+
+// Flags of the commits visited by mergeBase
+const (
+	reachableFromBase = 1 << iota
+	reachableFromHead
+	// stale marks the commits reachable from a common ancestor, they can not
+	// be the merge base
+	stale
+
+	reachableFromBoth = reachableFromBase | reachableFromHead
+)
+
+// mergeBase returns the newest commit reachable from both base and head. The
+// histories are walked from the newest commits to the oldest ones, like git
+// does: the flags are propagated again to the commits already visited when
+// they change, so a wrong committer time does not hide a common ancestor, and
+// the walk goes on until only the ancestors of the common ancestors found are
+// left. Only the commits after the merge base, and a few before it, are
+// visited.
+func mergeBase(base, head *object.Commit) (*object.Commit, error) {
+	if base.Hash == head.Hash {
+		return base, nil
+	}
+
+	flags := map[plumbing.Hash]int{
+		base.Hash: reachableFromBase,
+		head.Hash: reachableFromHead,
+	}
+	queue := &commitQueue{base, head}
+	heap.Init(queue)
+	// queued holds the commits in the queue, their flags are read when they
+	// are popped, so they are not pushed again when the flags change
+	queued := map[plumbing.Hash]bool{base.Hash: true, head.Hash: true}
+
+	var found []*object.Commit
+	for queue.hasNonStale(flags) {
+		c := heap.Pop(queue).(*object.Commit)
+		delete(queued, c.Hash)
+		f := flags[c.Hash]
+		if f&reachableFromBoth == reachableFromBoth {
+			if f&stale == 0 && !containsCommit(found, c) {
+				found = append(found, c)
+			}
+
+			// the ancestors of a common ancestor are not the newest one
+			f |= stale
+		}
+
+		// the parents of the stale commits are only loaded while they can
+		// hide another common ancestor, they may be missing in a shallow
+		// repository
+		if f&stale != 0 && !queue.hasNonStale(flags) {
+			break
+		}
+
+		err := c.Parents().ForEach(func(p *object.Commit) error {
+			pf := flags[p.Hash]
+			if pf&f == f {
+				return nil
+			}
+
+			flags[p.Hash] = pf | f
+			if !queued[p.Hash] {
+				queued[p.Hash] = true
+				heap.Push(queue, p)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// a common ancestor found first can be an ancestor of another one found
+	// later, when the committer times are not in order
+	for _, c := range found {
+		if flags[c.Hash]&stale == 0 {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("commits %s and %s have no common ancestor", base.Hash, head.Hash)
+}
+
+func containsCommit(commits []*object.Commit, c *object.Commit) bool {
+	for _, other := range commits {
+		if other.Hash == c.Hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commitQueue is a heap of commits, the newest one first
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// hasNonStale returns whether any commit of the queue is not stale
+func (q commitQueue) hasNonStale(flags map[plumbing.Hash]int) bool {
+	for _, c := range q {
+		if flags[c.Hash]&stale == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilePatch returns the hunks of the unified diff, with 3 lines of context,
+// between the file from in the base tree and the file to in the head tree.
+// The from and to paths are different for renamed files, and from is empty
+// for added files. The diff headers are not included, like in the patch of the
+// files returned by the GitHub API, and the result is empty for binary files.
+func FilePatch(ctx context.Context,
+	base, head *object.Tree, from, to string) (string, error) {
+
+	fromEntry, err := changeEntry(base, from)
+	if err != nil {
+		return "", err
+	}
+
+	toEntry, err := changeEntry(head, to)
+	if err != nil {
+		return "", err
+	}
+
+	change := &object.Change{From: fromEntry, To: toEntry}
+	patch, err := change.PatchContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := diff.NewUnifiedEncoder(&buf, diff.DefaultContextLines).Encode(patch); err != nil {
+		return "", err
+	}
+
+	s := buf.String()
+	if strings.HasPrefix(s, "@@") {
+		return s, nil
+	}
+
+	i := strings.Index(s, "\n@@")
+	if i < 0 {
+		return "", nil
+	}
+
+	return s[i+1:], nil
+}
+
+// FileHunks returns the ranges of changed lines between the file from in the
+// base tree and the file to in the head tree, like the hunks of FilePatch
+// without context lines. The from or to paths are empty for added or removed
+// files, and the result is nil for binary files and submodules.
+func FileHunks(ctx context.Context,
+	base, head *object.Tree, from, to string) ([]*lookout.Hunk, error) {
+
+	fromEntry, err := changeEntry(base, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toEntry, err := changeEntry(head, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromEntry.TreeEntry.Mode == filemode.Submodule ||
+		toEntry.TreeEntry.Mode == filemode.Submodule {
+		return nil, nil
+	}
+
+	change := &object.Change{From: fromEntry, To: toEntry}
+	patch, err := change.PatchContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hunks := []*lookout.Hunk{}
+	for _, fp := range patch.FilePatches() {
+		if fp.IsBinary() {
+			return nil, nil
+		}
+
+		var hunk *lookout.Hunk
+		var baseLine, headLine int32
+		for _, chunk := range fp.Chunks() {
+			if chunk.Content() == "" {
+				continue
+			}
+
+			lines := int32(strings.Count(chunk.Content(), "\n"))
+			if !strings.HasSuffix(chunk.Content(), "\n") {
+				lines++
+			}
+
+			if chunk.Type() == diff.Equal {
+				hunk = nil
+				baseLine += lines
+				headLine += lines
+				continue
+			}
+
+			if hunk == nil {
+				hunk = &lookout.Hunk{BaseStart: baseLine, HeadStart: headLine}
+				hunks = append(hunks, hunk)
+			}
+
+			if chunk.Type() == diff.Delete {
+				hunk.BaseLines += lines
+				baseLine += lines
+			} else {
+				hunk.HeadLines += lines
+				headLine += lines
+			}
+		}
+	}
+
+	// like in a unified diff, a range with lines starts at its first line,
+	// and an empty one at the line before it
+	for _, h := range hunks {
+		if h.BaseLines > 0 {
+			h.BaseStart++
+		}
+
+		if h.HeadLines > 0 {
+			h.HeadStart++
+		}
+	}
+
+	return hunks, nil
+}
+
+// changeEntry returns the entry of path in tree, or an empty one if path is
+// empty
+func changeEntry(tree *object.Tree, path string) (object.ChangeEntry, error) {
+	if path == "" {
+		return object.ChangeEntry{}, nil
+	}
+
+	entry, err := tree.FindEntry(path)
+	if err != nil {
+		return object.ChangeEntry{}, err
+	}
+
+	return object.ChangeEntry{
+		Name:      path,
+		Tree:      tree,
+		TreeEntry: *entry,
+	}, nil
+}
@@ -0,0 +1,330 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meyskens/lookout"
+
+	fixtures "github.com/src-d/go-git-fixtures"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+type PatchSuite struct {
+	suite.Suite
+	Storer storer.Storer
+}
+
+func TestPatchSuite(t *testing.T) {
+	suite.Run(t, new(PatchSuite))
+}
+
+func (s *PatchSuite) SetupSuite() {
+	fixture := fixtures.Basic().One()
+	s.Storer = filesystem.NewStorage(fixture.DotGit(), cache.NewObjectLRU(cache.DefaultMaxSize))
+}
+
+func (s *PatchSuite) TearDownSuite() {
+	s.Require().NoError(fixtures.Clean())
+}
+
+func (s *PatchSuite) tree(hash string) *object.Tree {
+	s.T().Helper()
+	require := s.Require()
+
+	commit, err := object.GetCommit(s.Storer, plumbing.NewHash(hash))
+	require.NoError(err)
+	tree, err := commit.Tree()
+	require.NoError(err)
+
+	return tree
+}
+
+func (s *PatchSuite) TestFilePatch() {
+	require := s.Require()
+
+	base := s.tree("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	head := s.tree("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+
+	patch, err := FilePatch(context.Background(), base, head, "", "vendor/foo.go")
+	require.NoError(err)
+	require.Equal(`@@ -0,0 +1,7 @@
++package main
++
++import "fmt"
++
++func main() {
++	fmt.Println("Hello, playground")
++}
+`, patch)
+
+	patch, err = FilePatch(context.Background(), base, head, "CHANGELOG", "CHANGELOG")
+	require.NoError(err)
+	require.Equal("", patch)
+
+	_, err = FilePatch(context.Background(), base, head, "", "missing.go")
+	require.Error(err)
+}
+
+func (s *PatchSuite) TestFilePatchRename() {
+	require := s.Require()
+
+	base := s.tree("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	head := s.tree("e8d3ffab552895c19b9fcf7aa264d277cde33881")
+
+	patch, err := FilePatch(context.Background(), base, head, "CHANGELOG", "README")
+	require.NoError(err)
+	require.Equal(`@@ -1 +1 @@
+-Initial changelog
++# README
+`, patch)
+}
+
+func (s *PatchSuite) TestFilePatchBinary() {
+	require := s.Require()
+
+	base := s.tree("b029517f6300c2da0f4b651b8642506cd6aaf45d")
+	head := s.tree("35e85108805c84807bc66a02d91535e1e24b38b9")
+
+	patch, err := FilePatch(context.Background(), base, head, "", "binary.jpg")
+	require.NoError(err)
+	require.Equal("", patch)
+}
+
+func (s *PatchSuite) TestFileHunks() {
+	require := s.Require()
+
+	base := s.tree("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	head := s.tree("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+
+	hunks, err := FileHunks(context.Background(), base, head, "", "vendor/foo.go")
+	require.NoError(err)
+	require.Equal([]*lookout.Hunk{{HeadStart: 1, HeadLines: 7}}, hunks)
+
+	hunks, err = FileHunks(context.Background(), base, head, "CHANGELOG", "CHANGELOG")
+	require.NoError(err)
+	require.Empty(hunks)
+
+	rename := s.tree("e8d3ffab552895c19b9fcf7aa264d277cde33881")
+	hunks, err = FileHunks(context.Background(), base, rename, "CHANGELOG", "README")
+	require.NoError(err)
+	require.Equal([]*lookout.Hunk{{BaseStart: 1, BaseLines: 1, HeadStart: 1, HeadLines: 1}}, hunks)
+
+	binaryBase := s.tree("b029517f6300c2da0f4b651b8642506cd6aaf45d")
+	binaryHead := s.tree("35e85108805c84807bc66a02d91535e1e24b38b9")
+	hunks, err = FileHunks(context.Background(), binaryBase, binaryHead, "", "binary.jpg")
+	require.NoError(err)
+	require.Nil(hunks)
+}
+
+func (s *PatchSuite) TestChangeHunkScanner() {
+	require := s.Require()
+
+	content := lines(10)
+	modified := strings.Replace(content, "line 2\n", "a\nb\n", 1)
+	modified = strings.Replace(modified, "line 7\n", "", 1)
+
+	sto := memory.NewStorage()
+	base := buildTree(s.T(), sto, map[string]string{"a.go": content, "b.go": content})
+	head := buildTree(s.T(), sto, map[string]string{"a.go": modified})
+
+	scanner := NewChangeHunkScanner(context.Background(),
+		NewDiffTreeScanner(base, head), base, head)
+
+	hunks := make(map[string][]*lookout.Hunk)
+	for scanner.Next() {
+		hunks[scanner.Change().Base.Path] = scanner.Hunks()
+	}
+
+	require.NoError(scanner.Err())
+	require.Equal(map[string][]*lookout.Hunk{
+		"a.go": {
+			{BaseStart: 3, BaseLines: 1, HeadStart: 3, HeadLines: 2},
+			{BaseStart: 8, BaseLines: 1, HeadStart: 8},
+		},
+		"b.go": {
+			{BaseStart: 1, BaseLines: 10},
+		},
+	}, hunks)
+}
+
+func (s *PatchSuite) TestPatchLoader() {
+	require := s.Require()
+
+	loader := NewPatchLoader(NewStorerCommitLoader(s.Storer))
+	patch, err := loader.FilePatch(context.Background(),
+		lookout.ReferencePointer{Hash: "918c48b83bd081e863dbe1b80f8998f058cd8294"},
+		lookout.ReferencePointer{Hash: "e8d3ffab552895c19b9fcf7aa264d277cde33881"},
+		"CHANGELOG", "README")
+	require.NoError(err)
+	require.Equal(`@@ -1 +1 @@
+-Initial changelog
++# README
+`, patch)
+}
+
+func (s *PatchSuite) TestPatchLoaderChanges() {
+	require := s.Require()
+
+	base := lookout.ReferencePointer{Hash: "918c48b83bd081e863dbe1b80f8998f058cd8294"}
+	head := lookout.ReferencePointer{Hash: "e8d3ffab552895c19b9fcf7aa264d277cde33881"}
+
+	commitLoader := &MockCommitLoader{}
+	baseCommit, err := object.GetCommit(s.Storer, plumbing.NewHash(base.Hash))
+	require.NoError(err)
+	headCommit, err := object.GetCommit(s.Storer, plumbing.NewHash(head.Hash))
+	require.NoError(err)
+	commitLoader.On("LoadCommits", mock.Anything, []lookout.ReferencePointer{base, head}).
+		Return([]*object.Commit{baseCommit, headCommit}, nil).Once()
+
+	loader := NewPatchLoader(commitLoader)
+	changes, err := loader.Changes(context.Background(), base, head)
+	require.NoError(err)
+
+	paths := make(map[string]string)
+	for _, ch := range changes {
+		switch {
+		case ch.Base == nil:
+			paths[ch.Head.Path] = "added"
+		case ch.Head == nil:
+			paths[ch.Base.Path] = "removed"
+		default:
+			paths[ch.Head.Path] = "modified"
+		}
+	}
+
+	require.Equal("added", paths["README"])
+
+	// the trees are loaded only once for the same diff
+	_, err = loader.FilePatch(context.Background(), base, head, "", "README")
+	require.NoError(err)
+	commitLoader.AssertExpectations(s.T())
+}
+
+func (s *PatchSuite) TestMergeBase() {
+	require := s.Require()
+
+	commit := func(hash string) *object.Commit {
+		c, err := object.GetCommit(s.Storer, plumbing.NewHash(hash))
+		require.NoError(err)
+		return c
+	}
+
+	branch := commit("e8d3ffab552895c19b9fcf7aa264d277cde33881")
+	master := commit("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+
+	mb, err := mergeBase(master, branch)
+	require.NoError(err)
+	require.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", mb.Hash.String())
+
+	mb, err = mergeBase(branch, master)
+	require.NoError(err)
+	require.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", mb.Hash.String())
+
+	// the base is an ancestor of the head
+	mb, err = mergeBase(commit("b029517f6300c2da0f4b651b8642506cd6aaf45d"), master)
+	require.NoError(err)
+	require.Equal("b029517f6300c2da0f4b651b8642506cd6aaf45d", mb.Hash.String())
+
+	mb, err = mergeBase(master, master)
+	require.NoError(err)
+	require.Equal(master.Hash, mb.Hash)
+}
+
+// TestMergeBaseCommitterTimeSkew checks the merge base of histories whose
+// committer times are not in order, like after a rebase or with a wrong clock
+func TestMergeBaseCommitterTimeSkew(t *testing.T) {
+	require := require.New(t)
+
+	sto := memory.NewStorage()
+	tree := buildTree(t, sto, map[string]string{"a": "a\n"})
+
+	commit := func(msg string, when int64, parents ...*object.Commit) *object.Commit {
+		c := &object.Commit{
+			Author:    object.Signature{Name: "foo", When: time.Unix(when, 0)},
+			Committer: object.Signature{Name: "foo", When: time.Unix(when, 0)},
+			Message:   msg,
+			TreeHash:  tree.Hash,
+		}
+		for _, p := range parents {
+			c.ParentHashes = append(c.ParentHashes, p.Hash)
+		}
+
+		obj := sto.NewEncodedObject()
+		require.NoError(c.Encode(obj))
+		hash, err := sto.SetEncodedObject(obj)
+		require.NoError(err)
+
+		c, err = object.GetCommit(sto, hash)
+		require.NoError(err)
+		return c
+	}
+
+	root := commit("root", 1)
+	ancestor := commit("ancestor", 5, root)
+	base := commit("base", 10, ancestor)
+	// the commit of head is older than its parent, the common ancestor is
+	// visited before it
+	rebased := commit("rebased", 3, ancestor)
+	head := commit("head", 10, rebased)
+
+	mb, err := mergeBase(base, head)
+	require.NoError(err)
+	require.Equal(ancestor.Hash, mb.Hash)
+
+	mb, err = mergeBase(head, base)
+	require.NoError(err)
+	require.Equal(ancestor.Hash, mb.Hash)
+
+	// the root is found first, but it is an ancestor of the merge base
+	old := commit("old", 2, root)
+	merge := commit("merge", 20, old, rebased)
+	mb, err = mergeBase(base, merge)
+	require.NoError(err)
+	require.Equal(ancestor.Hash, mb.Hash)
+}
+
+func (s *PatchSuite) TestPatchLoaderChangesFromMergeBase() {
+	require := s.Require()
+
+	// master adds vendor/foo.go after the branch was created, it is not part
+	// of the changes of the branch
+	loader := NewPatchLoader(NewStorerCommitLoader(s.Storer))
+	changes, err := loader.Changes(context.Background(),
+		lookout.ReferencePointer{Hash: "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"},
+		lookout.ReferencePointer{Hash: "e8d3ffab552895c19b9fcf7aa264d277cde33881"})
+	require.NoError(err)
+
+	require.Len(changes, 1)
+	require.Nil(changes[0].Base)
+	require.Equal("README", changes[0].Head.Path)
+}
+
+func (s *PatchSuite) TestPatchLoaderRevisionPatch() {
+	require := s.Require()
+
+	loader := NewPatchLoader(NewStorerCommitLoader(s.Storer))
+	patch, err := loader.RevisionPatch(context.Background(),
+		lookout.ReferencePointer{Hash: "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"},
+		lookout.ReferencePointer{Hash: "e8d3ffab552895c19b9fcf7aa264d277cde33881"},
+		"CHANGELOG")
+	require.NoError(err)
+	require.Equal("", patch)
+
+	// vendor/foo.go was added to master after the branch was created
+	_, err = loader.RevisionPatch(context.Background(),
+		lookout.ReferencePointer{Hash: "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"},
+		lookout.ReferencePointer{Hash: "e8d3ffab552895c19b9fcf7aa264d277cde33881"},
+		"vendor/foo.go")
+	require.Error(err)
+}
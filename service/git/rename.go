@@ -0,0 +1,250 @@
+package git
+
+import (
+	"bufio"
+	"io"
+	"sort"
+
+	"github.com/meyskens/lookout"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	gitioutil "gopkg.in/src-d/go-git.v4/utils/ioutil"
+)
+
+// maxRenameCandidates is the maximum number of sources, and of destinations,
+// compared by their contents, like the diff.renameLimit of git. Above it only
+// the files renamed or copied without modifications are detected.
+const maxRenameCandidates = 400
+
+// renameDetector pairs the added files of a list of changes with the deleted,
+// and with the modified if copies are detected, files they are similar to
+type renameDetector struct {
+	opts  lookout.RenameDetection
+	files map[plumbing.Hash]*object.File
+	lines map[plumbing.Hash]map[string]int64
+}
+
+// renamePair is a source and a destination change, by their index, with the
+// similarity of their contents
+type renamePair struct {
+	src, dst int
+	score    int
+}
+
+// detectRenames returns changes with the deletions and additions that are
+// renames, and the additions that are copies if enabled, replaced by one
+// change from the old file to the new one, in the position of the addition
+func detectRenames(changes object.Changes, opts lookout.RenameDetection) (
+	object.Changes, error) {
+	if opts.Threshold <= 0 {
+		return changes, nil
+	}
+
+	d := &renameDetector{
+		opts:  opts,
+		files: make(map[plumbing.Hash]*object.File),
+		lines: make(map[plumbing.Hash]map[string]int64),
+	}
+
+	var srcs, dsts []int
+	for i, ch := range changes {
+		switch {
+		case ch.To.Name == "":
+			srcs = append(srcs, i)
+		case ch.From.Name == "":
+			dsts = append(dsts, i)
+		case opts.Copies:
+			srcs = append(srcs, i)
+		}
+	}
+
+	if len(srcs) == 0 || len(dsts) == 0 {
+		return changes, nil
+	}
+
+	pairs, err := d.pairs(changes, srcs, dsts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the most similar files are paired first, a deleted file is renamed
+	// once and every other pair with it is a copy
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].score > pairs[j].score
+	})
+
+	renamed := make(map[int]bool)
+	sources := make(map[int]int)
+	for _, p := range pairs {
+		if _, ok := sources[p.dst]; ok {
+			continue
+		}
+
+		deleted := changes[p.src].To.Name == ""
+		if deleted && !renamed[p.src] {
+			renamed[p.src] = true
+		} else if !opts.Copies {
+			continue
+		}
+
+		sources[p.dst] = p.src
+	}
+
+	var result object.Changes
+	for i, ch := range changes {
+		if renamed[i] {
+			continue
+		}
+
+		if src, ok := sources[i]; ok {
+			ch = &object.Change{From: changes[src].From, To: ch.To}
+		}
+
+		result = append(result, ch)
+	}
+
+	return result, nil
+}
+
+// pairs returns the pairs of sources and destinations with a similarity over
+// the threshold
+func (d *renameDetector) pairs(changes object.Changes, srcs, dsts []int) (
+	[]renamePair, error) {
+	inexact := d.opts.Threshold < 100 &&
+		len(srcs) <= maxRenameCandidates && len(dsts) <= maxRenameCandidates
+
+	var pairs []renamePair
+	for _, dst := range dsts {
+		to := changes[dst].To
+		if !to.TreeEntry.Mode.IsFile() {
+			continue
+		}
+
+		// the empty files are never renamed
+		f, err := d.file(to)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Size == 0 {
+			continue
+		}
+
+		for _, src := range srcs {
+			from := changes[src].From
+			if !from.TreeEntry.Mode.IsFile() {
+				continue
+			}
+
+			score := 0
+			if from.TreeEntry.Hash == to.TreeEntry.Hash {
+				score = 100
+			} else if inexact {
+				score, err = d.similarity(from, to)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if score >= d.opts.Threshold {
+				pairs = append(pairs, renamePair{src: src, dst: dst, score: score})
+			}
+		}
+	}
+
+	return pairs, nil
+}
+
+// similarity returns the percentage of the contents of from and to they have
+// in common, in bytes of equal lines, over the size of the biggest one
+func (d *renameDetector) similarity(from, to object.ChangeEntry) (int, error) {
+	ff, err := d.file(from)
+	if err != nil {
+		return 0, err
+	}
+
+	tf, err := d.file(to)
+	if err != nil {
+		return 0, err
+	}
+
+	max, min := ff.Size, tf.Size
+	if min > max {
+		max, min = min, max
+	}
+
+	if max == 0 || min*100/max < int64(d.opts.Threshold) {
+		return 0, nil
+	}
+
+	fl, err := d.fileLines(ff)
+	if err != nil {
+		return 0, err
+	}
+
+	tl, err := d.fileLines(tf)
+	if err != nil {
+		return 0, err
+	}
+
+	var common int64
+	for line, n := range fl {
+		m := tl[line]
+		if m < n {
+			n = m
+		}
+
+		common += n
+	}
+
+	return int(common * 100 / max), nil
+}
+
+func (d *renameDetector) file(entry object.ChangeEntry) (*object.File, error) {
+	if f, ok := d.files[entry.TreeEntry.Hash]; ok {
+		return f, nil
+	}
+
+	f, err := entry.Tree.TreeEntryFile(&entry.TreeEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	d.files[entry.TreeEntry.Hash] = f
+	return f, nil
+}
+
+// fileLines returns the number of bytes of each distinct line of f
+func (d *renameDetector) fileLines(f *object.File) (lines map[string]int64, err error) {
+	if lines, ok := d.lines[f.Hash]; ok {
+		return lines, nil
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	defer gitioutil.CheckClose(r, &err)
+
+	lines = make(map[string]int64)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			lines[line] += int64(len(line))
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d.lines[f.Hash] = lines
+	return lines, nil
+}
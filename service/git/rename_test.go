@@ -0,0 +1,203 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/meyskens/lookout"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// buildTree writes a tree with the given regular files, by path, to s
+func buildTree(t *testing.T, s *memory.Storage, files map[string]string) *object.Tree {
+	t.Helper()
+	return buildTreeModes(t, s, files, nil)
+}
+
+// buildTreeModes writes a tree with the given files, by path, to s. The files
+// are regular unless they have another mode in modes, the content of a
+// submodule is the hash of its commit.
+func buildTreeModes(t *testing.T, s *memory.Storage, files map[string]string,
+	modes map[string]filemode.FileMode) *object.Tree {
+	t.Helper()
+	require := require.New(t)
+
+	tree := &object.Tree{}
+	for name, content := range files {
+		mode, ok := modes[name]
+		if !ok {
+			mode = filemode.Regular
+		}
+
+		if mode == filemode.Submodule {
+			tree.Entries = append(tree.Entries, object.TreeEntry{
+				Name: name,
+				Mode: mode,
+				Hash: plumbing.NewHash(content),
+			})
+			continue
+		}
+
+		obj := s.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		require.NoError(err)
+		_, err = w.Write([]byte(content))
+		require.NoError(err)
+		require.NoError(w.Close())
+
+		hash, err := s.SetEncodedObject(obj)
+		require.NoError(err)
+
+		tree.Entries = append(tree.Entries, object.TreeEntry{
+			Name: name,
+			Mode: mode,
+			Hash: hash,
+		})
+	}
+
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return tree.Entries[i].Name < tree.Entries[j].Name
+	})
+
+	obj := s.NewEncodedObject()
+	require.NoError(tree.Encode(obj))
+	hash, err := s.SetEncodedObject(obj)
+	require.NoError(err)
+
+	tree, err = object.GetTree(s, hash)
+	require.NoError(err)
+	return tree
+}
+
+// lines returns n numbered lines
+func lines(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+
+	return b.String()
+}
+
+// scanRenames returns the changes between the files in base and head, as
+// "base path -> head path"
+func scanRenames(t *testing.T, base, head map[string]string,
+	opts lookout.RenameDetection) []string {
+	t.Helper()
+
+	s := memory.NewStorage()
+	scanner := NewDiffTreeScanner(buildTree(t, s, base), buildTree(t, s, head))
+	scanner.SetRenameDetection(opts)
+
+	var changes []string
+	for scanner.Next() {
+		ch := scanner.Change()
+		var from, to string
+		if ch.Base != nil {
+			from = ch.Base.Path
+		}
+
+		if ch.Head != nil {
+			to = ch.Head.Path
+		}
+
+		changes = append(changes, from+" -> "+to)
+	}
+
+	require.NoError(t, scanner.Err())
+	return changes
+}
+
+func TestDiffTreeScannerRenames(t *testing.T) {
+	require := require.New(t)
+
+	content := lines(10)
+	modified := strings.Replace(content, "line 5\n", "line five\n", 1)
+
+	base := map[string]string{"a.go": content, "b.go": lines(3)}
+	head := map[string]string{"c.go": modified, "d.go": lines(3)}
+
+	require.Equal([]string{
+		"a.go -> ",
+		"b.go -> ",
+		" -> c.go",
+		" -> d.go",
+	}, scanRenames(t, base, head, lookout.RenameDetection{}))
+
+	require.Equal([]string{
+		"a.go -> c.go",
+		"b.go -> d.go",
+	}, scanRenames(t, base, head, lookout.RenameDetection{Threshold: 50}))
+
+	// only the file renamed without modifications is similar enough
+	require.Equal([]string{
+		"a.go -> ",
+		" -> c.go",
+		"b.go -> d.go",
+	}, scanRenames(t, base, head, lookout.RenameDetection{Threshold: 100}))
+}
+
+func TestDiffTreeScannerRenamesBestMatch(t *testing.T) {
+	require := require.New(t)
+
+	content := lines(10)
+	base := map[string]string{
+		"a.go": strings.Replace(content, "line 5\n", "line five\n", 1),
+		"b.go": content,
+	}
+	head := map[string]string{"c.go": content}
+
+	require.Equal([]string{
+		"a.go -> ",
+		"b.go -> c.go",
+	}, scanRenames(t, base, head, lookout.RenameDetection{Threshold: 50}))
+}
+
+func TestDiffTreeScannerCopies(t *testing.T) {
+	require := require.New(t)
+
+	content := lines(10)
+	base := map[string]string{"a.go": content, "b.go": lines(3)}
+	head := map[string]string{
+		"a.go": content + "line 10\n",
+		"c.go": content,
+		"d.go": lines(3),
+		"e.go": lines(3),
+	}
+
+	opts := lookout.RenameDetection{Threshold: 50}
+	require.Equal([]string{
+		"a.go -> a.go",
+		" -> c.go",
+		"b.go -> d.go",
+		" -> e.go",
+	}, scanRenames(t, base, head, opts))
+
+	opts.Copies = true
+	require.Equal([]string{
+		"a.go -> a.go",
+		"a.go -> c.go",
+		"b.go -> d.go",
+		"b.go -> e.go",
+	}, scanRenames(t, base, head, opts))
+}
+
+func TestDiffTreeScannerRenamesEmpty(t *testing.T) {
+	require := require.New(t)
+
+	base := map[string]string{"a.go": ""}
+	head := map[string]string{"b.go": ""}
+
+	require.Equal([]string{
+		"a.go -> ",
+		" -> b.go",
+	}, scanRenames(t, base, head, lookout.RenameDetection{Threshold: 50}))
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -10,20 +11,23 @@ import (
 	"github.com/meyskens/lookout"
 	"github.com/meyskens/lookout/util/ctxlog"
 
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/utils/binary"
 	gitioutil "gopkg.in/src-d/go-git.v4/utils/ioutil"
 	log "gopkg.in/src-d/go-log.v1"
 )
 
 // TreeScanner is a scanner for files of git tree
 type TreeScanner struct {
-	storer storer.EncodedObjectStorer
-	tree   *object.Tree
-	tw     *object.TreeWalker
-	val    *lookout.File
-	err    error
-	done   bool
+	storer     storer.EncodedObjectStorer
+	tree       *object.Tree
+	tw         *object.TreeWalker
+	submodules bool
+	val        *lookout.File
+	err        error
+	done       bool
 }
 
 // NewTreeScanner creates new TreeScanner
@@ -34,6 +38,12 @@ func NewTreeScanner(tree *object.Tree) *TreeScanner {
 	}
 }
 
+// SetSubmodules makes the scanner return the submodules too, with the hash of
+// the commit they point to
+func (s *TreeScanner) SetSubmodules(submodules bool) {
+	s.submodules = submodules
+}
+
 func (s *TreeScanner) Next() bool {
 	if s.done {
 		return false
@@ -52,7 +62,7 @@ func (s *TreeScanner) Next() bool {
 			return false
 		}
 
-		if !entry.Mode.IsFile() {
+		if !entry.Mode.IsFile() && !(s.submodules && entry.Mode == filemode.Submodule) {
 			continue
 		}
 
@@ -88,6 +98,8 @@ func (s *TreeScanner) Close() error {
 // DiffTreeScanner is a scanner for files of diff between git trees
 type DiffTreeScanner struct {
 	base, head *object.Tree
+	renames    lookout.RenameDetection
+	submodules bool
 	val        *object.Change
 	err        error
 	started    bool
@@ -102,6 +114,19 @@ func NewDiffTreeScanner(base, head *object.Tree) *DiffTreeScanner {
 	}
 }
 
+// SetRenameDetection makes the scanner report the renamed, and copied if
+// enabled, files as one change, see lookout.RenameDetection. It must be called
+// before the first call to Next.
+func (s *DiffTreeScanner) SetRenameDetection(d lookout.RenameDetection) {
+	s.renames = d
+}
+
+// SetSubmodules makes the scanner return the changes of the submodules too,
+// with the hashes of the commits they point to
+func (s *DiffTreeScanner) SetSubmodules(submodules bool) {
+	s.submodules = submodules
+}
+
 func (s *DiffTreeScanner) Next() bool {
 	if !s.started {
 		defer func() { s.started = true }()
@@ -111,6 +136,16 @@ func (s *DiffTreeScanner) Next() bool {
 			return false
 		}
 
+		changes, err = detectRenames(changes, s.renames)
+		if err != nil {
+			s.err = err
+			return false
+		}
+
+		if !s.submodules {
+			changes = withoutSubmodules(changes)
+		}
+
 		s.changes = changes
 	}
 
@@ -139,6 +174,34 @@ func (s *DiffTreeScanner) Close() error {
 	return nil
 }
 
+// withoutSubmodules returns the changes without their submodule sides, a
+// file replaced by a submodule is returned as removed
+func withoutSubmodules(changes object.Changes) object.Changes {
+	var result object.Changes
+	for _, ch := range changes {
+		from, to := ch.From, ch.To
+		if from.TreeEntry.Mode == filemode.Submodule {
+			from = object.ChangeEntry{}
+		}
+
+		if to.TreeEntry.Mode == filemode.Submodule {
+			to = object.ChangeEntry{}
+		}
+
+		if from.Name == "" && to.Name == "" {
+			continue
+		}
+
+		if from != ch.From || to != ch.To {
+			ch = &object.Change{From: from, To: to}
+		}
+
+		result = append(result, ch)
+	}
+
+	return result
+}
+
 func gitChangeEntryToApiFile(entry object.ChangeEntry) *lookout.File {
 	if entry.Name == "" {
 		return nil
@@ -247,6 +310,13 @@ func NewFileFilterScanner(ctx context.Context, scanner lookout.FileScanner, incl
 type blobAdder struct {
 	tree *object.Tree
 	ctx  context.Context
+	// symlinksOnly only adds the target of the symbolic links
+	symlinksOnly bool
+	// limits of the contents, the reasons of the files whose content is not
+	// added because of them are kept in skipped. The contents are not
+	// limited if skipped is nil.
+	limits  lookout.ContentLimits
+	skipped contentSkips
 }
 
 func (b *blobAdder) Fn(f *lookout.File) (bool, error) {
@@ -254,7 +324,11 @@ func (b *blobAdder) Fn(f *lookout.File) (bool, error) {
 		return false, nil
 	}
 
-	if f.Hash == "" {
+	if f.Hash == "" || lookout.IsSubmodule(f) {
+		return false, nil
+	}
+
+	if b.symlinksOnly && !lookout.IsSymlink(f) {
 		return false, nil
 	}
 
@@ -267,6 +341,13 @@ func (b *blobAdder) Fn(f *lookout.File) (bool, error) {
 		return true, nil
 	}
 
+	// the target of a symbolic link is always added
+	limited := b.skipped != nil && !lookout.IsSymlink(f)
+	if limited && b.limits.MaxSize > 0 && of.Blob.Size > b.limits.MaxSize {
+		b.skipped[f] = lookout.SkipTooBig
+		return false, nil
+	}
+
 	r, err := of.Blob.Reader()
 	if err != nil {
 		return true, fmt.Errorf("cannot get reader for file:'%v', %v", f.Path, err)
@@ -279,12 +360,34 @@ func (b *blobAdder) Fn(f *lookout.File) (bool, error) {
 		return true, fmt.Errorf("cannot read file:'%v', %v", f.Path, err)
 	}
 
+	if limited && b.limits.ExcludeBinary {
+		isBinary, err := binary.IsBinary(bytes.NewReader(f.Content))
+		if err != nil {
+			return true, fmt.Errorf("cannot read file:'%v', %v", f.Path, err)
+		}
+
+		if isBinary {
+			f.Content = nil
+			b.skipped[f] = lookout.SkipBinary
+		}
+	}
+
 	return false, nil
 }
 
 // NewFileBlobScanner creates new FnFileScanner
 func NewFileBlobScanner(ctx context.Context, scanner lookout.FileScanner, tree *object.Tree) *lookout.FnFileScanner {
-	adder := blobAdder{tree, ctx}
+	adder := blobAdder{tree: tree, ctx: ctx}
+	return &lookout.FnFileScanner{
+		Scanner: scanner,
+		Fn:      adder.Fn,
+	}
+}
+
+// NewFileSymlinkScanner creates new FnFileScanner that only adds the content
+// of the symbolic links, their target
+func NewFileSymlinkScanner(ctx context.Context, scanner lookout.FileScanner, tree *object.Tree) *lookout.FnFileScanner {
+	adder := blobAdder{tree: tree, ctx: ctx, symlinksOnly: true}
 	return &lookout.FnFileScanner{
 		Scanner: scanner,
 		Fn:      adder.Fn,
@@ -293,8 +396,24 @@ func NewFileBlobScanner(ctx context.Context, scanner lookout.FileScanner, tree *
 
 // NewChangeBlobScanner creates new FnChangeScanner
 func NewChangeBlobScanner(ctx context.Context, scanner lookout.ChangeScanner, base, head *object.Tree) *lookout.FnChangeScanner {
-	baseAdder := blobAdder{base, ctx}
-	headAdder := blobAdder{head, ctx}
+	return newChangeBlobScanner(ctx, scanner, base, head, false)
+}
+
+// NewChangeSymlinkScanner creates new FnChangeScanner that only adds the
+// content of the symbolic links, their target
+func NewChangeSymlinkScanner(ctx context.Context, scanner lookout.ChangeScanner, base, head *object.Tree) *lookout.FnChangeScanner {
+	return newChangeBlobScanner(ctx, scanner, base, head, true)
+}
+
+func newChangeBlobScanner(ctx context.Context, scanner lookout.ChangeScanner,
+	base, head *object.Tree, symlinksOnly bool) *lookout.FnChangeScanner {
+	return newChangeAdderScanner(scanner,
+		blobAdder{tree: base, ctx: ctx, symlinksOnly: symlinksOnly},
+		blobAdder{tree: head, ctx: ctx, symlinksOnly: symlinksOnly})
+}
+
+func newChangeAdderScanner(scanner lookout.ChangeScanner,
+	baseAdder, headAdder blobAdder) *lookout.FnChangeScanner {
 
 	fn := func(ch *lookout.Change) (bool, error) {
 		skip, err := baseAdder.Fn(ch.Base)
@@ -310,3 +429,151 @@ func NewChangeBlobScanner(ctx context.Context, scanner lookout.ChangeScanner, ba
 		Fn:      fn,
 	}
 }
+
+// contentSkips holds why the contents of the files of the current file or
+// change were not added
+type contentSkips map[*lookout.File]string
+
+func (s contentSkips) reset() {
+	for f := range s {
+		delete(s, f)
+	}
+}
+
+// FileContentScanner is a FileScanner that adds the contents of the files up
+// to some limits, and returns why they were not added
+type FileContentScanner struct {
+	*lookout.FnFileScanner
+	skipped contentSkips
+}
+
+var _ lookout.SkipScanner = &FileContentScanner{}
+
+// NewFileContentScanner creates new FileContentScanner that adds the contents
+// of the files within limits
+func NewFileContentScanner(ctx context.Context, scanner lookout.FileScanner,
+	tree *object.Tree, limits lookout.ContentLimits) *FileContentScanner {
+	skipped := make(contentSkips)
+	adder := blobAdder{tree: tree, ctx: ctx, limits: limits, skipped: skipped}
+	return &FileContentScanner{
+		FnFileScanner: &lookout.FnFileScanner{
+			Scanner: scanner,
+			Fn:      adder.Fn,
+		},
+		skipped: skipped,
+	}
+}
+
+func (s *FileContentScanner) Next() bool {
+	s.skipped.reset()
+	return s.FnFileScanner.Next()
+}
+
+func (s *FileContentScanner) SkipReason(f *lookout.File) string {
+	if reason, ok := s.skipped[f]; ok {
+		return reason
+	}
+
+	return s.FnFileScanner.SkipReason(f)
+}
+
+// ChangeContentScanner is a ChangeScanner that adds the contents of the files
+// up to some limits, and returns why they were not added
+type ChangeContentScanner struct {
+	*lookout.FnChangeScanner
+	skipped contentSkips
+}
+
+var _ lookout.SkipScanner = &ChangeContentScanner{}
+
+// NewChangeContentScanner creates new ChangeContentScanner that adds the
+// contents of the files within limits
+func NewChangeContentScanner(ctx context.Context, scanner lookout.ChangeScanner,
+	base, head *object.Tree, limits lookout.ContentLimits) *ChangeContentScanner {
+	skipped := make(contentSkips)
+	return &ChangeContentScanner{
+		FnChangeScanner: newChangeAdderScanner(scanner,
+			blobAdder{tree: base, ctx: ctx, limits: limits, skipped: skipped},
+			blobAdder{tree: head, ctx: ctx, limits: limits, skipped: skipped}),
+		skipped: skipped,
+	}
+}
+
+func (s *ChangeContentScanner) Next() bool {
+	s.skipped.reset()
+	return s.FnChangeScanner.Next()
+}
+
+func (s *ChangeContentScanner) SkipReason(f *lookout.File) string {
+	if reason, ok := s.skipped[f]; ok {
+		return reason
+	}
+
+	return s.FnChangeScanner.SkipReason(f)
+}
+
+// ChangeHunkScanner is a ChangeScanner that computes the changed lines of each
+// change of the underlying scanner, see FileHunks
+type ChangeHunkScanner struct {
+	ctx        context.Context
+	scanner    lookout.ChangeScanner
+	base, head *object.Tree
+	hunks      []*lookout.Hunk
+	err        error
+}
+
+var _ lookout.HunkScanner = &ChangeHunkScanner{}
+
+// NewChangeHunkScanner creates new ChangeHunkScanner, base is nil if all the
+// files are new
+func NewChangeHunkScanner(ctx context.Context, scanner lookout.ChangeScanner, base, head *object.Tree) *ChangeHunkScanner {
+	return &ChangeHunkScanner{
+		ctx:     ctx,
+		scanner: scanner,
+		base:    base,
+		head:    head,
+	}
+}
+
+func (s *ChangeHunkScanner) Next() bool {
+	if s.err != nil || !s.scanner.Next() {
+		return false
+	}
+
+	var from, to string
+	ch := s.scanner.Change()
+	if ch.Base != nil {
+		from = ch.Base.Path
+	}
+
+	if ch.Head != nil {
+		to = ch.Head.Path
+	}
+
+	s.hunks, s.err = FileHunks(s.ctx, s.base, s.head, from, to)
+	return s.err == nil
+}
+
+func (s *ChangeHunkScanner) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.scanner.Err()
+}
+
+func (s *ChangeHunkScanner) Change() *lookout.Change {
+	return s.scanner.Change()
+}
+
+func (s *ChangeHunkScanner) Hunks() []*lookout.Hunk {
+	return s.hunks
+}
+
+func (s *ChangeHunkScanner) SkipReason(f *lookout.File) string {
+	return lookout.ScannerSkipReason(s.scanner, f)
+}
+
+func (s *ChangeHunkScanner) Close() error {
+	return s.scanner.Close()
+}
@@ -12,9 +12,11 @@ import (
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/plumbing/storer"
 	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
 type ScannerSuite struct {
@@ -391,3 +393,164 @@ func (s *ScannerSuite) TestDiffTreeScanner() {
 
 	require.Len(changes, 1)
 }
+
+const (
+	submoduleBase = "b029517f6300c2da0f4b651b8642506cd6aaf45d"
+	submoduleHead = "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"
+)
+
+var linkModes = map[string]filemode.FileMode{
+	"lib":  filemode.Submodule,
+	"link": filemode.Symlink,
+}
+
+func TestTreeScannerSubmodules(t *testing.T) {
+	require := require.New(t)
+
+	tree := buildTreeModes(t, memory.NewStorage(), map[string]string{
+		"a.go": "package a\n",
+		"lib":  submoduleHead,
+		"link": "a.go",
+	}, linkModes)
+
+	scan := func(submodules bool) map[string]*lookout.File {
+		scanner := NewTreeScanner(tree)
+		scanner.SetSubmodules(submodules)
+
+		files := make(map[string]*lookout.File)
+		for scanner.Next() {
+			files[scanner.File().Path] = scanner.File()
+		}
+
+		require.NoError(scanner.Err())
+		return files
+	}
+
+	files := scan(false)
+	require.Len(files, 2)
+	require.True(lookout.IsSymlink(files["link"]))
+
+	files = scan(true)
+	require.Len(files, 3)
+	require.True(lookout.IsSubmodule(files["lib"]))
+	require.Equal(submoduleHead, files["lib"].Hash)
+}
+
+func TestDiffTreeScannerSubmodules(t *testing.T) {
+	require := require.New(t)
+
+	sto := memory.NewStorage()
+	base := buildTreeModes(t, sto, map[string]string{
+		"lib":  submoduleBase,
+		"link": "a.go",
+	}, linkModes)
+	head := buildTreeModes(t, sto, map[string]string{
+		"lib":  submoduleHead,
+		"link": "b.go",
+	}, linkModes)
+
+	scan := func(submodules bool) map[string]*lookout.Change {
+		scanner := NewChangeSymlinkScanner(context.Background(),
+			NewDiffTreeScanner(base, head), base, head)
+		scanner.Scanner.(*DiffTreeScanner).SetSubmodules(submodules)
+
+		changes := make(map[string]*lookout.Change)
+		for scanner.Next() {
+			changes[scanner.Change().Head.Path] = scanner.Change()
+		}
+
+		require.NoError(scanner.Err())
+		return changes
+	}
+
+	changes := scan(false)
+	require.Len(changes, 1)
+	require.Equal("a.go", string(changes["link"].Base.Content))
+	require.Equal("b.go", string(changes["link"].Head.Content))
+
+	changes = scan(true)
+	require.Len(changes, 2)
+	require.Equal(submoduleBase, changes["lib"].Base.Hash)
+	require.Equal(submoduleHead, changes["lib"].Head.Hash)
+	require.Nil(changes["lib"].Head.Content)
+}
+
+func TestFileContentScanner(t *testing.T) {
+	require := require.New(t)
+
+	tree := buildTreeModes(t, memory.NewStorage(), map[string]string{
+		"a.go":    "package a\n",
+		"big.go":  lines(100),
+		"img.png": "\x89PNG\x00\x00",
+		"link":    "big.go",
+	}, linkModes)
+
+	scan := func(limits lookout.ContentLimits) (map[string]*lookout.File, map[string]string) {
+		ctx := context.Background()
+		scanner := NewFileContentScanner(ctx, NewTreeScanner(tree), tree, limits)
+
+		files := make(map[string]*lookout.File)
+		reasons := make(map[string]string)
+		for scanner.Next() {
+			f := scanner.File()
+			files[f.Path] = f
+			if reason := lookout.ScannerSkipReason(scanner, f); reason != "" {
+				reasons[f.Path] = reason
+			}
+		}
+
+		require.NoError(scanner.Err())
+		return files, reasons
+	}
+
+	files, reasons := scan(lookout.ContentLimits{})
+	require.Len(files, 4)
+	require.Empty(reasons)
+	require.Equal(lines(100), string(files["big.go"].Content))
+
+	files, reasons = scan(lookout.ContentLimits{MaxSize: 100, ExcludeBinary: true})
+	require.Len(files, 4)
+	require.Equal(map[string]string{
+		"big.go":  lookout.SkipTooBig,
+		"img.png": lookout.SkipBinary,
+	}, reasons)
+	require.Equal("package a\n", string(files["a.go"].Content))
+	require.Nil(files["big.go"].Content)
+	require.Nil(files["img.png"].Content)
+	require.Equal("big.go", string(files["link"].Content))
+}
+
+func TestChangeContentScanner(t *testing.T) {
+	require := require.New(t)
+
+	sto := memory.NewStorage()
+	base := buildTree(t, sto, map[string]string{
+		"a.go":    lines(100),
+		"img.png": "\x89PNG\x00\x00",
+	})
+	head := buildTree(t, sto, map[string]string{
+		"a.go":    lines(2),
+		"img.png": "\x89PNG\x00\x01",
+	})
+
+	ctx := context.Background()
+	scanner := NewChangeContentScanner(ctx, NewDiffTreeScanner(base, head),
+		base, head, lookout.ContentLimits{MaxSize: 100, ExcludeBinary: true})
+
+	require.True(scanner.Next())
+	ch := scanner.Change()
+	require.Equal("a.go", ch.Head.Path)
+	require.Equal(lookout.SkipTooBig, scanner.SkipReason(ch.Base))
+	require.Nil(ch.Base.Content)
+	require.Equal("", scanner.SkipReason(ch.Head))
+	require.Equal(lines(2), string(ch.Head.Content))
+
+	require.True(scanner.Next())
+	ch = scanner.Change()
+	require.Equal("img.png", ch.Head.Path)
+	require.Equal(lookout.SkipBinary, scanner.SkipReason(ch.Base))
+	require.Equal(lookout.SkipBinary, scanner.SkipReason(ch.Head))
+
+	require.False(scanner.Next())
+	require.NoError(scanner.Err())
+}
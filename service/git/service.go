@@ -13,6 +13,9 @@ import (
 // Service implements data service interface on top of go-git
 type Service struct {
 	loader CommitLoader
+	// lfs fetches the content of the Git LFS pointers, if it is set
+	lfs        LFSResolver
+	lfsMaxSize int64
 }
 
 var _ lookout.ChangeGetter = &Service{}
@@ -25,6 +28,26 @@ func NewService(loader CommitLoader) *Service {
 	}
 }
 
+// SetLFSResolver makes the service replace the Git LFS pointers with the
+// content of their objects fetched by r, when the contents of the files are
+// requested. The objects bigger than maxSize bytes, zero means no limit, or
+// that can not be fetched are not returned, and their files have the reason as
+// skip reason.
+func (r *Service) SetLFSResolver(resolver LFSResolver, maxSize int64) {
+	r.lfs = resolver
+	r.lfsMaxSize = maxSize
+}
+
+// lfsLimits returns the limits of the Git LFS objects fetched for a request
+// with the given content limits
+func (r *Service) lfsLimits(limits lookout.ContentLimits) lookout.ContentLimits {
+	if r.lfsMaxSize > 0 && (limits.MaxSize == 0 || r.lfsMaxSize < limits.MaxSize) {
+		limits.MaxSize = r.lfsMaxSize
+	}
+
+	return limits
+}
+
 var ErrRefValidation = errors.NewKind("reference %v does not have a %s")
 
 // validateReferences checks if all the References have enough information to clone a repo.
@@ -58,6 +81,16 @@ func (r *Service) GetChanges(ctx context.Context, req *lookout.ChangesRequest) (
 		return nil, err
 	}
 
+	renames, err := lookout.RenameDetectionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := lookout.ContentLimitsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	base, head, err := r.loadTrees(ctx, req.Base, req.Head)
 	if err != nil {
 		return nil, err
@@ -65,10 +98,16 @@ func (r *Service) GetChanges(ctx context.Context, req *lookout.ChangesRequest) (
 
 	var scanner lookout.ChangeScanner
 
+	submodules := lookout.WantSubmodulesFromContext(ctx)
 	if base == nil {
-		scanner = NewTreeScanner(head)
+		tree := NewTreeScanner(head)
+		tree.SetSubmodules(submodules)
+		scanner = tree
 	} else {
-		scanner = NewDiffTreeScanner(base, head)
+		diff := NewDiffTreeScanner(base, head)
+		diff.SetRenameDetection(renames)
+		diff.SetSubmodules(submodules)
+		scanner = diff
 	}
 
 	if req.IncludePattern != "" || req.ExcludePattern != "" {
@@ -76,8 +115,26 @@ func (r *Service) GetChanges(ctx context.Context, req *lookout.ChangesRequest) (
 			req.IncludePattern, req.ExcludePattern)
 	}
 
-	if req.WantContents {
+	if req.WantContents && !limits.IsZero() {
+		scanner = NewChangeContentScanner(ctx, scanner, base, head, limits)
+	} else if req.WantContents {
 		scanner = NewChangeBlobScanner(ctx, scanner, base, head)
+	} else if lookout.WantSymlinkTargetsFromContext(ctx) {
+		scanner = NewChangeSymlinkScanner(ctx, scanner, base, head)
+	}
+
+	if req.WantContents && r.lfs != nil {
+		var baseRepo *lookout.RepositoryInfo
+		if req.Base != nil {
+			baseRepo = req.Base.Repository()
+		}
+
+		scanner = NewChangeLFSScanner(ctx, scanner, r.lfs, r.lfsLimits(limits),
+			baseRepo, req.Head.Repository())
+	}
+
+	if lookout.WantHunksFromContext(ctx) {
+		scanner = NewChangeHunkScanner(ctx, scanner, base, head)
 	}
 
 	return scanner, nil
@@ -91,21 +148,38 @@ func (r *Service) GetFiles(ctx context.Context, req *lookout.FilesRequest) (
 		return nil, err
 	}
 
+	limits, err := lookout.ContentLimitsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	_, tree, err := r.loadTrees(ctx, nil, req.Revision)
 	if err != nil {
 		return nil, err
 	}
 
+	treeScanner := NewTreeScanner(tree)
+	treeScanner.SetSubmodules(lookout.WantSubmodulesFromContext(ctx))
+
 	var scanner lookout.FileScanner
-	scanner = NewTreeScanner(tree)
+	scanner = treeScanner
 
 	if req.IncludePattern != "" || req.ExcludePattern != "" {
 		scanner = NewFileFilterScanner(ctx, scanner,
 			req.IncludePattern, req.ExcludePattern)
 	}
 
-	if req.WantContents {
+	if req.WantContents && !limits.IsZero() {
+		scanner = NewFileContentScanner(ctx, scanner, tree, limits)
+	} else if req.WantContents {
 		scanner = NewFileBlobScanner(ctx, scanner, tree)
+	} else if lookout.WantSymlinkTargetsFromContext(ctx) {
+		scanner = NewFileSymlinkScanner(ctx, scanner, tree)
+	}
+
+	if req.WantContents && r.lfs != nil {
+		scanner = NewFileLFSScanner(ctx, scanner, r.lfs, r.lfsLimits(limits),
+			req.Revision.Repository())
 	}
 
 	return scanner, nil
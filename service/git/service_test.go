@@ -3,6 +3,7 @@ package git
 import (
 	"context"
 	"fmt"
+	"net"
 	"testing"
 
 	"github.com/meyskens/lookout"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
@@ -452,3 +454,93 @@ func (s *ServiceGetFilesSuite) TestWithPatternAndContent() {
 hs_err_pid*
 `, string(files[".gitignore"].Content))
 }
+
+func (s *ServiceGetFilesSuite) TestContentLimits() {
+	headHash := s.Basic.Head.String()
+	req := &lookout.FilesRequest{
+		Revision:     s.buildRefPointer("file:///myrepo", "referenceName", headHash),
+		WantContents: true,
+	}
+
+	skipped := func(limits lookout.ContentLimits) map[string]string {
+		require := s.Require()
+
+		srv := NewService(&StorerCommitLoader{s.Storer})
+		scanner, err := srv.GetFiles(lookout.WithContentLimits(context.TODO(), limits), req)
+		require.NoError(err)
+
+		reasons := make(map[string]string)
+		for scanner.Next() {
+			f := scanner.File()
+			if reason := lookout.ScannerSkipReason(scanner, f); reason != "" {
+				require.Nil(f.Content)
+				reasons[f.Path] = reason
+			}
+		}
+
+		require.NoError(scanner.Err())
+		return reasons
+	}
+
+	s.Equal(map[string]string{
+		"binary.jpg": lookout.SkipBinary,
+	}, skipped(lookout.ContentLimits{ExcludeBinary: true}))
+
+	s.Equal(map[string]string{
+		"LICENSE":        lookout.SkipTooBig,
+		"binary.jpg":     lookout.SkipTooBig,
+		"go/example.go":  lookout.SkipTooBig,
+		"json/long.json": lookout.SkipTooBig,
+		"php/crappy.php": lookout.SkipTooBig,
+	}, skipped(lookout.ContentLimits{MaxSize: 1000}))
+}
+
+func (s *ServiceGetFilesSuite) TestContentLimitsDefaultOverGRPC() {
+	require := s.Require()
+
+	headHash := s.Basic.Head.String()
+	req := &lookout.FilesRequest{
+		Revision:     s.buildRefPointer("file:///myrepo", "referenceName", headHash),
+		WantContents: true,
+	}
+
+	expected := s.requireScannerFiles(req, 9)
+
+	srv := NewService(&StorerCommitLoader{s.Storer})
+	grpcServer := grpc.NewServer()
+	lookout.RegisterDataServer(grpcServer, &lookout.DataServerHandler{
+		ChangeGetter: srv,
+		FileGetter:   srv,
+	})
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(err)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(err)
+	defer conn.Close()
+
+	// the call is made without the content limits metadata, like the
+	// analyzers that do not know it
+	scanner, err := lookout.NewDataClient(conn).GetFiles(context.TODO(), req)
+	require.NoError(err)
+
+	files := make(map[string]*lookout.File)
+	for scanner.Next() {
+		f := scanner.File()
+		require.Empty(lookout.ScannerSkipReason(scanner, f))
+		files[f.Path] = f
+	}
+
+	require.NoError(scanner.Err())
+	require.NoError(scanner.Close())
+
+	require.Len(files, len(expected))
+	for path, f := range expected {
+		require.NotNil(files[path], path)
+		require.NotEmpty(files[path].Content, path)
+		require.Equal(f.Content, files[path].Content, path)
+	}
+}
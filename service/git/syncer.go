@@ -3,7 +3,9 @@ package git
 import (
 	"context"
 	"fmt"
-	"sync"
+	"io"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/meyskens/lookout"
@@ -11,26 +13,37 @@ import (
 
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage"
 
 	log "gopkg.in/src-d/go-log.v1"
 )
 
 const defaultRemoteName = "origin"
 
+// maxShallowDepth is the depth above which a shallow repository is deepened
+// by fetching its whole history
+const maxShallowDepth = 1024
+
 type Syncer interface {
 	Sync(context.Context, ...lookout.ReferencePointer) error
 }
 
 // LibrarySyncer syncs the local copy of git repository for a given CommitRevision.
 type LibrarySyncer struct {
-	m sync.Map // holds mutexes per repository
-
 	l *Library
 
 	authProvider AuthProvider
 	// fetchTimeout of zero means no timeout.
 	fetchTimeout time.Duration
+	// depth is the number of commits fetched from the references, zero
+	// means the whole history.
+	depth int
+	// parentProvider is optional, see SetParentProvider
+	parentProvider ParentProvider
 }
 
 // AuthProvider is an object that provides go-git auth methods
@@ -39,10 +52,36 @@ type AuthProvider interface {
 	GitAuth(ctx context.Context, repoInfo *lookout.RepositoryInfo) transport.AuthMethod
 }
 
+// ParentProvider is an object that provides the repositories forks were
+// created from
+type ParentProvider interface {
+	// ParentRepository returns the repository repo was forked from, or nil if
+	// it is not a fork
+	ParentRepository(ctx context.Context, repo *lookout.RepositoryInfo) (
+		*lookout.RepositoryInfo, error)
+}
+
 // NewSyncer returns a Syncer for the given Library. authProvider can be nil.
-// A fetchTimeout of zero means no timeout.
-func NewSyncer(l *Library, authProvider AuthProvider, fetchTimeout time.Duration) Syncer {
-	return &LibrarySyncer{l: l, authProvider: authProvider, fetchTimeout: fetchTimeout}
+// A fetchTimeout of zero means no timeout. A depth greater than zero makes
+// shallow fetches of that number of commits, the repository is deepened if
+// the requested commits, or the history to their merge base, are missing. A
+// depth of zero fetches the whole history.
+func NewSyncer(l *Library, authProvider AuthProvider,
+	fetchTimeout time.Duration, depth int) *LibrarySyncer {
+	return &LibrarySyncer{
+		l:            l,
+		authProvider: authProvider,
+		fetchTimeout: fetchTimeout,
+		depth:        depth,
+	}
+}
+
+// SetParentProvider sets the provider of the repositories forks were created
+// from. When a fork is added to the library after the repository it was
+// created from, it shares its objects instead of fetching them again, see
+// Library.ShareObjects.
+func (s *LibrarySyncer) SetParentProvider(p ParentProvider) {
+	s.parentProvider = p
 }
 
 // Sync syncs the local git repository to the given reference pointers.
@@ -53,64 +92,532 @@ func (s *LibrarySyncer) Sync(ctx context.Context,
 	}
 
 	frp := rps[0]
-	for _, orp := range rps[1:] {
-		if orp.InternalRepositoryURL != frp.InternalRepositoryURL {
-			return fmt.Errorf(
-				"sync from multiple repositories is not supported")
+	repoInfo := frp.Repository()
+	if repoInfo == nil {
+		return fmt.Errorf("invalid repository URL %q", frp.InternalRepositoryURL)
+	}
+
+	has, err := s.l.Has(repoInfo)
+	if err != nil {
+		return err
+	}
+
+	gitRepo, err := s.l.GetOrInit(ctx, repoInfo)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		s.shareParentObjects(ctx, repoInfo)
+	}
+
+	fetches, err := s.remoteFetches(ctx, repoInfo, gitRepo, rps)
+	if err != nil {
+		return err
+	}
+
+	alternates, err := s.l.alternateStorers(repoInfo)
+	if err != nil {
+		return err
+	}
+
+	fetchRepo, err := haveRepository(gitRepo, alternates)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fetches {
+		if err := s.fetch(ctx, repoInfo, fetchRepo, f, s.depth); err != nil {
+			return err
 		}
 	}
 
-	repoInfo := frp.Repository()
-	gitRepo, err := s.l.GetOrInit(ctx, frp.Repository())
+	if s.depth == 0 {
+		return nil
+	}
+
+	return s.deepen(ctx, repoInfo, gitRepo, fetches, rps)
+}
+
+// forkRefPrefix is the prefix of the references fetched from other
+// repositories than the one of the library
+const forkRefPrefix = "refs/forks/"
+
+// remoteFetch holds the references fetched from a remote of a repository
+type remoteFetch struct {
+	remote   string
+	repoInfo *lookout.RepositoryInfo
+	refspecs []config.RefSpec
+}
+
+// remoteFetches returns the fetches of the reference pointers rps into the
+// repository r of repoInfo. The pointers of other repositories, like the
+// heads of pull requests from forks, are fetched from a remote of r for each
+// repository, into references under forkRefPrefix.
+func (s *LibrarySyncer) remoteFetches(ctx context.Context, repoInfo *lookout.RepositoryInfo,
+	r *git.Repository, rps []lookout.ReferencePointer) ([]*remoteFetch, error) {
+
+	byURL := make(map[string]*remoteFetch)
+	var fetches []*remoteFetch
+	for _, rp := range rps {
+		f, ok := byURL[rp.InternalRepositoryURL]
+		if !ok {
+			info := rp.Repository()
+			if info == nil {
+				return nil, fmt.Errorf("invalid repository URL %q", rp.InternalRepositoryURL)
+			}
+
+			f = &remoteFetch{remote: defaultRemoteName, repoInfo: info}
+			if info.CloneURL != repoInfo.CloneURL {
+				f.remote = forkRemoteName(info)
+				if err := s.addRemote(repoInfo, r, f.remote, info); err != nil {
+					return nil, err
+				}
+			}
+
+			byURL[rp.InternalRepositoryURL] = f
+			fetches = append(fetches, f)
+		}
+
+		f.refspecs = append(f.refspecs, refspec(ctx, rp, f.remote))
+	}
+
+	return fetches, nil
+}
+
+// refspec returns the refspec to fetch rp from remote
+func refspec(ctx context.Context, rp lookout.ReferencePointer, remote string) config.RefSpec {
+	if "" == rp.ReferenceName {
+		rs := config.RefSpec(fmt.Sprintf(config.DefaultFetchRefSpec, remote))
+		if remote != defaultRemoteName {
+			rs = config.RefSpec(fmt.Sprintf("refs/heads/*:%s%s/heads/*", forkRefPrefix, remote))
+		}
+
+		ctxlog.Get(ctx).Warningf("empty ReferenceName given in %v, using default '%s' instead", rp, rs)
+		return rs
+	}
+
+	if remote == defaultRemoteName {
+		return config.RefSpec(fmt.Sprintf("%s:%[1]s", rp.ReferenceName))
+	}
+
+	return config.RefSpec(fmt.Sprintf("%s:%s", rp.ReferenceName,
+		forkReferenceName(remote, rp.ReferenceName)))
+}
+
+// ForkReferenceName returns the name of the reference name of the repository
+// info once fetched into the library repository of another one, like the
+// head of a pull request from a fork into the repository of its base
+func ForkReferenceName(info *lookout.RepositoryInfo,
+	name plumbing.ReferenceName) plumbing.ReferenceName {
+	return forkReferenceName(forkRemoteName(info), name)
+}
+
+func forkReferenceName(remote string, name plumbing.ReferenceName) plumbing.ReferenceName {
+	return plumbing.ReferenceName(fmt.Sprintf("%s%s/%s", forkRefPrefix, remote,
+		strings.TrimPrefix(name.String(), "refs/")))
+}
+
+// invalidRemoteChars matches the characters replaced in the remote names
+var invalidRemoteChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// forkRemoteName returns the name of the remote of the repository info
+func forkRemoteName(info *lookout.RepositoryInfo) string {
+	name := invalidRemoteChars.ReplaceAllString(info.Host+"/"+info.FullName, "-")
+	return strings.Trim(name, "-.")
+}
+
+// addRemote adds the remote name for the repository info to the repository r
+// of repoInfo, if it does not have it yet
+func (s *LibrarySyncer) addRemote(repoInfo *lookout.RepositoryInfo, r *git.Repository,
+	name string, info *lookout.RepositoryInfo) error {
+
+	unlock := s.l.lock(repoInfo)
+	defer unlock()
+
+	_, err := r.Remote(name)
+	if err != git.ErrRemoteNotFound {
+		return err
+	}
+
+	_, err = r.CreateRemote(&config.RemoteConfig{
+		Name: name,
+		URLs: []string{info.CloneURL},
+	})
+
+	return err
+}
+
+// shareParentObjects makes the repository share the objects of the
+// repository it was forked from, if it is in the library. The errors are
+// logged, the repository is then fetched without sharing them.
+func (s *LibrarySyncer) shareParentObjects(ctx context.Context, repoInfo *lookout.RepositoryInfo) {
+	if s.parentProvider == nil {
+		return
+	}
+
+	logger := ctxlog.Get(ctx).With(log.Fields{"repository": repoInfo.CloneURL})
+	parent, err := s.parentProvider.ParentRepository(ctx, repoInfo)
+	if err != nil {
+		logger.Warningf("the parent repository could not be requested: %s", err)
+		return
+	}
+
+	if parent == nil {
+		return
+	}
+
+	logger = logger.With(log.Fields{"parent": parent.CloneURL})
+	has, err := s.l.Has(parent)
+	if err != nil {
+		logger.Errorf(err, "the parent repository could not be checked")
+		return
+	}
+
+	if !has {
+		return
+	}
+
+	if err := s.l.ShareObjects(repoInfo, parent); err != nil {
+		logger.Errorf(err, "the objects of the parent repository could not be shared")
+		return
+	}
+
+	logger.Infof("sharing the objects of the parent repository")
+}
+
+// deepen fetches more history of the references until the repository has the
+// commits of rps and the history from the first two to their merge base. The
+// depth is doubled on each fetch, and the whole history is fetched once it
+// exceeds maxShallowDepth.
+func (s *LibrarySyncer) deepen(ctx context.Context, repoInfo *lookout.RepositoryInfo,
+	r *git.Repository, fetches []*remoteFetch, rps []lookout.ReferencePointer) error {
+
+	depth := s.depth
+	for {
+		ok, err := hasHistory(r, rps)
+		if err != nil || ok {
+			return err
+		}
+
+		// the whole history was fetched already, the missing commits are
+		// reported when they are loaded
+		if depth == 0 {
+			return nil
+		}
+
+		depth *= 2
+		if depth > maxShallowDepth {
+			depth = 0
+		}
+
+		ctxlog.Get(ctx).With(log.Fields{"depth": depth}).
+			Debugf("deepening repository %s", repoInfo.CloneURL)
+
+		dr, err := deepenRepository(r)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range fetches {
+			if err := s.fetch(ctx, repoInfo, dr, f, depth); err != nil {
+				return err
+			}
+		}
+
+		if err := updateShallows(r); err != nil {
+			return err
+		}
+	}
+}
+
+// updateShallows removes from the shallow commits of r the ones whose parents
+// were fetched. go-git only adds the new shallow commits when a repository is
+// deepened.
+func updateShallows(r *git.Repository) error {
+	shallows, err := r.Storer.Shallow()
 	if err != nil {
 		return err
 	}
 
-	var refspecs []config.RefSpec
+	var kept []plumbing.Hash
+	for _, h := range shallows {
+		c, err := r.CommitObject(h)
+		if err == plumbing.ErrObjectNotFound {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, p := range c.ParentHashes {
+			_, err := r.Storer.EncodedObject(plumbing.CommitObject, p)
+			if err == plumbing.ErrObjectNotFound {
+				kept = append(kept, h)
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(kept) == len(shallows) {
+		return nil
+	}
+
+	return r.Storer.SetShallow(kept)
+}
+
+// hasHistory returns whether the repository has the commits of rps and, if
+// there are two or more, the history from the first two to their merge base
+func hasHistory(r *git.Repository, rps []lookout.ReferencePointer) (bool, error) {
+	var commits []*object.Commit
 	for _, rp := range rps {
-		var rs config.RefSpec
-		if "" == rp.ReferenceName {
-			rs = config.RefSpec(fmt.Sprintf(config.DefaultFetchRefSpec, defaultRemoteName))
-			ctxlog.Get(ctx).Warningf("empty ReferenceName given in %v, using default '%s' instead", rp, rs)
-		} else {
-			rs = config.RefSpec(fmt.Sprintf("%s:%[1]s", rp.ReferenceName))
+		if rp.Hash == "" {
+			continue
+		}
+
+		c, err := r.CommitObject(plumbing.NewHash(rp.Hash))
+		if err == plumbing.ErrObjectNotFound {
+			return false, nil
 		}
-		refspecs = append(refspecs, rs)
+
+		if err != nil {
+			return false, err
+		}
+
+		commits = append(commits, c)
+	}
+
+	if len(commits) < 2 {
+		return true, nil
+	}
+
+	_, err := mergeBase(commits[0], commits[1])
+	if err == plumbing.ErrObjectNotFound {
+		return false, nil
+	}
+
+	// commits without a common ancestor don't need more history, and the
+	// other errors are reported when the commits are loaded
+	return true, nil
+}
+
+// haveRepository returns a view of the repository r to fetch into it. go-git
+// tells the remote that it has the last commits of each reference, and fails
+// if some are missing, so the shallow commits are returned without parents,
+// like git does. The references of the repositories r shares objects with,
+// its alternates, are also returned, so the remote does not send their
+// objects again.
+func haveRepository(r *git.Repository, alternates []storage.Storer) (*git.Repository, error) {
+	s := &fetchStorer{Storer: r.Storer, shallows: make(map[plumbing.Hash]bool)}
+	for _, st := range append([]storage.Storer{r.Storer}, alternates...) {
+		shallows, err := st.Shallow()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, h := range shallows {
+			s.shallows[h] = true
+		}
+	}
+
+	for _, st := range alternates {
+		iter, err := st.IterReferences()
+		if err != nil {
+			return nil, err
+		}
+
+		err = iter.ForEach(func(ref *plumbing.Reference) error {
+			if ref.Type() == plumbing.HashReference {
+				s.alternateRefs = append(s.alternateRefs, ref)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.shallows) == 0 && len(s.alternateRefs) == 0 {
+		return r, nil
 	}
 
-	return s.fetch(ctx, repoInfo, gitRepo, refspecs)
+	return git.Open(s, nil)
+}
+
+// fetchStorer is a storage.Storer that returns its shallow commits without
+// parents, and the references of its alternates along with its own, see
+// haveRepository
+type fetchStorer struct {
+	storage.Storer
+	shallows      map[plumbing.Hash]bool
+	alternateRefs []*plumbing.Reference
 }
 
+// EncodedObject implements the storer.EncodedObjectStorer interface
+func (s *fetchStorer) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (
+	plumbing.EncodedObject, error) {
+	obj, err := s.Storer.EncodedObject(t, h)
+	if err != nil || !s.shallows[h] || obj.Type() != plumbing.CommitObject {
+		return obj, err
+	}
+
+	c, err := object.DecodeCommit(s.Storer, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ParentHashes = nil
+	shallow := &shallowObject{MemoryObject: &plumbing.MemoryObject{}, hash: h}
+	if err := c.Encode(shallow.MemoryObject); err != nil {
+		return nil, err
+	}
+
+	return shallow, nil
+}
+
+// IterReferences implements the storer.ReferenceStorer interface
+func (s *fetchStorer) IterReferences() (storer.ReferenceIter, error) {
+	iter, err := s.Storer.IterReferences()
+	if err != nil || len(s.alternateRefs) == 0 {
+		return iter, err
+	}
+
+	var refs []*plumbing.Reference
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return storer.NewReferenceSliceIter(append(refs, s.alternateRefs...)), nil
+}
+
+// PackfileWriter implements the storer.PackfileWriter interface, see
+// deepenStorer.PackfileWriter
+func (s *fetchStorer) PackfileWriter() (io.WriteCloser, error) {
+	return packfileWriter(s.Storer)
+}
+
+// shallowObject is a shallow commit without parents, with the hash of the
+// original commit
+type shallowObject struct {
+	*plumbing.MemoryObject
+	hash plumbing.Hash
+}
+
+// Hash implements the plumbing.EncodedObject interface
+func (o *shallowObject) Hash() plumbing.Hash {
+	return o.hash
+}
+
+// deepenRepository returns a view of the repository r that hides its
+// references and the commits they point to. go-git only fetches the
+// references that are missing, so they must be hidden to fetch them again
+// with a greater depth.
+func deepenRepository(r *git.Repository) (*git.Repository, error) {
+	refs, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[plumbing.Hash]bool)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			hidden[ref.Hash()] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return git.Open(&deepenStorer{Storer: r.Storer, hidden: hidden}, nil)
+}
+
+// deepenStorer is a storage.Storer that hides its references and some of its
+// objects, see deepenRepository
+type deepenStorer struct {
+	storage.Storer
+	hidden map[plumbing.Hash]bool
+}
+
+// EncodedObject implements the storer.EncodedObjectStorer interface
+func (s *deepenStorer) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (
+	plumbing.EncodedObject, error) {
+	if s.hidden[h] {
+		return nil, plumbing.ErrObjectNotFound
+	}
+
+	return s.Storer.EncodedObject(t, h)
+}
+
+// IterReferences implements the storer.ReferenceStorer interface. No
+// reference is returned, so go-git does not tell the remote that the objects
+// reachable from them are already fetched.
+func (s *deepenStorer) IterReferences() (storer.ReferenceIter, error) {
+	return storer.NewReferenceSliceIter(nil), nil
+}
+
+// PackfileWriter implements the storer.PackfileWriter interface, so the
+// fetched packfile is written as it is instead of as loose objects
+func (s *deepenStorer) PackfileWriter() (io.WriteCloser, error) {
+	return packfileWriter(s.Storer)
+}
+
+func packfileWriter(s storage.Storer) (io.WriteCloser, error) {
+	pw, ok := s.(storer.PackfileWriter)
+	if !ok {
+		return nil, fmt.Errorf("the repository storage can't write packfiles")
+	}
+
+	return pw.PackfileWriter()
+}
+
+// fetch fetches f into the repository r of repoInfo
 func (s *LibrarySyncer) fetch(ctx context.Context, repoInfo *lookout.RepositoryInfo,
-	r *git.Repository, refspecs []config.RefSpec) (err error) {
+	r *git.Repository, f *remoteFetch, depth int) (err error) {
 
-	ctxlog.Get(ctx).Infof("fetching references for repository %s: %v", repoInfo.CloneURL, refspecs)
+	ctxlog.Get(ctx).Infof("fetching references for repository %s: %v", f.repoInfo.CloneURL, f.refspecs)
 	start := time.Now()
 	defer func() {
 		if err == nil {
 			ctxlog.Get(ctx).
 				With(log.Fields{"duration": time.Now().Sub(start)}).
-				Debugf("references %v fetched for repository %s", refspecs, repoInfo.CloneURL)
+				Debugf("references %v fetched for repository %s", f.refspecs, f.repoInfo.CloneURL)
 		}
 		// in case of error it will be logged on upper level
 	}()
 
 	var auth transport.AuthMethod
 	if s.authProvider != nil {
-		auth = s.authProvider.GitAuth(ctx, repoInfo)
+		auth = s.authProvider.GitAuth(ctx, f.repoInfo)
+		// a fork may be readable with the credentials of the repository
+		if auth == nil && f.repoInfo != repoInfo {
+			auth = s.authProvider.GitAuth(ctx, repoInfo)
+		}
 	}
 
 	opts := &git.FetchOptions{
-		RemoteName: defaultRemoteName,
-		RefSpecs:   refspecs,
+		RemoteName: f.remote,
+		RefSpecs:   f.refspecs,
 		Force:      true,
 		Auth:       auth,
+		Depth:      depth,
 	}
 
-	mi, _ := s.m.LoadOrStore(repoInfo.CloneURL, &sync.Mutex{})
-	mutex := mi.(*sync.Mutex)
-	mutex.Lock()
-	defer mutex.Unlock()
+	// the library lock is shared with its Janitor, so the repository is not
+	// cleaned up while it is fetched
+	unlock := s.l.lock(repoInfo)
+	defer unlock()
 
 	if s.fetchTimeout > 0 {
 		var cancel context.CancelFunc
@@ -2,12 +2,19 @@ package git
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/meyskens/lookout"
 
+	fixtures "github.com/src-d/go-git-fixtures"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 	"gopkg.in/meyskens/lookout-sdk.v0/pb"
@@ -16,7 +23,7 @@ import (
 func TestLibrary_Sync(t *testing.T) {
 	require := require.New(t)
 	library := NewLibrary(memfs.New())
-	syncer := NewSyncer(library, nil, 0)
+	syncer := NewSyncer(library, nil, 0, 0)
 
 	url, _ := pb.ParseRepositoryInfo("https://github.com/meyskens/lookout")
 	err := syncer.Sync(context.TODO(), lookout.ReferencePointer{
@@ -52,7 +59,7 @@ func TestLibrary_Auth(t *testing.T) {
 	require.Equal(0, authCalls)
 
 	library := NewLibrary(memfs.New())
-	syncer := NewSyncer(library, testAuthProvider{}, 0)
+	syncer := NewSyncer(library, testAuthProvider{}, 0, 0)
 
 	url, _ := pb.ParseRepositoryInfo("https://github.com/meyskens/lookout")
 	err := syncer.Sync(context.TODO(), lookout.ReferencePointer{
@@ -68,3 +75,237 @@ func TestLibrary_Auth(t *testing.T) {
 
 	require.Equal(1, authCalls)
 }
+
+// shallowSync syncs a new library from the basic fixture with the given
+// depth, and returns its repository
+func shallowSync(t *testing.T, depth int, rps ...lookout.ReferencePointer) *git.Repository {
+	require := require.New(t)
+
+	url := "file://" + fixtures.Basic().One().DotGit().Root()
+	for i := range rps {
+		rps[i].InternalRepositoryURL = url
+	}
+
+	library := NewLibrary(memfs.New())
+	syncer := NewSyncer(library, nil, 0, depth)
+	require.NoError(syncer.Sync(context.TODO(), rps...))
+
+	r, err := library.Get(context.TODO(), rps[0].Repository())
+	require.NoError(err)
+
+	return r
+}
+
+func TestLibrary_SyncShallow(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	r := shallowSync(t, 1, lookout.ReferencePointer{
+		ReferenceName: "refs/heads/master",
+		Hash:          "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	})
+
+	_, err := r.CommitObject(plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"))
+	require.NoError(err)
+	_, err = r.CommitObject(plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
+	require.Equal(plumbing.ErrObjectNotFound, err)
+
+	shallows, err := r.Storer.Shallow()
+	require.NoError(err)
+	require.Len(shallows, 1)
+}
+
+func TestLibrary_SyncShallowMergeBase(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	// the merge base is the parent of both commits
+	r := shallowSync(t, 1, lookout.ReferencePointer{
+		ReferenceName: "refs/heads/master",
+		Hash:          "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	}, lookout.ReferencePointer{
+		ReferenceName: "refs/remotes/origin/branch",
+		Hash:          "e8d3ffab552895c19b9fcf7aa264d277cde33881",
+	})
+
+	_, err := r.CommitObject(plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
+	require.NoError(err)
+	_, err = r.CommitObject(plumbing.NewHash("b029517f6300c2da0f4b651b8642506cd6aaf45d"))
+	require.Equal(plumbing.ErrObjectNotFound, err)
+
+	// only the commits at the new depth are shallow
+	shallows, err := r.Storer.Shallow()
+	require.NoError(err)
+	require.ElementsMatch([]plumbing.Hash{
+		plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"),
+	}, shallows)
+}
+
+func TestLibrary_SyncShallowMovedReference(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	dir, err := ioutil.TempDir("", "lookout-syncer")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	url := "file://" + fixtures.Basic().One().DotGit().Root()
+	library := NewLibrary(osfs.New(dir))
+	syncer := NewSyncer(library, nil, 0, 2)
+
+	master := lookout.ReferencePointer{
+		InternalRepositoryURL: url,
+		ReferenceName:         "refs/heads/master",
+		Hash:                  "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	}
+	require.NoError(syncer.Sync(context.TODO(), master))
+
+	// the local reference points to a shallow commit that is not the head of
+	// any remote reference, its history can't be sent to the remote
+	r, err := library.Get(context.TODO(), master.Repository())
+	require.NoError(err)
+	require.NoError(r.Storer.SetReference(plumbing.NewHashReference("refs/pull/1/head",
+		plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))))
+	require.NoError(r.Storer.RemoveReference("refs/heads/master"))
+
+	err = syncer.Sync(context.TODO(), lookout.ReferencePointer{
+		InternalRepositoryURL: url,
+		ReferenceName:         "refs/remotes/origin/branch",
+		Hash:                  "e8d3ffab552895c19b9fcf7aa264d277cde33881",
+	})
+	require.NoError(err)
+
+	_, err = r.CommitObject(plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881"))
+	require.NoError(err)
+}
+
+func TestLibrary_SyncShallowMissingBase(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	// the base of a push is not the head of any reference
+	r := shallowSync(t, 1, lookout.ReferencePointer{
+		ReferenceName: "refs/heads/master",
+		Hash:          "1669dce138d9b841a518c64b10914d88f5e488ea",
+	}, lookout.ReferencePointer{
+		ReferenceName: "refs/heads/master",
+		Hash:          "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	})
+
+	_, err := r.CommitObject(plumbing.NewHash("1669dce138d9b841a518c64b10914d88f5e488ea"))
+	require.NoError(err)
+	_, err = r.CommitObject(plumbing.NewHash("b029517f6300c2da0f4b651b8642506cd6aaf45d"))
+	require.Equal(plumbing.ErrObjectNotFound, err)
+}
+
+func TestLibrary_SyncShallowUnrelated(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	// the commit is not in the history of the reference, the whole history is
+	// fetched looking for it
+	r := shallowSync(t, 1, lookout.ReferencePointer{
+		ReferenceName: "refs/heads/master",
+		Hash:          "0000000000000000000000000000000000000001",
+	}, lookout.ReferencePointer{
+		ReferenceName: "refs/heads/master",
+		Hash:          "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	})
+
+	_, err := r.CommitObject(plumbing.NewHash("b029517f6300c2da0f4b651b8642506cd6aaf45d"))
+	require.NoError(err)
+}
+
+type testParentProvider map[string]*lookout.RepositoryInfo
+
+func (p testParentProvider) ParentRepository(ctx context.Context,
+	repoInfo *lookout.RepositoryInfo) (*lookout.RepositoryInfo, error) {
+	return p[repoInfo.CloneURL], nil
+}
+
+func TestLibrary_SyncFork(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	dir, err := ioutil.TempDir("", "lookout-syncer")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	parent := lookout.ReferencePointer{
+		InternalRepositoryURL: "file://" + fixtures.Basic().One().DotGit().Root(),
+		ReferenceName:         "refs/heads/master",
+		Hash:                  "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	}
+	fork := lookout.ReferencePointer{
+		InternalRepositoryURL: "file://" + fixtures.Basic().One().DotGit().Root(),
+		ReferenceName:         "refs/remotes/origin/branch",
+		Hash:                  "e8d3ffab552895c19b9fcf7aa264d277cde33881",
+	}
+
+	library := NewLibrary(osfs.New(dir))
+	syncer := NewSyncer(library, nil, 0, 0)
+	syncer.SetParentProvider(testParentProvider{
+		fork.Repository().CloneURL: parent.Repository(),
+	})
+
+	require.NoError(syncer.Sync(context.TODO(), parent))
+	require.NoError(syncer.Sync(context.TODO(), fork))
+
+	r, err := library.Get(context.TODO(), fork.Repository())
+	require.NoError(err)
+	_, err = r.CommitObject(plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
+	require.NoError(err)
+
+	// only the objects of the fork that the parent does not have were fetched
+	parentPath := filepath.Join(dir, library.repositoryPath(parent.Repository()))
+	require.NoError(os.Rename(parentPath, parentPath+".moved"))
+
+	_, err = r.CommitObject(plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881"))
+	require.NoError(err)
+	_, err = r.CommitObject(plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
+	require.Equal(plumbing.ErrObjectNotFound, err)
+}
+
+func TestLibrary_SyncMultipleRepositories(t *testing.T) {
+	require := require.New(t)
+	defer func() { require.NoError(fixtures.Clean()) }()
+
+	base := lookout.ReferencePointer{
+		InternalRepositoryURL: "file://" + fixtures.Basic().One().DotGit().Root(),
+		ReferenceName:         "refs/heads/master",
+		Hash:                  "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	}
+	head := lookout.ReferencePointer{
+		InternalRepositoryURL: "file://" + fixtures.Basic().One().DotGit().Root(),
+		ReferenceName:         "refs/remotes/origin/branch",
+		Hash:                  "e8d3ffab552895c19b9fcf7aa264d277cde33881",
+	}
+
+	library := NewLibrary(memfs.New())
+	syncer := NewSyncer(library, nil, 0, 1)
+	require.NoError(syncer.Sync(context.TODO(), base, head))
+
+	// the head is fetched into the base repository, from a remote for its
+	// repository
+	has, err := library.Has(head.Repository())
+	require.NoError(err)
+	require.False(has)
+
+	r, err := library.Get(context.TODO(), base.Repository())
+	require.NoError(err)
+
+	remote, err := r.Remote(forkRemoteName(head.Repository()))
+	require.NoError(err)
+	require.Equal([]string{head.Repository().CloneURL}, remote.Config().URLs)
+
+	ref, err := r.Reference(ForkReferenceName(head.Repository(), head.ReferenceName), false)
+	require.NoError(err)
+	require.Equal(head.Hash, ref.Hash().String())
+
+	// the merge base was fetched from both remotes
+	_, err = r.CommitObject(plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
+	require.NoError(err)
+
+	// the remote is reused
+	require.NoError(syncer.Sync(context.TODO(), base, head))
+}
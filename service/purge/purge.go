@@ -93,6 +93,14 @@ func (s *purgeChangesScanner) Change() *lookout.Change {
 	return s.val
 }
 
+func (s *purgeChangesScanner) Hunks() []*lookout.Hunk {
+	return lookout.ScannerHunks(s.underlying)
+}
+
+func (s *purgeChangesScanner) SkipReason(f *lookout.File) string {
+	return lookout.ScannerSkipReason(s.underlying, f)
+}
+
 func (s *purgeChangesScanner) Close() error {
 	return s.underlying.Close()
 }
@@ -125,6 +133,10 @@ func (s *purgeFileScanner) File() *lookout.File {
 	return s.val
 }
 
+func (s *purgeFileScanner) SkipReason(f *lookout.File) string {
+	return lookout.ScannerSkipReason(s.underlying, f)
+}
+
 func (s *purgeFileScanner) Close() error {
 	return s.underlying.Close()
 }
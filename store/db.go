@@ -296,3 +296,53 @@ func (o *DBOrganizationOperator) Config(ctx context.Context, provider string, or
 
 	return m.Config, nil
 }
+
+// DBRepositoryOperator operates on a repository database store
+type DBRepositoryOperator struct {
+	repositoryStore *models.RepositoryStore
+}
+
+// NewDBRepositoryOperator creates new DBRepositoryOperator using kallax as storage
+func NewDBRepositoryOperator(store *models.RepositoryStore) *DBRepositoryOperator {
+	return &DBRepositoryOperator{store}
+}
+
+var _ RepositoryOperator = &DBRepositoryOperator{}
+
+func (o *DBRepositoryOperator) getRepository(ctx context.Context, provider string, repoID string) (*models.Repository, error) {
+	q := models.NewRepositoryQuery().FindByProvider(provider).FindByInternalID(repoID)
+	return o.repositoryStore.FindOne(q)
+}
+
+// Save persists the given config, updating the current one if it exists
+// for the given (provider, repoID)
+func (o *DBRepositoryOperator) Save(ctx context.Context, provider string, repoID string, config string) error {
+	m, err := o.getRepository(ctx, provider, repoID)
+	if err != nil && err != kallax.ErrNotFound {
+		return err
+	}
+
+	if err == kallax.ErrNotFound {
+		m = models.NewRepository(provider, repoID, config)
+	} else {
+		m.Config = config
+	}
+
+	_, err = o.repositoryStore.Save(m)
+	return err
+}
+
+// Config returns the stored config for the given (provider, repoID). If there
+// are no records in the DB, it returns "" without error.
+func (o *DBRepositoryOperator) Config(ctx context.Context, provider string, repoID string) (string, error) {
+	m, err := o.getRepository(ctx, provider, repoID)
+	if err == kallax.ErrNotFound {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return m.Config, nil
+}
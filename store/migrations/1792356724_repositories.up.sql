@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE repository (
+	id uuid NOT NULL PRIMARY KEY,
+	provider text NOT NULL,
+	internal_id text NOT NULL,
+	config text NOT NULL
+);
+
+/* the application logic acts as if the primary key is (provider, internal_id),
+the same way it does for organization */
+CREATE UNIQUE INDEX repository_composite_pkey
+	ON repository (provider, internal_id);
+
+COMMIT;
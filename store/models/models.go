@@ -117,3 +117,24 @@ func newOrganization(provider string, internalID string, config string) *Organiz
 		Config:     config,
 	}
 }
+
+// Repository is a persisted model for a repository. It contains settings that
+// apply only to that repository, on top of the Organization ones.
+// InternalID is the repository clone URL, the same value used in the
+// ReferencePointer.InternalRepositoryURL of the events.
+type Repository struct {
+	kallax.Model `pk:"id"`
+	ID           kallax.ULID
+	Provider     string
+	InternalID   string
+	Config       string
+}
+
+func newRepository(provider string, internalID string, config string) *Repository {
+	return &Repository{
+		ID:         kallax.NewULID(),
+		Provider:   provider,
+		InternalID: internalID,
+		Config:     config,
+	}
+}
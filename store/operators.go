@@ -33,6 +33,16 @@ type OrganizationOperator interface {
 	Config(ctx context.Context, provider string, orgID string) (string, error)
 }
 
+// RepositoryOperator manages persistence of config for repositories
+type RepositoryOperator interface {
+	// Save persists the given config, updating the current one if it exists
+	// for the given (provider, repoID)
+	Save(ctx context.Context, provider string, repoID string, config string) error
+	// Config returns the stored config for the given (provider, repoID). If there
+	// are no records in the DB, it returns "" without error.
+	Config(ctx context.Context, provider string, repoID string) (string, error)
+}
+
 // NoopEventOperator satisfies EventOperator interface but does nothing
 type NoopEventOperator struct{}
 
@@ -80,3 +90,19 @@ func (o *NoopOrganizationOperator) Config(ctx context.Context, provider string,
 	return "", nil
 }
 
+// NoopRepositoryOperator satisfies RepositoryOperator interface but does nothing
+type NoopRepositoryOperator struct{}
+
+var _ RepositoryOperator = &NoopRepositoryOperator{}
+
+// Save persists the given config, updating the current one if it exists
+// for the given (provider, repoID)
+func (o *NoopRepositoryOperator) Save(ctx context.Context, provider string, repoID string, config string) error {
+	return nil
+}
+
+// Config returns the stored config for the given (provider, repoID). If there
+// are no records in the DB, it returns "" without error.
+func (o *NoopRepositoryOperator) Config(ctx context.Context, provider string, repoID string) (string, error) {
+	return "", nil
+}
@@ -216,7 +216,7 @@ var _escData = map[string]*_escFile{
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicgnyD1AIcXTycVUoKC3OiE8tS80rQRVPzs/NxRAsSi3LTC2HK3f29/X1
-DLHmAgQAAP//Z/tze1cAAAA=
+DLHmAgwAZ/tze1cAAAA=
 `,
 	},
 
@@ -231,7 +231,7 @@ H4sIAAAAAAAC/8ySTW7CMBCF1/EpZgkSN2AFyK1QIVRpumAVOfEQpkrsyB7Tn9NXQWoVcCraXbfvfVH0
 SDpnT6TRRQUZRmdUU5COusqhYtSFYmBq0bNqO/4YEqHTNwjyRYuuRlU2CKW1DSoz7L0NrkJ48daUw/z8
 URxX1hyoDk4xWRPXDjvria177/eUVJO5WGRCW6IbKUrlR/52RKWvUjGdiytDlW3b38kZuiy+2EzeyUym
 K/l04XpCejoTyYEajMQ0ZBB6czW6YX7mIov9k2k0FV7vHtnSBX/8p7fWPzOxH5GnyTOZioufkRt381f9
-u+12nc/FZwAAAP//zPqiPbEDAAA=
+u+12nc/F5wDM+qI9sQMAAA==
 `,
 	},
 
@@ -242,7 +242,7 @@ u+12nc/FZwAAAP//zPqiPbEDAAA=
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicgnyD1AIcXTycVUoSi3LTC2PL0ksSk8tsebicvQJcQ1ClUstS80rUQDr
-cfb3CfX1Q9UUn5lizcXl7O/r6xlizQUIAAD//5hwoGFjAAAA
+cfb3CfX1Q9UUn5lizcXl7O/r6xlizQUYAJhwoGFjAAAA
 `,
 	},
 
@@ -255,7 +255,7 @@ cfb3CfX1Q9UUn5lizcXl7O/r6xlizQUIAAD//5hwoGFjAAAA
 H4sIAAAAAAAC/2TPwUoDMRDG8fPOU8yxQt9gT2k6ymKSlZgeelpaMpQBzZYxu+rbSw8KseffxzD/HT0N
 oQewkUwiTGbnCJVX4c+pnvTCFTfQScZlkYxhTBgOzuFLHLyJR3ym4xa6q86rZFas/FX/RlvopFTWcnqb
 JN+Z8nX+kDrr903PcpHSeFnez6z/AR56ADAuUWyf5ZVLRbPfox3dwYc2YvotiPRIkYKl13awkXy7bEfv
-h9TDTwAAAP//hNuj3xcBAAA=
+h9TDzwCE26PfFwEAAA==
 `,
 	},
 
@@ -266,7 +266,7 @@ h9TDTwAAAP//hNuj3xcBAAA=
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVSM7PzU3NK1FwCfIPUHD29wn19VNIzEvMqaxKLbLm
-4nL29/X1DLHmAgQAAP//djugFzsAAAA=
+4nL29/X1DLHmAgwAdjugFzsAAAA=
 `,
 	},
 
@@ -277,7 +277,7 @@ H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVSM7PzU3NK1FwCfIPUHD29wn19VNIzEvMqaxKLbLm
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/wTAQQoCMQwF0H1O8XdziK46M0UKaQqSHqBoXLUVJIJ6et+eLlkCUWRNV2jcOeH2nNOW
-I54njsqtCPrq4/uzF9w+DqkKacy426O/h2PbAtFRS8ka6B8AAP//Ux0Zt1MAAAA=
+I54njsqtCPrq4/uzF9w+DqkKacy426O/h2PbAtFRS8ka6D8AUx0Zt1MAAAA=
 `,
 	},
 
@@ -288,7 +288,7 @@ I54njsqtCPrq4/uzF9w+DqkKacy426O/h2PbAtFRS8ka6B8AAP//Ux0Zt1MAAAA=
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVKEoty0wtj08tS80rUXAJ8g9QcPb3CfX1U8jPSYnP
-zCtJLcpLzInPTLHm4nL29/X1DLHmAgQAAP//0vNoAUcAAAA=
+zCtJLcpLzInPTLHm4nL29/X1DLHmAgwA0vNoAUcAAAA=
 `,
 	},
 
@@ -299,7 +299,7 @@ zCtJLcpLzInPTLHm4nL29/X1DLHmAgQAAP//0vNoAUcAAAA=
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/wTAwQrDIAwG4Hue4r/1ITzZVkYhKoz0LAUzEMRBydwef98aHkdyRJ4lPCF+5YBbZ9Nv
-0anD4PcdW+YzJrx7LW2Y3uPqpVWY/gwpC9LJjKqv69MNy+KIthzjIY7+AQAA//9qFuu3XwAAAA==
+0anD4PcdW+YzJrx7LW2Y3uPqpVWY/gwpC9LJjKqv69MNy+KIthzjIY7+AwBqFuu3XwAAAA==
 `,
 	},
 
@@ -310,7 +310,7 @@ H4sIAAAAAAAC/wTAwQrDIAwG4Hue4r/1ITzZVkYhKoz0LAUzEMRBydwef98aHkdyRJ4lPCF+5YBbZ9Nv
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVSM7PzU3NK1FwCfIPUHD29wn19VNILkpNLElNiU8s
-IUJxaUEKdsVFqWWZqeXxJYlF6alEmo9bC4otzv6+vp4h1lyAAAAA///vMdEl0AAAAA==
+IUJxaUEKdsVFqWWZqeXxJYlF6alEmo9bC4otzv6+vp4h1lyAAQDvMdEl0AAAAA==
 `,
 	},
 
@@ -323,7 +323,7 @@ IUJxaUEKdsVFqWWZqeXxJYlF6alEmo9bC4otzv6+vp4h1lyAAAAA///vMdEl0AAAAA==
 H4sIAAAAAAAC/7TPsWrDMBSF4V1PcbZA2hB5Kq0nJ3ZLQLahyHO42NetQYqCdF3TPn0he+niwJnPx3+o
 3k5NrtR+izkxBh5pdgJKSEJREEbIJ0Mmz4+YBMvkHGgcuReEi/tGcANiWBK2e6UKY6t32OJgKvTBe74I
 irLEsTVd3aCPTMLDmeR2mIT8VX7QtBZNZwzK6rXojMUme37SO53tdAatX2570HqT/yvM12FNIfLXxMtZ
-KH7wPUv+dFboObZ1fbK5+g0AAP//WzsWhuoBAAA=
+KH7wPUv+dFboObZ1fbK5+h0AWzsWhuoBAAA=
 `,
 	},
 
@@ -334,7 +334,7 @@ KH7wPUv+dFboObZ1fbK5+g0AAP//WzsWhuoBAAA=
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/yTLsQ3DIBAF0D5T/A6Jhpk+4QSnKHcSfBfe3oUHeK1iJCK1PCa6fXkdg6scDIr4+9yU
-Z0CLwkg7UYS+jT8w7tfV9nkCAAD//7QpIAJJAAAA
+Z0CLwkg7UYS+jT8w7tfV9nkGALQpIAJJAAAA
 `,
 	},
 
@@ -352,7 +352,7 @@ TVk4Y0/170BXu3WNahrJXLgxqr08f98GGYvYjNdq8ou2/PDl5vbjmmCRJsuOXUFjRkeYdyPMoCulrmxC
 Sa4Pv74Lgd+FHvaxG7orZNAeq4k2cwUAOox3l4fosbwmpvG8X9ZdtDf9W7mrxZ1H7pV3s1cJGld6lefw
 X3r+yxAhJSRtRaUcDkJVVPqD8Gu7Mara6XKMNW1EVRKOBGn00OFoC0dwv2gHoU87YwlCSx84YSM8Yk11
 C2/mNOIsBZ8+RQxJHP3snniTnSXRahm3drM+/TlbTFcRr4/5PSwtF/6PqONHl+rz+5jOx/SWIpgly2XI
-H4I/AQAA//8OZoMGLAUAAA==
+H4I/AwAOZoMGLAUAAA==
 `,
 	},
 
@@ -364,7 +364,7 @@ H4I/AQAA//8OZoMGLAUAAA==
 		compressed: `
 H4sIAAAAAAAC/5zMQarCMBAA0H1OMffoKm3DpzBJ4JOui6WDDOikjGPU24t7F+oB3uvD35Q65zyW8A/F
 9xhAqTHdFmokBn4cYcg4xwS71sYbKRjdDVIukGbEDzGLkcrhtPD2k1fa64Wt6uM1rHxk+fqQ63klfYOH
-HONUOvcMAAD//7TEya4OAQAA
+HONUOvccALTEya4OAQAA
 `,
 	},
 
@@ -375,7 +375,7 @@ HONUOvcMAAD//7TEya4OAQAA
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVKEoty0wtj08tS80rUXAJ8g9QcPb3CfX1Uygoyi/L
-TEktIlZ9Zl5JalFeYk58ZgqxWopSC/KLM0vyiypJ0JRXmpsEdpWzv6+vZ4g1FyAAAP//t7XXZtYAAAA=
+TEktIlZ9Zl5JalFeYk58ZgqxWopSC/KLM0vyiypJ0JRXmpsEdpWzv6+vZ4g1F2AAt7XXZtYAAAA=
 `,
 	},
 
@@ -386,7 +386,7 @@ TEktIlZ9Zl5JalFeYk58ZgqxWopSC/KLM0vyiypJ0JRXmpsEdpWzv6+vZ4g1FyAAAP//t7XXZtYAAAA=
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVKEoty0wtj08tS80rUXAJ8g9QcPb3CfX1U8jMK0kt
-ykvMic9Msebicvb39fUMseYCBAAA//9aEbrDQwAAAA==
+ykvMic9Msebicvb39fUMseYCDABaEbrDQwAAAA==
 `,
 	},
 
@@ -397,7 +397,7 @@ ykvMic9Msebicvb39fUMseYCBAAA//9aEbrDQwAAAA==
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/wTAwQqDMAwG4Hue4r/5ED1VLUNIUxjxLIIZFKSHknV7fL85vTYJRJE1vaFx5oRuo9rv
-sGHNEdcVS+E9C2pz6+28j3rB7e+QopCdGZd9zu/tmKZAtJScNw30BAAA//+NTHcJWwAAAA==
+sGHNEdcVS+E9C2pz6+28j3rB7e+QopCdGZd9zu/tmKZAtJScNw30DACNTHcJWwAAAA==
 `,
 	},
 
@@ -407,7 +407,7 @@ sGHNEdcVS+E9C2pz6+28j3rB7e+QopCdGZd9zu/tmKZAtJScNw30BAAA//+NTHcJWwAAAA==
 		size:    0,
 		modtime: 1,
 		compressed: `
-H4sIAAAAAAAC/wEAAP//AAAAAAAAAAA=
+H4sIAAAAAAAC/wMAAAAAAAAAAAA=
 `,
 	},
 
@@ -420,7 +420,7 @@ H4sIAAAAAAAC/wEAAP//AAAAAAAAAAA=
 H4sIAAAAAAAC/1SQwUrEMBCG73mKYS/pQi143dKF6mY1h6bSRNxbCc3QRCQpaagKPrx0UejehmG++T/+
 B/bERUnIY8dqxYBdFBOStwL4GUSrgF24VBJ20zjE7ymFXUnI68tpvY24OPzscUGfoJaARDIFzieMXn/0
 zlToh2AwM27EOWVD8INOWSqmGBZnMOZAf2gOqdgwfzssLGpzOLzPwd8dj9Tq2dJ9DnS2+v46WPyie3Lu
-2uZfJOk44tUkkbdn1jHA7eeKUqjFaY0zUAEWN1jvzNpC2zRcleQ3AAD//2L4P4IWAQAA
+2uZfJOk44tUkkbdn1jHA7eeKUqjFaY0zUAEWN1jvzNpC2zRcleR3AGL4P4IWAQAA
 `,
 	},
 
@@ -431,7 +431,7 @@ zlToh2AwM27EOWVD8INOWSqmGBZnMOZAf2gOqdgwfzssLGpzOLzPwd8dj9Tq2dJ9DnS2+v46WPyie3Lu
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/wTAwQrDIAwG4Hue4n8PT7aVUYgRRnqWQXMQxIEEt8fvt6XXKYEosqY3NG6cMG01+1Vb
-NhzxOLAXvrLg2+/ahtscn17bDbe/Q4pCLuZAtJecTw30BAAA//94UwlHVAAAAA==
+NhzxOLAXvrLg2+/ahtscn17bDbe/Q4pCLuZAtJecTw30DAB4UwlHVAAAAA==
 `,
 	},
 
@@ -442,7 +442,7 @@ NhzxOLAXvrLg2+/ahtscn17bDbe/Q4pCLuZAtJecTw30BAAA//94UwlHVAAAAA==
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVKEoty0wtj08tS80rUXAJ8g9QcPb3CfX1U8jPSYnP
-zCtJLcpLzInPTLHm4nL29/X1DLHmAgQAAP//0vNoAUcAAAA=
+zCtJLcpLzInPTLHm4nL29/X1DLHmAgwA0vNoAUcAAAA=
 `,
 	},
 
@@ -452,8 +452,8 @@ zCtJLcpLzInPTLHm4nL29/X1DLHmAgQAAP//0vNoAUcAAAA=
 		size:    42,
 		modtime: 1,
 		compressed: `
-H4sIAAAAAAAC/3Jydff0s+bicgnyD1AIcXTycVXIL0pPzMusSizJzM+z5uJy9vf19Qyx5gIEAAD//3ZC
-3tcqAAAA
+H4sIAAAAAAAC/3Jydff0s+bicgnyD1AIcXTycVXIL0pPzMusSizJzM+z5uJy9vf19Qyx5gIMAHZC3tcq
+AAAA
 `,
 	},
 
@@ -465,7 +465,7 @@ H4sIAAAAAAAC/3Jydff0s+bicgnyD1AIcXTycVXIL0pPzMusSizJzM+z5uJy9vf19Qyx5gIEAAD//3ZC
 		compressed: `
 H4sIAAAAAAAC/2TKwQoCIRCA4bPzFHMs2Dfw5C4SkrohdthTSNoyEGOIG9HTdwui6/9/oz4YLwGmoFXU
 GNVoNda2JqZ36lQZdyAo47ZRRj9H9Gdr8RSMU2HBo14GEI9Wn5RLw15e/YsGEMS9NE73C+W/d618o/U3
-w14CwDQ7Z6KETwAAAP//+lQfNZsAAAA=
+w14CwDQ7Z6KEzwD6VB81mwAAAA==
 `,
 	},
 
@@ -475,8 +475,8 @@ w14CwDQ7Z6KETwAAAP//+lQfNZsAAAA=
 		size:    40,
 		modtime: 1,
 		compressed: `
-H4sIAAAAAAAC/3IJ8g9Q8PRzcY1QyC9KT8zLrEosyczPi0/Ozy3IL84sSY0vyE6ttOYCBAAA//+4y7zd
-KAAAAA==
+H4sIAAAAAAAC/3IJ8g9Q8PRzcY1QyC9KT8zLrEosyczPi0/Ozy3IL84sSY0vyE6ttOYCDAC4y7zdKAAA
+AA==
 `,
 	},
 
@@ -488,7 +488,7 @@ KAAAAA==
 		compressed: `
 H4sIAAAAAAAC/3SNsW7CMBRF5/or7thGqfIBnarWQ5ZURURiix72IzzFsS3bQYSvR8oEA/O955ymwhg+
 J3KOrjCJqbAFQSyWRSxikpnSionXGseloJwZFKMTQ0WChwujGJApWVGGnLbDAwXJeI8pXMRyqiG+cPLk
-BrEfqBqlfnb6e6/Rd+1/r9F2v/qAkEbyctsCgwlzDFkKD3HiVb39dU/7K/mXugcAAP//av1XvtoAAAA=
+BrEfqBqlfnb6e6/Rd+1/r9F2v/qAkEbyctsCgwlzDFkKD3HiVb39dU/7K/mXug8Aav1XvtoAAAA=
 `,
 	},
 
@@ -499,7 +499,7 @@ BrEfqBqlfnb6e6/Rd+1/r9F2v/qAkEbyctsCgwlzDFkKD3HiVb39dU/7K/mXugcAAP//av1XvtoAAAA=
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVKCgtzohPLUvNK1FwCfIPUHD29wn19VPIL0pPzMus
-SizJzM+Lz0yx5uJy9vf19Qyx5gIEAAD//5tdm8pFAAAA
+SizJzM+Lz0yx5uJy9vf19Qyx5gIMAJtdm8pFAAAA
 `,
 	},
 
@@ -510,7 +510,7 @@ SizJzM+Lz0yx5uJy9vf19Qyx5gIEAAD//5tdm8pFAAAA
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/wTAwQoCIRAG4Ps8xf8entxdCWEcIcazCEl50agpoqffbwuXKI7Is4Yr1G8c8Py8H7V/
-+zT448CeuSTBet3bHP9mY806brD+M0hWSGF2RHtOKaqjMwAA//8FYkZlUgAAAA==
++zT448CeuSTBet3bHP9mY806brD+M0hWSGF2RHtOKaqjcwAFYkZlUgAAAA==
 `,
 	},
 
@@ -521,7 +521,7 @@ H4sIAAAAAAAC/wTAwQoCIRAG4Ps8xf8entxdCWEcIcazCEl50agpoqffbwuXKI7Is4Yr1G8c8Py8H7V/
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVKEoty0wtj08tS80rUXB0cVFw9vcJ9fVTyE0tSk9V
-yCrOz0tS8PMPUfAL9fGx5uJy9vf19Qyx5gIEAAD//0AcbxZLAAAA
+yCrOz0tS8PMPUfAL9fGx5uJy9vf19Qyx5gIMAEAcbxZLAAAA
 `,
 	},
 
@@ -532,25 +532,50 @@ yCrOz0tS8PMPUfAL9fGx5uJy9vf19Qyx5gIEAAD//0AcbxZLAAAA
 		modtime: 1,
 		compressed: `
 H4sIAAAAAAAC/3Jydff0s+bicvQJcQ1SCHF08nFVKEoty0wtj08tS80rUXAJ8g9QcPb3CfX1U8hNLUpP
-tebicvb39fUMseYCBAAA//+Y8F+aPQAAAA==
+tebicvb39fUMseYCDACY8F+aPQAAAA==
+`,
+	},
+
+	"/store/migrations/1792356724_repositories.down.sql": {
+		name:    "1792356724_repositories.down.sql",
+		local:   "store/migrations/1792356724_repositories.down.sql",
+		size:    40,
+		modtime: 1,
+		compressed: `
+H4sIAAAAAAAC/3Jydff0s+bicgnyD1AIcXTycVUoSi3IL84syS+qtObicvb39fUMseYCDABTA9ZKKAAA
+AA==
+`,
+	},
+
+	"/store/migrations/1792356724_repositories.up.sql": {
+		name:    "1792356724_repositories.up.sql",
+		local:   "store/migrations/1792356724_repositories.up.sql",
+		size:    360,
+		modtime: 1,
+		compressed: `
+H4sIAAAAAAAC/2yQ0WqzQBBGr52n+C6TIOQBcmX+fylSXVtRaK5k0dUOUXfZ3bS1T1+SkpLQ3g0zB85w
+9uIhlTuif6VIKoEq2WcCTlvjORi3YEURdziduIMsKsg6y/BUpnlSHvAoDjFF1pk37rRD0B/hB4op4jlo
+N6ux4e7XrTVzz8P9mtY7ou0G4VVDWTtyqwKbGaMZuIVqg4fy4P4CWMeTcguOegF7rK5fxLjRrmM6s15N
+Gu9qAQd0Rnv0xsG4Qc38+a3YbK8Bapk+1wKp/C9ebjo0rZkus27sUS8UFfKu0t/6c9ciz9NqR18DAGSz
+2bNoAQAA
 `,
 	},
 
 	"/store/migrations/lock.json": {
 		name:    "lock.json",
 		local:   "store/migrations/lock.json",
-		size:    8563,
+		size:    9384,
 		modtime: 1,
 		compressed: `
-H4sIAAAAAAAC/+xZzW7bMAy+5ykEn/sEue44IBiG7jQMBm0zLgeJ8iQqm1Pk3Ye4bWY7drYVWyunugSG
-CYbfR/HP4v1KqewWCo0+W6vPK6WUuu9+lco2YDBbq6y0xiBLdvMkeGd1MPxLo6810KTqpNS9v22b7n0I
-Y8kHRwZc+x7bbK3EBRxIP+IWHXJ5VOag9UC4sbIJWk/pfWL6Fo5KW9AeT5LDzWXYpUMQrHKQafhCBr2A
-aWR/gUVn8zVphKa6BhoOd4Tfc9whS/7MiLpMom/2KR/Glgd/fsqBxyDviQ4z3jhH8Ex3bEnjzHniD4n5
-IDXxDHJiwRpdzODPnbsUt5eWt1R19ibxF1QTR80AGHS7H8dHNP5/fPqy6nE5a6LW1cC0ByHLb6eTNs7u
-qIr25H6D/liWHIOe7TqLSP16yWnTBH83asBXnzReQIJfZsSlhE9fLv+ibhlD4pc6r1TkhbiUfOE8HtpH
-cMOhZUDiq7dcxMyhAI8LhX6HUC0Uen/ajbeY/tEEMnkJkGaQ1MX/IwGfG3Q1dvdQ073DWo3AMZPwNrhy
-qaU3db7U+dLu4Gp2BwKuxtdZHjyYfrntwd/MNCNsaa+XcjNdFb2VIdNhYz2Jde0shfhvKDiYYi6CIkA/
-LMar49PhZwAAAP//f8ci+3MhAAA=
+H4sIAAAAAAAC/+xZzW7bMAy+5ykEn/MEue44IBiG7jQMhmwzLgeJ8igqm1Pk3Ye4f3ZiZ12xNVaqS2CY
+YPh9lCh+Fu8WSmU3ujDgs5X6ulBKqbvuV6lsrS1kK5WVzlogyZaPhg/OBEvPHn2vgSdW2bL//qZtuvch
+HFs+MVrN7Udos5USDjCwfoYNMFB5cKZgzMC4drIOxoz5fSH8EQ5OG208PFn2y/OwSwYtUOVaxuELWvCi
+bSO7Myy6mJekEZrqGmgwbBF+5rAFkvyVO+o8iX7Yx3o4jjz486caeNjkPdN+IhunCF6Zjg0amFhP+CVz
+XkiDNIEcSaAGnjP40+TGkvbS0QarLt4o/gJrpFkz0KRNuwMex3/x/D88fVv0uJw0Uce1JtxpQUfvp5M2
+7LZYzXbl/oD+cCwxaTPZdaIo/TrmsmmCvz1qwFdfNF60BB/njksFn75c/sW5ZS2Kj1WvVOgFqZQ8ch73
+7SPwULQMSHz3joo5cyi0h0ih34KuIoXeV7vzPUxfpEAYGudRHLdJtqcunmT7C4tm5OYsCfdUNP+RgM8t
+cA3d5e244HLOgKY5k/AucBmrXklyMcnFNHC7moGbaK7hMhO3+9BvN3L7G01zhC0Nw1Ntpi+z9yIyny8D
+JinM/1qPgi2AZ4t+eBgvDk/73wMAJNCz3KgkAAA=
 `,
 	},
 
@@ -592,6 +617,8 @@ var _escDirs = map[string][]os.FileInfo{
 		_escData["/store/migrations/1548435439_event_wrappers.up.sql"],
 		_escData["/store/migrations/1550864142_remove_merge_field.down.sql"],
 		_escData["/store/migrations/1550864142_remove_merge_field.up.sql"],
+		_escData["/store/migrations/1792356724_repositories.down.sql"],
+		_escData["/store/migrations/1792356724_repositories.up.sql"],
 		_escData["/store/migrations/lock.json"],
 	},
 }
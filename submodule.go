@@ -0,0 +1,64 @@
+package lookout
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+)
+
+// The submodules are not returned by GetChanges and GetFiles unless the
+// analyzer asks for them in the gRPC metadata of the call. The symbolic links
+// are returned like the other files, but their Content is the path of their
+// target, and they have no Language nor UAST.
+const (
+	// WantSubmodulesMetadata is the gRPC metadata key of the GetChanges and
+	// GetFiles calls that want the submodules, "true" to enable it. A
+	// submodule has no Content, and its Hash is the hash of the commit it
+	// points to.
+	WantSubmodulesMetadata = "lookout-want-submodules"
+	// WantSymlinkTargetsMetadata is the gRPC metadata key of the GetChanges
+	// and GetFiles calls that want the target of the symbolic links in their
+	// Content even if they do not want the contents of the files, "true" to
+	// enable it
+	WantSymlinkTargetsMetadata = "lookout-want-symlink-targets"
+)
+
+// IsSubmodule returns true if f is a submodule
+func IsSubmodule(f *File) bool {
+	return f != nil && filemode.FileMode(f.Mode) == filemode.Submodule
+}
+
+// IsSymlink returns true if f is a symbolic link
+func IsSymlink(f *File) bool {
+	return f != nil && filemode.FileMode(f.Mode) == filemode.Symlink
+}
+
+// WithSubmodules returns a copy of ctx that makes the GetChanges and GetFiles
+// calls made with it return the submodules
+func WithSubmodules(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, WantSubmodulesMetadata, "true")
+}
+
+// WantSubmodulesFromContext returns true if the analyzer asked for the
+// submodules in the metadata of a GetChanges or GetFiles call, or if they were
+// asked for with WithSubmodules for the calls made inside the same process
+func WantSubmodulesFromContext(ctx context.Context) bool {
+	val, _ := metadataValue(ctx, WantSubmodulesMetadata)
+	return val == "true"
+}
+
+// WithSymlinkTargets returns a copy of ctx that makes the GetChanges and
+// GetFiles calls made with it return the target of the symbolic links
+func WithSymlinkTargets(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, WantSymlinkTargetsMetadata, "true")
+}
+
+// WantSymlinkTargetsFromContext returns true if the analyzer asked for the
+// target of the symbolic links in the metadata of a GetChanges or GetFiles
+// call, or if they were asked for with WithSymlinkTargets for the calls made
+// inside the same process
+func WantSymlinkTargetsFromContext(ctx context.Context) bool {
+	val, _ := metadataValue(ctx, WantSymlinkTargetsMetadata)
+	return val == "true"
+}
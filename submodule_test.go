@@ -0,0 +1,38 @@
+package lookout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFileModes(t *testing.T) {
+	require := require.New(t)
+
+	require.False(IsSubmodule(nil))
+	require.False(IsSymlink(nil))
+
+	require.True(IsSubmodule(&File{Mode: 0160000}))
+	require.False(IsSymlink(&File{Mode: 0160000}))
+
+	require.True(IsSymlink(&File{Mode: 0120000}))
+	require.False(IsSubmodule(&File{Mode: 0100644}))
+}
+
+func TestWantSubmodulesFromContext(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	require.False(WantSubmodulesFromContext(ctx))
+	require.False(WantSymlinkTargetsFromContext(ctx))
+
+	require.True(WantSubmodulesFromContext(WithSubmodules(ctx)))
+	require.True(WantSymlinkTargetsFromContext(WithSymlinkTargets(ctx)))
+
+	in := metadata.NewIncomingContext(ctx,
+		metadata.Pairs(WantSubmodulesMetadata, "true"))
+	require.True(WantSubmodulesFromContext(in))
+	require.False(WantSymlinkTargetsFromContext(in))
+}
@@ -0,0 +1,296 @@
+// Package config implements helpers to load the lookoutd configuration files.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// filePrefix is the prefix of the references to files
+const filePrefix = "file:"
+
+// referenceRegexp matches ${NAME} references, and the $${ escape sequence
+var referenceRegexp = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// Interpolate replaces the references in the string values of the YAML
+// document data:
+//   - ${NAME} is replaced by the value of the environment variable NAME.
+//   - ${file:PATH} is replaced by the content of the file at PATH, without
+//     the trailing newline.
+//   - $${ is replaced by a literal ${.
+//
+// When a value is made only of a reference, and the result is a YAML number or
+// boolean, it keeps that type, so it can be used for fields like app_id.
+// The settings of the analyzers are sent to them as they are, so they are not
+// interpolated.
+// It returns the new YAML document, and the paths of the non-empty values that
+// contain a reference, so they can be redacted from the logs with Redact.
+func Interpolate(data []byte) ([]byte, []Path, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	var interpolated []Path
+	doc, err := interpolateValue(doc, nil, &interpolated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !referenceRegexp.Match(data) {
+		return data, nil, nil
+	}
+
+	res, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, interpolated, nil
+}
+
+// Path is the location of a value in a YAML document: the keys of the maps,
+// and the indexes of the lists
+type Path []interface{}
+
+// with returns a copy of p with the key or index k at the end
+func (p Path) with(k interface{}) Path {
+	res := make(Path, len(p), len(p)+1)
+	copy(res, p)
+	return append(res, k)
+}
+
+// anyIndex matches any index of a list in rawPaths
+type anyIndex struct{}
+
+// rawPaths are the paths of the values that are not interpolated
+var rawPaths = []Path{
+	{"analyzers", anyIndex{}, "settings"},
+}
+
+// isRawPath returns true if the value at path is not interpolated
+func isRawPath(path Path) bool {
+	for _, raw := range rawPaths {
+		if len(raw) != len(path) {
+			continue
+		}
+
+		match := true
+		for i := range raw {
+			if _, isIndex := path[i].(int); isIndex && raw[i] == (anyIndex{}) {
+				continue
+			}
+
+			if raw[i] != path[i] {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
+func interpolateValue(v interface{}, path Path, interpolated *[]Path) (interface{}, error) {
+	if isRawPath(path) {
+		return v, nil
+	}
+
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		for k, mv := range v {
+			res, err := interpolateValue(mv, path.with(k), interpolated)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %s", k, err)
+			}
+
+			v[k] = res
+		}
+
+		return v, nil
+	case []interface{}:
+		for i, sv := range v {
+			res, err := interpolateValue(sv, path.with(i), interpolated)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+
+			v[i] = res
+		}
+
+		return v, nil
+	case string:
+		res, found, err := interpolateString(v)
+		if err != nil {
+			return nil, err
+		}
+
+		// numbers can be secrets too, like PINs or all-digit tokens
+		if found && res != "" {
+			*interpolated = append(*interpolated, path)
+		}
+
+		return res, nil
+	default:
+		return v, nil
+	}
+}
+
+// interpolateString returns s with its references replaced, and whether it
+// had any
+func interpolateString(s string) (interface{}, bool, error) {
+	var found bool
+	var err error
+	res := referenceRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		found = true
+		value, refErr := resolve(match[2 : len(match)-1])
+		if refErr != nil && err == nil {
+			err = refErr
+		}
+
+		return value
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !found {
+		return res, false, nil
+	}
+
+	if referenceRegexp.FindString(s) == s {
+		if typed, ok := typedScalar(res); ok {
+			return typed, true, nil
+		}
+	}
+
+	return res, true, nil
+}
+
+// resolve returns the value of the reference ref
+func resolve(ref string) (string, error) {
+	if strings.HasPrefix(ref, filePrefix) {
+		path := strings.TrimPrefix(ref, filePrefix)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("can't read file referenced by ${%s}: %s", ref, err)
+		}
+
+		return strings.TrimSuffix(string(b), "\n"), nil
+	}
+
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable referenced by ${%s} is not set", ref)
+	}
+
+	return value, nil
+}
+
+// typedScalar returns s as a YAML number or boolean, only if s is exactly how
+// YAML would write that value
+func typedScalar(s string) (interface{}, bool) {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		return nil, false
+	}
+
+	switch v.(type) {
+	case int, int64, uint64, float64, bool:
+	default:
+		return nil, false
+	}
+
+	b, err := yaml.Marshal(v)
+	if err != nil || strings.TrimSuffix(string(b), "\n") != s {
+		return nil, false
+	}
+
+	return v, true
+}
+
+// redacted replaces the values in the output of Redact
+const redacted = "****"
+
+// Redact returns the YAML document of v, a configuration loaded from a
+// document interpolated by Interpolate, with the values at the given paths
+// replaced by ****. Only those values are replaced, whatever the others are.
+func Redact(v interface{}, paths []Path) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	if len(paths) == 0 {
+		return string(data), nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", err
+	}
+
+	for _, path := range paths {
+		redactPath(doc, path)
+	}
+
+	data, err = yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// redactPath replaces the value at path of doc, if it is there
+func redactPath(doc interface{}, path Path) {
+	if len(path) == 0 {
+		return
+	}
+
+	last := len(path) - 1
+	for _, k := range path[:last] {
+		doc = child(doc, k)
+		if doc == nil {
+			return
+		}
+	}
+
+	switch v := doc.(type) {
+	case map[interface{}]interface{}:
+		if _, ok := v[path[last]]; ok {
+			v[path[last]] = redacted
+		}
+	case []interface{}:
+		if i, ok := path[last].(int); ok && i < len(v) {
+			v[i] = redacted
+		}
+	}
+}
+
+// child returns the value of the key or index k of doc, nil if there is none
+func child(doc interface{}, k interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[interface{}]interface{}:
+		return v[k]
+	case []interface{}:
+		if i, ok := k.(int); ok && i < len(v) {
+			return v[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestInterpolate(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "lookout-config")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	secretPath := filepath.Join(dir, "secret")
+	require.NoError(ioutil.WriteFile(secretPath, []byte("file-secret\n"), 0600))
+
+	os.Setenv("LOOKOUT_TEST_TOKEN", "env-token")
+	os.Setenv("LOOKOUT_TEST_APP_ID", "1234")
+	os.Setenv("LOOKOUT_TEST_PADDED", "0123")
+	defer os.Unsetenv("LOOKOUT_TEST_TOKEN")
+	defer os.Unsetenv("LOOKOUT_TEST_APP_ID")
+	defer os.Unsetenv("LOOKOUT_TEST_PADDED")
+
+	data := []byte(`
+providers:
+  github:
+    app_id: ${LOOKOUT_TEST_APP_ID}
+    client_secret: ${file:` + secretPath + `}
+repositories:
+  - url: github.com/foo/bar
+    client:
+      token: ${LOOKOUT_TEST_TOKEN}
+      user: ${LOOKOUT_TEST_PADDED}
+  - url: github.com/foo/${LOOKOUT_TEST_TOKEN}-repo
+footer: costs $${LOOKOUT_TEST_TOKEN}
+plain: value
+`)
+
+	res, interpolated, err := Interpolate(data)
+	require.NoError(err)
+	require.ElementsMatch([]Path{
+		{"providers", "github", "app_id"},
+		{"providers", "github", "client_secret"},
+		{"repositories", 0, "client", "token"},
+		{"repositories", 0, "client", "user"},
+		{"repositories", 1, "url"},
+	}, interpolated)
+
+	var conf struct {
+		Providers struct {
+			Github struct {
+				AppID        int    `yaml:"app_id"`
+				ClientSecret string `yaml:"client_secret"`
+			}
+		}
+		Repositories []struct {
+			URL    string
+			Client struct {
+				Token string
+				User  string
+			}
+		}
+		Footer string
+		Plain  string
+	}
+	require.NoError(yaml.Unmarshal(res, &conf))
+
+	require.Equal(1234, conf.Providers.Github.AppID)
+	require.Equal("file-secret", conf.Providers.Github.ClientSecret)
+	require.Len(conf.Repositories, 2)
+	require.Equal("env-token", conf.Repositories[0].Client.Token)
+	require.Equal("0123", conf.Repositories[0].Client.User)
+	require.Equal("github.com/foo/env-token-repo", conf.Repositories[1].URL)
+	require.Equal("costs ${LOOKOUT_TEST_TOKEN}", conf.Footer)
+	require.Equal("value", conf.Plain)
+}
+
+func TestInterpolateNoReferences(t *testing.T) {
+	require := require.New(t)
+
+	data := []byte("# comment\nanalyzers: []\n")
+	res, interpolated, err := Interpolate(data)
+	require.NoError(err)
+	require.Empty(interpolated)
+	require.Equal(data, res)
+}
+
+func TestInterpolateEmptyAndSettings(t *testing.T) {
+	require := require.New(t)
+
+	os.Setenv("LOOKOUT_TEST_EMPTY", "")
+	defer os.Unsetenv("LOOKOUT_TEST_EMPTY")
+
+	data := []byte(`
+footer: ${LOOKOUT_TEST_EMPTY}
+analyzers:
+  - name: example
+    addr: ipv4://localhost:9930
+    settings:
+      template: ${NOT_INTERPOLATED}
+`)
+
+	res, interpolated, err := Interpolate(data)
+	require.NoError(err)
+	require.Empty(interpolated)
+
+	var conf struct {
+		Footer    string
+		Analyzers []struct {
+			Settings map[string]string
+		}
+	}
+	require.NoError(yaml.Unmarshal(res, &conf))
+
+	require.Equal("", conf.Footer)
+	require.Len(conf.Analyzers, 1)
+	require.Equal("${NOT_INTERPOLATED}", conf.Analyzers[0].Settings["template"])
+}
+
+func TestRedact(t *testing.T) {
+	require := require.New(t)
+
+	type repo struct {
+		URL   string
+		Token string
+	}
+
+	var conf struct {
+		Providers struct {
+			Github struct {
+				AppID        int    `yaml:"app_id"`
+				ClientSecret string `yaml:"client_secret"`
+				Footer       string
+			}
+		}
+		// the same value as the app ID, it must be kept
+		Port         int
+		Repositories []repo
+	}
+
+	conf.Providers.Github.AppID = 8080
+	conf.Providers.Github.ClientSecret = "secret"
+	conf.Port = 8080
+	conf.Repositories = []repo{{URL: "github.com/foo/bar", Token: "secret"}}
+
+	res, err := Redact(conf, []Path{
+		{"providers", "github", "app_id"},
+		{"providers", "github", "client_secret"},
+		{"repositories", 0, "token"},
+		// paths that are not in the configuration are ignored
+		{"repositories", 1, "token"},
+		{"unknown", "key"},
+	})
+	require.NoError(err)
+	require.Equal(`port: 8080
+providers:
+  github:
+    app_id: '****'
+    client_secret: '****'
+    footer: ""
+repositories:
+- token: '****'
+  url: github.com/foo/bar
+`, res)
+
+	res, err = Redact(conf, nil)
+	require.NoError(err)
+	require.Contains(res, "client_secret: secret")
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	require := require.New(t)
+
+	os.Unsetenv("LOOKOUT_TEST_UNSET")
+	_, _, err := Interpolate([]byte("web:\n  signing_key: ${LOOKOUT_TEST_UNSET}\n"))
+	require.EqualError(err,
+		"web: signing_key: environment variable referenced by ${LOOKOUT_TEST_UNSET} is not set")
+
+	_, _, err = Interpolate([]byte("key: ${file:/does/not/exist}\n"))
+	require.Error(err)
+
+	_, _, err = Interpolate([]byte("not: [valid"))
+	require.Error(err)
+}
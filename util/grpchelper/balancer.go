@@ -0,0 +1,402 @@
+package grpchelper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/codes"
+	// registers the client side health checking
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
+)
+
+const (
+	// RoundRobin is the balancing policy that sends each call to the next
+	// endpoint
+	RoundRobin = roundrobin.Name
+	// LeastRequest is the balancing policy that sends each call to the
+	// endpoint with less calls in progress
+	LeastRequest = "least_request"
+
+	// staticScheme is the scheme of the resolver for a list of addresses
+	staticScheme = "lookout-static"
+
+	// healthCheckServiceConfig enables the gRPC health checking of each
+	// endpoint. The endpoints that do not implement the gRPC health service
+	// are considered healthy
+	healthCheckServiceConfig = `{"healthCheckConfig": {"serviceName": ""}}`
+)
+
+// retryBackoff is the time to wait before retrying a call that failed
+// with Unavailable
+var retryBackoff = 100 * time.Millisecond
+
+func init() {
+	resolver.Register(&staticResolverBuilder{})
+	balancer.Register(&leastRequestBalancerBuilder{})
+}
+
+// DialBalanced creates a client connection to addr, with custom options and
+// log interceptors. addr can be:
+//   - a gRPC URL, like ipv4://127.0.0.1:9930
+//   - a comma separated list of gRPC URLs
+//   - a dns:// URL, like dns://analyzer:9930, resolved to all its addresses
+//
+// When addr is a list or a DNS name, the calls are balanced between the
+// endpoints with the given policy, RoundRobin if it's empty, each endpoint is
+// health checked, and the calls that fail with Unavailable are retried on
+// another endpoint, the server streaming ones only before the first response.
+func DialBalanced(ctx context.Context, addr string, policy string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	target, endpoints, err := balancedTarget(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoints == 1 {
+		return DialContext(ctx, target, opts...)
+	}
+
+	if policy == "" {
+		policy = RoundRobin
+	}
+
+	if balancer.Get(policy) == nil {
+		return nil, fmt.Errorf("balancing policy not supported: %s", policy)
+	}
+
+	// a DNS name can have any number of addresses, try each one at least once
+	// if there are up to 3
+	attempts := endpoints
+	if attempts <= 0 {
+		attempts = 3
+	}
+
+	opts = append(opts,
+		grpc.WithBalancerName(policy),
+		grpc.WithDefaultServiceConfig(healthCheckServiceConfig),
+	)
+
+	return dialContext(ctx, target,
+		[]grpc.StreamClientInterceptor{RetryUnavailableStreamClientInterceptor(attempts)},
+		[]grpc.UnaryClientInterceptor{RetryUnavailableUnaryClientInterceptor(attempts)},
+		opts...)
+}
+
+// balancedTarget returns the grpc-go target for addr, and its number of
+// endpoints, or 0 if it is unknown
+func balancedTarget(addr string) (string, int, error) {
+	if strings.HasPrefix(addr, "dns://") {
+		hostPort := strings.TrimPrefix(addr, "dns://")
+		// dns://authority/host:port is also valid
+		if strings.Contains(hostPort, "/") {
+			return addr, 0, nil
+		}
+
+		return "dns:///" + hostPort, 0, nil
+	}
+
+	var targets []string
+	for _, a := range strings.Split(addr, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+
+		t, err := pb.ToGoGrpcAddress(a)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid address '%s': %s", a, err)
+		}
+
+		targets = append(targets, t)
+	}
+
+	switch len(targets) {
+	case 0:
+		return "", 0, fmt.Errorf("empty address")
+	case 1:
+		return targets[0], 1, nil
+	default:
+		return staticScheme + ":///" + strings.Join(targets, ","), len(targets), nil
+	}
+}
+
+// RetryUnavailableUnaryClientInterceptor retries up to attempts times the
+// calls that fail with Unavailable, as long as the context is not done. When
+// the connection balances the calls, each retry is sent to another endpoint.
+func RetryUnavailableUnaryClientInterceptor(attempts int) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		var err error
+		for i := 0; i < attempts; i++ {
+			if i > 0 && !waitRetry(ctx) {
+				return err
+			}
+
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			if status.Code(err) != codes.Unavailable {
+				return err
+			}
+		}
+
+		return err
+	}
+}
+
+// RetryUnavailableStreamClientInterceptor retries up to attempts times the
+// server streaming calls that fail with Unavailable before the first response
+// is received, as long as the context is not done. The request is sent again
+// to the new stream, when the connection balances the calls, to another
+// endpoint. The calls that fail after receiving a response, and the ones with
+// client streaming, are not retried.
+func RetryUnavailableStreamClientInterceptor(attempts int) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if desc.ClientStreams || !desc.ServerStreams {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		s := &retryStream{
+			ctx:      ctx,
+			attempts: attempts,
+			newStream: func() (grpc.ClientStream, error) {
+				return streamer(ctx, desc, cc, method, opts...)
+			},
+		}
+
+		var err error
+		for s.tries < attempts {
+			if s.tries > 0 && !waitRetry(ctx) {
+				return nil, err
+			}
+
+			s.tries++
+			s.ClientStream, err = s.newStream()
+			if status.Code(err) != codes.Unavailable {
+				break
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	}
+}
+
+// retryStream is a server streaming call that is created again, sending the
+// same request, when it fails with Unavailable before the first response
+type retryStream struct {
+	grpc.ClientStream
+	ctx       context.Context
+	attempts  int
+	tries     int
+	newStream func() (grpc.ClientStream, error)
+	// sent holds the messages sent until the first response is received
+	sent     []interface{}
+	closed   bool
+	received bool
+}
+
+func (s *retryStream) SendMsg(m interface{}) error {
+	if !s.received {
+		s.sent = append(s.sent, m)
+	}
+
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *retryStream) CloseSend() error {
+	s.closed = true
+	return s.ClientStream.CloseSend()
+}
+
+func (s *retryStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	for !s.received && status.Code(err) == codes.Unavailable && s.tries < s.attempts {
+		if !waitRetry(s.ctx) {
+			return err
+		}
+
+		s.tries++
+		if err = s.retry(); err != nil {
+			continue
+		}
+
+		err = s.ClientStream.RecvMsg(m)
+	}
+
+	if err == nil {
+		s.received = true
+		s.sent = nil
+	}
+
+	return err
+}
+
+// retry creates the stream again and sends the same messages
+func (s *retryStream) retry() error {
+	stream, err := s.newStream()
+	if err != nil {
+		return err
+	}
+
+	s.ClientStream = stream
+	for _, m := range s.sent {
+		if err := stream.SendMsg(m); err != nil {
+			return err
+		}
+	}
+
+	if s.closed {
+		return stream.CloseSend()
+	}
+
+	return nil
+}
+
+// waitRetry waits before retrying a call, it returns false if ctx is done
+func waitRetry(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(retryBackoff):
+		return true
+	}
+}
+
+// staticResolverBuilder resolves a target with a comma separated list of
+// addresses as endpoint
+type staticResolverBuilder struct{}
+
+func (*staticResolverBuilder) Build(
+	target resolver.Target,
+	cc resolver.ClientConn,
+	opts resolver.BuildOption,
+) (resolver.Resolver, error) {
+	var addrs []resolver.Address
+	for _, a := range strings.Split(target.Endpoint, ",") {
+		addrs = append(addrs, resolver.Address{Addr: a})
+	}
+
+	cc.UpdateState(resolver.State{Addresses: addrs})
+	return &staticResolver{}, nil
+}
+
+func (*staticResolverBuilder) Scheme() string {
+	return staticScheme
+}
+
+// staticResolver does nothing, the addresses never change
+type staticResolver struct{}
+
+func (*staticResolver) ResolveNow(o resolver.ResolveNowOption) {}
+
+func (*staticResolver) Close() {}
+
+// leastRequestBalancerBuilder builds the balancers of the LeastRequest policy.
+// Each ClientConn has its own picker builder, so the calls in progress of an
+// analyzer are not mixed with the ones of the others.
+type leastRequestBalancerBuilder struct{}
+
+func (*leastRequestBalancerBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	return base.NewBalancerBuilderWithConfig(LeastRequest,
+		&leastRequestPickerBuilder{}, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+func (*leastRequestBalancerBuilder) Name() string {
+	return LeastRequest
+}
+
+// leastRequestPickerBuilder builds pickers that choose the SubConn with less
+// calls in progress, for the SubConns of a single ClientConn
+type leastRequestPickerBuilder struct {
+	// inFlight holds the number of calls in progress by SubConn. It is kept
+	// in the builder because the pickers are replaced when SubConns change
+	inFlight sync.Map
+}
+
+func (b *leastRequestPickerBuilder) Build(readySCs map[resolver.Address]balancer.SubConn) balancer.Picker {
+	if len(readySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	p := &leastRequestPicker{}
+	for _, sc := range readySCs {
+		counter, _ := b.inFlight.LoadOrStore(sc, new(int64))
+		p.subConns = append(p.subConns, sc)
+		p.inFlight = append(p.inFlight, counter.(*int64))
+	}
+
+	// forget the SubConns that are not ready, they may have been removed
+	b.inFlight.Range(func(sc, _ interface{}) bool {
+		found := false
+		for _, ready := range p.subConns {
+			if ready == sc {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			b.inFlight.Delete(sc)
+		}
+
+		return true
+	})
+
+	return p
+}
+
+type leastRequestPicker struct {
+	subConns []balancer.SubConn
+	inFlight []*int64
+	// next is used to break ties in round-robin
+	next uint32
+}
+
+func (p *leastRequestPicker) Pick(ctx context.Context, opts balancer.PickOptions) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	start := int(atomic.AddUint32(&p.next, 1)) % len(p.subConns)
+
+	best := start
+	for i := 1; i < len(p.subConns); i++ {
+		j := (start + i) % len(p.subConns)
+		if atomic.LoadInt64(p.inFlight[j]) < atomic.LoadInt64(p.inFlight[best]) {
+			best = j
+		}
+	}
+
+	counter := p.inFlight[best]
+	atomic.AddInt64(counter, 1)
+	done := func(balancer.DoneInfo) {
+		atomic.AddInt64(counter, -1)
+	}
+
+	return p.subConns[best], done, nil
+}
@@ -0,0 +1,351 @@
+package grpchelper
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+// startHealthServer starts a gRPC server where the service "who" has the
+// given status, to know which endpoint answered a call
+func startHealthServer(t *testing.T, who healthpb.HealthCheckResponse_ServingStatus) (string, func()) {
+	lis, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	hs := health.NewServer()
+	hs.SetServingStatus("who", who)
+
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+
+	return "ipv4://" + lis.Addr().String(), srv.Stop
+}
+
+func TestDialBalanced(t *testing.T) {
+	require := require.New(t)
+
+	addr1, stop1 := startHealthServer(t, healthpb.HealthCheckResponse_SERVING)
+	defer stop1()
+	addr2, stop2 := startHealthServer(t, healthpb.HealthCheckResponse_NOT_SERVING)
+	defer stop2()
+
+	for _, policy := range []string{"", RoundRobin, LeastRequest} {
+		conn, err := DialBalanced(context.Background(), addr1+","+addr2, policy, grpc.WithBlock())
+		require.NoError(err)
+
+		client := healthpb.NewHealthClient(conn)
+		answered := make(map[healthpb.HealthCheckResponse_ServingStatus]int)
+		for i := 0; i < 20; i++ {
+			resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "who"})
+			require.NoError(err)
+			answered[resp.Status]++
+		}
+
+		require.Len(answered, 2, "policy %q", policy)
+		require.NoError(conn.Close())
+	}
+}
+
+func TestDialBalancedErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := DialBalanced(context.Background(), "ipv4://127.0.0.1:1,http://foo", "")
+	require.Error(err)
+
+	_, err = DialBalanced(context.Background(), " , ", "")
+	require.Error(err)
+
+	_, err = DialBalanced(context.Background(), "ipv4://127.0.0.1:1,ipv4://127.0.0.1:2", "random")
+	require.EqualError(err, "balancing policy not supported: random")
+}
+
+func TestBalancedTarget(t *testing.T) {
+	require := require.New(t)
+
+	target, n, err := balancedTarget("ipv4://127.0.0.1:9930")
+	require.NoError(err)
+	require.Equal("127.0.0.1:9930", target)
+	require.Equal(1, n)
+
+	target, n, err = balancedTarget("ipv4://127.0.0.1:9930, ipv4://127.0.0.1:9931")
+	require.NoError(err)
+	require.Equal("lookout-static:///127.0.0.1:9930,127.0.0.1:9931", target)
+	require.Equal(2, n)
+
+	target, n, err = balancedTarget("dns://analyzer:9930")
+	require.NoError(err)
+	require.Equal("dns:///analyzer:9930", target)
+	require.Equal(0, n)
+}
+
+func TestRetryUnavailableUnaryClientInterceptor(t *testing.T) {
+	require := require.New(t)
+
+	retryBackoff = 0
+
+	var calls int
+	invoker := func(failures int, code codes.Code) grpc.UnaryInvoker {
+		calls = 0
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			if calls <= failures {
+				return status.Error(code, "failed")
+			}
+
+			return nil
+		}
+	}
+
+	interceptor := RetryUnavailableUnaryClientInterceptor(3)
+
+	err := interceptor(context.Background(), "method", nil, nil, nil, invoker(2, codes.Unavailable))
+	require.NoError(err)
+	require.Equal(3, calls)
+
+	err = interceptor(context.Background(), "method", nil, nil, nil, invoker(3, codes.Unavailable))
+	require.Equal(codes.Unavailable, status.Code(err))
+	require.Equal(3, calls)
+
+	err = interceptor(context.Background(), "method", nil, nil, nil, invoker(1, codes.Internal))
+	require.Equal(codes.Internal, status.Code(err))
+	require.Equal(1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = interceptor(ctx, "method", nil, nil, nil, invoker(3, codes.Unavailable))
+	require.Equal(codes.Unavailable, status.Code(err))
+	require.Equal(1, calls)
+}
+
+// fakeClientStream is a server streaming call that fails with err on the
+// first RecvMsg, or returns the messages it was sent
+type fakeClientStream struct {
+	grpc.ClientStream
+	err    error
+	sent   []interface{}
+	closed bool
+}
+
+func (s *fakeClientStream) Context() context.Context { return context.Background() }
+func (s *fakeClientStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+func (s *fakeClientStream) CloseSend() error {
+	s.closed = true
+	return nil
+}
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	if s.err != nil {
+		err := s.err
+		s.err = nil
+		return err
+	}
+
+	if !s.closed || len(s.sent) == 0 {
+		return fmt.Errorf("request not sent")
+	}
+
+	*m.(*string) = s.sent[0].(string)
+	return nil
+}
+
+func TestRetryUnavailableStreamClientInterceptor(t *testing.T) {
+	require := require.New(t)
+
+	retryBackoff = 0
+
+	var streams []*fakeClientStream
+	streamer := func(newErr error, failures int, code codes.Code) grpc.Streamer {
+		streams = nil
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if newErr != nil {
+				streams = append(streams, nil)
+				return nil, newErr
+			}
+
+			s := &fakeClientStream{}
+			if len(streams) < failures {
+				s.err = status.Error(code, "failed")
+			}
+
+			streams = append(streams, s)
+			return s, nil
+		}
+	}
+
+	interceptor := RetryUnavailableStreamClientInterceptor(3)
+	desc := &grpc.StreamDesc{ServerStreams: true}
+
+	recv := func(s grpc.ClientStream) (string, error) {
+		require.NoError(s.SendMsg("req"))
+		require.NoError(s.CloseSend())
+
+		var resp string
+		err := s.RecvMsg(&resp)
+		return resp, err
+	}
+
+	s, err := interceptor(context.Background(), desc, nil, "method", streamer(nil, 2, codes.Unavailable))
+	require.NoError(err)
+	resp, err := recv(s)
+	require.NoError(err)
+	require.Equal("req", resp)
+	require.Len(streams, 3)
+
+	// the errors after the first response are not retried
+	streams[2].err = status.Error(codes.Unavailable, "failed")
+	require.Equal(codes.Unavailable, status.Code(s.RecvMsg(&resp)))
+	require.Len(streams, 3)
+
+	s, err = interceptor(context.Background(), desc, nil, "method", streamer(nil, 3, codes.Unavailable))
+	require.NoError(err)
+	_, err = recv(s)
+	require.Equal(codes.Unavailable, status.Code(err))
+	require.Len(streams, 3)
+
+	s, err = interceptor(context.Background(), desc, nil, "method", streamer(nil, 1, codes.Internal))
+	require.NoError(err)
+	_, err = recv(s)
+	require.Equal(codes.Internal, status.Code(err))
+	require.Len(streams, 1)
+
+	// the stream can not be created
+	_, err = interceptor(context.Background(), desc, nil, "method",
+		streamer(status.Error(codes.Unavailable, "failed"), 0, codes.OK))
+	require.Equal(codes.Unavailable, status.Code(err))
+	require.Len(streams, 3)
+
+	// client streaming calls are not retried
+	s, err = interceptor(context.Background(), &grpc.StreamDesc{ClientStreams: true, ServerStreams: true},
+		nil, "method", streamer(nil, 1, codes.Unavailable))
+	require.NoError(err)
+	_, err = recv(s)
+	require.Equal(codes.Unavailable, status.Code(err))
+	require.Len(streams, 1)
+}
+
+type fakeSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+func TestLeastRequestPicker(t *testing.T) {
+	require := require.New(t)
+
+	sc1 := &fakeSubConn{name: "1"}
+	sc2 := &fakeSubConn{name: "2"}
+
+	b := &leastRequestPickerBuilder{}
+	p := b.Build(map[resolver.Address]balancer.SubConn{
+		{Addr: "1"}: sc1,
+		{Addr: "2"}: sc2,
+	})
+
+	first, done1, err := p.Pick(context.Background(), balancer.PickOptions{})
+	require.NoError(err)
+
+	// the other SubConn has less calls in progress
+	second, done2, err := p.Pick(context.Background(), balancer.PickOptions{})
+	require.NoError(err)
+	require.NotEqual(first, second)
+
+	// the first call finishes, so the first SubConn is picked again
+	done1(balancer.DoneInfo{})
+	third, _, err := p.Pick(context.Background(), balancer.PickOptions{})
+	require.NoError(err)
+	require.Equal(first, third)
+
+	// the counters are kept when the picker is rebuilt
+	done2(balancer.DoneInfo{})
+	p = b.Build(map[resolver.Address]balancer.SubConn{
+		{Addr: "1"}: sc1,
+		{Addr: "2"}: sc2,
+	})
+	fourth, _, err := p.Pick(context.Background(), balancer.PickOptions{})
+	require.NoError(err)
+	require.Equal(second, fourth)
+
+	p = b.Build(map[resolver.Address]balancer.SubConn{})
+	_, _, err = p.Pick(context.Background(), balancer.PickOptions{})
+	require.Equal(balancer.ErrNoSubConnAvailable, err)
+}
+
+// fakeClientConn is a balancer.ClientConn that creates fakeSubConns and keeps
+// them and the last picker
+type fakeClientConn struct {
+	balancer.ClientConn
+	subConns []balancer.SubConn
+	picker   balancer.Picker
+}
+
+func (cc *fakeClientConn) NewSubConn(addrs []resolver.Address, opts balancer.NewSubConnOptions) (balancer.SubConn, error) {
+	sc := &fakeSubConn{name: addrs[0].Addr}
+	cc.subConns = append(cc.subConns, sc)
+	return sc, nil
+}
+
+func (cc *fakeClientConn) UpdateBalancerState(s connectivity.State, p balancer.Picker) {
+	cc.picker = p
+}
+
+func (sc *fakeSubConn) Connect() {}
+
+// newLeastRequestConn builds a LeastRequest balancer for a new ClientConn with
+// the given number of ready endpoints
+func newLeastRequestConn(endpoints int) (*fakeClientConn, balancer.V2Balancer) {
+	var addrs []resolver.Address
+	for i := 0; i < endpoints; i++ {
+		addrs = append(addrs, resolver.Address{Addr: fmt.Sprint(i)})
+	}
+
+	cc := &fakeClientConn{}
+	b := balancer.Get(LeastRequest).Build(cc, balancer.BuildOptions{}).(balancer.V2Balancer)
+	b.UpdateResolverState(resolver.State{Addresses: addrs})
+	for _, sc := range cc.subConns {
+		b.UpdateSubConnState(sc, balancer.SubConnState{ConnectivityState: connectivity.Ready})
+	}
+
+	return cc, b
+}
+
+func TestLeastRequestBalancerPerClientConn(t *testing.T) {
+	require := require.New(t)
+
+	cc1, b1 := newLeastRequestConn(3)
+	first, _, err := cc1.picker.Pick(context.Background(), balancer.PickOptions{})
+	require.NoError(err)
+
+	// the picker of another ClientConn is rebuilt
+	cc2, b2 := newLeastRequestConn(2)
+	b2.UpdateSubConnState(cc2.subConns[0],
+		balancer.SubConnState{ConnectivityState: connectivity.TransientFailure})
+
+	// and then the one of the first ClientConn, it keeps its calls in progress
+	for _, sc := range cc1.subConns {
+		if sc != first {
+			b1.UpdateSubConnState(sc,
+				balancer.SubConnState{ConnectivityState: connectivity.TransientFailure})
+			break
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		sc, done, err := cc1.picker.Pick(context.Background(), balancer.PickOptions{})
+		require.NoError(err)
+		require.NotEqual(first, sc)
+		done(balancer.DoneInfo{})
+	}
+}
@@ -39,16 +39,28 @@ func NewServer(opts ...grpc.ServerOption) *grpc.Server {
 // DialContext creates a client connection to the given target with custom
 // options and log interceptors
 func DialContext(ctx context.Context, target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return dialContext(ctx, target, nil, nil, opts...)
+}
+
+// dialContext is like DialContext, but it runs streamInterceptors and
+// unaryInterceptors before the default ones
+func dialContext(
+	ctx context.Context,
+	target string,
+	streamInterceptors []grpc.StreamClientInterceptor,
+	unaryInterceptors []grpc.UnaryClientInterceptor,
+	opts ...grpc.DialOption,
+) (*grpc.ClientConn, error) {
 	return pb.DialContextWithInterceptors(
 		ctx, target,
-		[]grpc.StreamClientInterceptor{
+		append(streamInterceptors,
 			CtxlogStreamClientInterceptor,
 			pb.LogStreamClientInterceptor(logFn),
-		},
-		[]grpc.UnaryClientInterceptor{
+		),
+		append(unaryInterceptors,
 			CtxlogUnaryClientInterceptor,
 			pb.LogUnaryClientInterceptor(logFn),
-		},
+		),
 		opts...,
 	)
 }
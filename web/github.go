@@ -1,21 +1,26 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strconv"
 
+	"github.com/meyskens/lookout"
 	github_provider "github.com/meyskens/lookout/provider/github"
+	"github.com/meyskens/lookout/server"
 
 	"github.com/bradleyfalzon/ghinstallation"
 	"github.com/go-chi/chi"
 	"github.com/google/go-github/v28/github"
 	"github.com/meyskens/lookout/store"
 	"github.com/meyskens/lookout/util/ctxlog"
+	"gopkg.in/meyskens/lookout-sdk.v0/pb"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -24,6 +29,10 @@ type GitHub struct {
 	AppID          int
 	PrivateKey     string
 	OrganizationOp store.OrganizationOperator
+	RepositoryOp   store.RepositoryOperator
+	// Analyzers is the global analyzers configuration, used to show the
+	// effective configuration of a repository
+	Analyzers []lookout.AnalyzerConfig
 }
 
 func (g *GitHub) appClient() (*github.Client, error) {
@@ -86,17 +95,24 @@ func (g *GitHub) installation(ctx context.Context, orgName string) (*github.Inst
 	return installation, nil
 }
 
-func (g *GitHub) isAdmin(ctx context.Context, installation *github.Installation, login string) (bool, error) {
+func (g *GitHub) installationClient(installation *github.Installation) (*github.Client, error) {
 	// New transport for each installation
 	itr, err := ghinstallation.NewKeyFromFile(
 		http.DefaultTransport, g.AppID, int(installation.GetID()), g.PrivateKey)
 
 	if err != nil {
-		return false, fmt.Errorf("failed to initialize the GitHub App installation client: %s", err)
+		return nil, fmt.Errorf("failed to initialize the GitHub App installation client: %s", err)
 	}
 
 	// Use installation transport in a new client
-	client := github.NewClient(&http.Client{Transport: itr})
+	return github.NewClient(&http.Client{Transport: itr}), nil
+}
+
+func (g *GitHub) isAdmin(ctx context.Context, installation *github.Installation, login string) (bool, error) {
+	client, err := g.installationClient(installation)
+	if err != nil {
+		return false, err
+	}
 
 	org := installation.GetAccount().GetLogin()
 	mem, _, err := client.Organizations.GetOrgMembership(ctx, login, org)
@@ -240,25 +256,53 @@ func (g *GitHub) Org(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-type updateOrgReq struct {
+type updateConfigReq struct {
 	Config string `json:"config,omitempty"`
 }
 
 // UpdateOrg is a hander that updates the organization settings, and returns
 // the updated organization information with the same response as Org
 func (g *GitHub) UpdateOrg(w http.ResponseWriter, r *http.Request) {
-	var configRequest updateOrgReq
+	configRequest, err := readConfigRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	installation, err := g.orgInstallation(w, r)
+	if err != nil {
+		return
+	}
+
+	idStr := strconv.FormatInt(installation.GetAccount().GetID(), 10)
+	err = g.OrganizationOp.Save(r.Context(), github_provider.Provider, idStr, configRequest.Config)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to save the organization config")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	successJSON(w, r, orgResponse{
+		ID:     installation.GetAccount().GetID(),
+		Name:   installation.GetAccount().GetLogin(),
+		Config: configRequest.Config,
+	})
+}
+
+// readConfigRequest reads and validates the body of a config update request.
+// If there is any error the proper HTTP headers are set in w.
+func readConfigRequest(w http.ResponseWriter, r *http.Request) (*updateConfigReq, error) {
+	var configRequest updateConfigReq
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		ctxlog.Get(r.Context()).Errorf(err, "failed to read the request body")
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	err = json.Unmarshal(body, &configRequest)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Bad Request. Body is not a valid JSON: %s", err), http.StatusBadRequest)
-		return
+		return nil, err
 	}
 
 	var empty struct{}
@@ -266,25 +310,327 @@ func (g *GitHub) UpdateOrg(w http.ResponseWriter, r *http.Request) {
 		http.Error(w,
 			fmt.Sprintf("Bad Request. The configuration is not valid YAML: %s", err),
 			http.StatusBadRequest)
+		return nil, err
+	}
+
+	return &configRequest, nil
+}
+
+// reposListItem is the response type used by the repositories list handler
+type reposListItem struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Repos writes in the response the list of repositories of the organization
+// requested by the URL parameter "orgName" where the GitHub App is installed,
+// only if the user is an admin
+func (g *GitHub) Repos(w http.ResponseWriter, r *http.Request) {
+	installation, err := g.orgInstallation(w, r)
+	if err != nil {
 		return
 	}
 
+	client, err := g.installationClient(installation)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to create the installation client")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// initialized as empty array because otherwise json response will be null
+	// instead of []
+	repos := []reposListItem{}
+
+	opts := &github.ListOptions{
+		PerPage: 100,
+	}
+	for {
+		ghRepos, resp, err := client.Apps.ListRepos(r.Context(), opts)
+		if err != nil {
+			ctxlog.Get(r.Context()).Errorf(err, "failed to retrieve the installation repositories")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		for _, repo := range ghRepos {
+			repos = append(repos, reposListItem{
+				ID:   repo.GetID(),
+				Name: repo.GetName(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+
+	successJSON(w, r, repos)
+}
+
+// orgRepository returns the GitHub repository corresponding to the URL
+// parameters "orgName" and "repoName", and the client of the installation it
+// belongs to. The logged-in user must be an administrator of the organization.
+// If there is any error the proper HTTP headers are set in w.
+func (g *GitHub) orgRepository(w http.ResponseWriter, r *http.Request) (*github.Installation, *github.Client, *github.Repository, error) {
 	installation, err := g.orgInstallation(w, r)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	repoName := chi.URLParam(r, "repoName")
+	if repoName == "" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return nil, nil, nil, fmt.Errorf(http.StatusText(http.StatusBadRequest))
+	}
+
+	client, err := g.installationClient(installation)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to create the installation client")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, nil, fmt.Errorf(http.StatusText(http.StatusInternalServerError))
+	}
+
+	repo, resp, err := client.Repositories.Get(r.Context(), installation.GetAccount().GetLogin(), repoName)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return nil, nil, nil, fmt.Errorf(http.StatusText(http.StatusNotFound))
+		}
+
+		ctxlog.Get(r.Context()).Errorf(err, "failed to get repository with name %v", repoName)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, nil, fmt.Errorf(http.StatusText(http.StatusInternalServerError))
+	}
+
+	return installation, client, repo, nil
+}
+
+// repoResponse is the response type used by the individual repository handler
+type repoResponse struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Config string `json:"config"`
+	// EffectiveConfig is the configuration each analyzer receives for the
+	// default branch, once all the configuration sources are merged
+	EffectiveConfig string `json:"effective_config"`
+}
+
+// Repo writes in the response the individual repository requested by the URL
+// parameters "orgName" and "repoName", only if the user is an admin
+func (g *GitHub) Repo(w http.ResponseWriter, r *http.Request) {
+	installation, client, repo, err := g.orgRepository(w, r)
 	if err != nil {
 		return
 	}
 
+	repoID, err := repositoryID(repo)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to parse the repository URL")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	config, err := g.RepositoryOp.Config(r.Context(), github_provider.Provider, repoID)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed read the repository config from the DB")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	g.writeRepo(w, r, installation, client, repo, config)
+}
+
+// UpdateRepo is a hander that updates the repository settings, and returns
+// the updated repository information with the same response as Repo
+func (g *GitHub) UpdateRepo(w http.ResponseWriter, r *http.Request) {
+	configRequest, err := readConfigRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	installation, client, repo, err := g.orgRepository(w, r)
+	if err != nil {
+		return
+	}
+
+	repoID, err := repositoryID(repo)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to parse the repository URL")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	err = g.RepositoryOp.Save(r.Context(), github_provider.Provider, repoID, configRequest.Config)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to save the repository config")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	g.writeRepo(w, r, installation, client, repo, configRequest.Config)
+}
+
+func (g *GitHub) writeRepo(
+	w http.ResponseWriter,
+	r *http.Request,
+	installation *github.Installation,
+	client *github.Client,
+	repo *github.Repository,
+	config string,
+) {
+	effective, err := g.effectiveConfig(r.Context(), installation, client, repo, config)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to calculate the effective config")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	successJSON(w, r, repoResponse{
+		ID:              repo.GetID(),
+		Name:            repo.GetName(),
+		Config:          config,
+		EffectiveConfig: effective,
+	})
+}
+
+// effectiveConfig returns, in YAML, the configuration that the analyzers
+// receive for the default branch of the repository
+func (g *GitHub) effectiveConfig(
+	ctx context.Context,
+	installation *github.Installation,
+	client *github.Client,
+	repo *github.Repository,
+	repoConfig string,
+) (string, error) {
 	idStr := strconv.FormatInt(installation.GetAccount().GetID(), 10)
-	err = g.OrganizationOp.Save(r.Context(), github_provider.Provider, idStr, configRequest.Config)
+	orgConfig, err := g.OrganizationOp.Config(ctx, github_provider.Provider, idStr)
 	if err != nil {
-		ctxlog.Get(r.Context()).Errorf(err, "failed to save the organization config")
+		return "", err
+	}
+
+	lookoutYml, err := lookoutYml(ctx, client, repo, repo.GetDefaultBranch())
+	if err != nil {
+		return "", err
+	}
+
+	conf, err := server.EffectiveConfig(ctx, g.Analyzers, orgConfig, repoConfig, lookoutYml)
+	if err != nil {
+		return "", err
+	}
+
+	var effective server.Config
+	for _, aConf := range conf {
+		// the address is an internal detail of the deployment
+		aConf.Addr = ""
+		aConf.Balancer = ""
+		effective.Analyzers = append(effective.Analyzers, aConf)
+	}
+
+	sort.Slice(effective.Analyzers, func(i, j int) bool {
+		return effective.Analyzers[i].Name < effective.Analyzers[j].Name
+	})
+
+	b, err := yaml.Marshal(effective)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// lookoutYml returns the content of the .lookout.yml file in the given ref of
+// the repository, or "" if it does not exist
+func lookoutYml(ctx context.Context, client *github.Client, repo *github.Repository, ref string) (string, error) {
+	return github_provider.FileContent(ctx, client,
+		repo.GetOwner().GetLogin(), repo.GetName(), ".lookout.yml", ref)
+}
+
+// repositoryID returns the ID used to persist the repository, the same
+// internal repository URL used by the provider in the events
+func repositoryID(repo *github.Repository) (string, error) {
+	info, err := pb.ParseRepositoryInfo(repo.GetHTMLURL())
+	if err != nil {
+		return "", err
+	}
+
+	return info.CloneURL, nil
+}
+
+// explainResponse is the response type used by the RepoExplain handler
+type explainResponse struct {
+	Ref string `json:"ref"`
+	// Config is the effective configuration in YAML, with a comment next to
+	// each value telling the layer it comes from
+	Config string `json:"config"`
+}
+
+// RepoExplain writes in the response the effective configuration of the
+// repository requested by the URL parameters "orgName" and "repoName",
+// annotated with the layer each value comes from. The optional query parameter
+// "ref" sets the revision used to read the .lookout.yml file, by default it
+// is the default branch
+func (g *GitHub) RepoExplain(w http.ResponseWriter, r *http.Request) {
+	installation, client, repo, err := g.orgRepository(w, r)
+	if err != nil {
+		return
+	}
+
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		ref = repo.GetDefaultBranch()
+	}
+
+	repoID, err := repositoryID(repo)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to parse the repository URL")
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	successJSON(w, r, orgResponse{
-		ID:     installation.GetAccount().GetID(),
-		Name:   installation.GetAccount().GetLogin(),
-		Config: configRequest.Config,
+	idStr := strconv.FormatInt(installation.GetAccount().GetID(), 10)
+	orgConfig, err := g.OrganizationOp.Config(r.Context(), github_provider.Provider, idStr)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed read the organization config from the DB")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	repoConfig, err := g.RepositoryOp.Config(r.Context(), github_provider.Provider, repoID)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed read the repository config from the DB")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	lookoutYml, err := lookoutYml(r.Context(), client, repo, ref)
+	if err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to get the .lookout.yml file in ref %v", ref)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	confs, err := server.ExplainConfig(r.Context(), g.Analyzers, orgConfig, repoConfig, lookoutYml)
+	if err != nil {
+		http.Error(w,
+			fmt.Sprintf("The configuration is not valid: %s", err),
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := server.WriteExplainedConfig(&buf, confs); err != nil {
+		ctxlog.Get(r.Context()).Errorf(err, "failed to write the explained config")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	successJSON(w, r, explainResponse{
+		Ref:    ref,
+		Config: buf.String(),
 	})
 }
@@ -41,6 +41,13 @@ func NewHTTPServer(auth *Auth, gh *GitHub, static *Static) *HTTPServer {
 		r.Route("/org/{orgName}", func(r chi.Router) {
 			r.Get("/", gh.Org)
 			r.Put("/", gh.UpdateOrg)
+			r.Get("/repos", gh.Repos)
+
+			r.Route("/repo/{repoName}", func(r chi.Router) {
+				r.Get("/", gh.Repo)
+				r.Put("/", gh.UpdateRepo)
+				r.Get("/explain", gh.RepoExplain)
+			})
 		})
 	})
 	r.Get("/static/*", static.ServeHTTP)